@@ -0,0 +1,99 @@
+package wordvec
+
+import (
+	"fmt"
+	"math"
+)
+
+// normalizedRow returns a copy of the vector of word i divided by its length, or false for a zero vector.
+func normalizedRow(rows vectorRows, i int) ([]float32, bool) {
+	norm := rows.norm(i)
+	if norm == 0 {
+		return nil, false
+	}
+	vec := make([]float32, rows.Dim())
+	copy(vec, rows.row(i, vec))
+	for d := range vec {
+		vec[d] /= norm
+	}
+	return vec, true
+}
+
+/*
+analogy answers "a is to b as c is to ?" like the original word-analogy tool: the n words nearest to the normalized vectors b - a + c, the three query words left out.
+*/
+func analogy(rows vectorRows, a, b, c string, n int) ([]Neighbor, error) {
+	query := make([]float32, rows.Dim())
+	for k, word := range []string{a, b, c} {
+		i, ok := rows.Index(word)
+		if !ok {
+			return nil, fmt.Errorf("Out of dictionary word: %s", word)
+		}
+		vec, ok := normalizedRow(rows, i)
+		if !ok {
+			continue
+		}
+		sign := float32(1)
+		if k == 0 {
+			sign = -1
+		}
+		for d, x := range vec {
+			query[d] += sign * x
+		}
+	}
+	return mostSimilarVector(rows, query, n, []string{a, b, c}), nil
+}
+
+/*
+oddOneOut returns the word that matches the others least: the one whose vector has the lowest cosine similarity to the mean of the normalized vectors of all the words, as gensim's doesnt_match does. It needs at least three words, all in the vocabulary.
+*/
+func oddOneOut(rows vectorRows, words []string) (string, error) {
+	if len(words) < 3 {
+		return "", fmt.Errorf("Odd one out needs at least 3 words, got %d", len(words))
+	}
+	vecs := make([][]float32, len(words))
+	mean := make([]float32, rows.Dim())
+	for k, word := range words {
+		i, ok := rows.Index(word)
+		if !ok {
+			return "", fmt.Errorf("Out of dictionary word: %s", word)
+		}
+		if vecs[k], ok = normalizedRow(rows, i); !ok {
+			continue
+		}
+		for d, x := range vecs[k] {
+			mean[d] += x
+		}
+	}
+	odd, worst := "", math.Inf(1)
+	for k, vec := range vecs {
+		sim := math.Inf(-1) // a zero vector matches nothing
+		if vec != nil {
+			sim = float64(dot32(vec, mean))
+		}
+		if k == 0 || sim < worst {
+			odd, worst = words[k], sim
+		}
+	}
+	return odd, nil
+}
+
+// Analogy returns the n words that complete "a is to b as c is to ?", nearest to b - a + c; e.g. Analogy("man", "king", "woman", 1) should give "queen".
+func (e *Embeddings) Analogy(a, b, c string, n int) ([]Neighbor, error) {
+	return analogy(e, a, b, c, n)
+}
+
+// OddOneOut returns the word of words that matches the others least.
+func (e *Embeddings) OddOneOut(words []string) (string, error) {
+	return oddOneOut(e, words)
+}
+
+// Analogy returns the n words that complete "a is to b as c is to ?", nearest to b - a + c.
+func (e *MmapEmbeddings) Analogy(a, b, c string, n int) ([]Neighbor, error) {
+	return analogy(e, a, b, c, n)
+}
+
+// OddOneOut returns the word of words that matches the others least.
+func (e *MmapEmbeddings) OddOneOut(words []string) (string, error) {
+	return oddOneOut(e, words)
+}
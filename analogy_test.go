@@ -0,0 +1,75 @@
+package wordvec
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAnalogy(t *testing.T) {
+	words := []string{"man", "woman", "king", "queen", "apple"}
+	e, err := NewEmbeddings(words, 3, []float32{
+		1, 0, 0,
+		0, 0, 1,
+		1, 1, 0,
+		0, 1, 1,
+		0.5, -1, 0.2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nn, err := e.Analogy("man", "king", "woman", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nn) != 2 || nn[0].Word != "queen" || nn[1].Word != "apple" {
+		t.Errorf("man is to king as woman is to queen, then apple, without the query words; got %+v", nn)
+	}
+	if _, err := e.Analogy("man", "king", "princess", 1); err == nil {
+		t.Error("an unknown word should be an error")
+	}
+}
+
+func TestOddOneOut(t *testing.T) {
+	e, err := NewEmbeddings(testEmbeddingWords, 3, testEmbeddingVectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if odd, err := e.OddOneOut([]string{"cat", "car", "dog"}); err != nil || odd != "car" {
+		t.Errorf("car should not match cat and dog, got %q, %v", odd, err)
+	}
+	if odd, err := e.OddOneOut([]string{"cat", "zero", "dog"}); err != nil || odd != "zero" {
+		t.Errorf("a zero vector should match nothing, got %q, %v", odd, err)
+	}
+	if _, err := e.OddOneOut([]string{"cat", "dog"}); err == nil {
+		t.Error("two words should be an error")
+	}
+	if _, err := e.OddOneOut([]string{"cat", "dog", "bus"}); err == nil {
+		t.Error("an unknown word should be an error")
+	}
+}
+
+func TestMmapEmbeddingsAnalogy(t *testing.T) {
+	mv := trainSmallModel(t, IterOption(1))
+	name := filepath.Join(t.TempDir(), "model.wv")
+	if err := mv.SaveFile(name); err != nil {
+		t.Fatal(err)
+	}
+	mapped, err := OpenMmapEmbeddings(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mapped.Close()
+	inMemory, _ := mv.Embeddings()
+	a, b, c := mv.Vocab[3].Word, mv.Vocab[4].Word, mv.Vocab[5].Word
+	want, _ := inMemory.Analogy(a, b, c, 5)
+	got, err := mapped.Analogy(a, b, c, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSameNeighbors(t, want, got)
+	query := []string{a, b, c, mv.Vocab[6].Word}
+	wantOdd, _ := inMemory.OddOneOut(query)
+	if odd, err := mapped.OddOneOut(query); err != nil || odd != wantOdd {
+		t.Errorf("odd one out of %v should be %q, got %q, %v", query, wantOdd, odd, err)
+	}
+}
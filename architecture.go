@@ -0,0 +1,83 @@
+package wordvec
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Architecture selects how a word is trained from its window, see the Architecture field of VectorModel.
+
+The position-aware architectures follow wang2vec (Ling et al., https://aclanthology.org/N15-1142): word order matters for syntax, so both keep separate output weights for each of the 2*WindowSkipLen relative positions of the window. Their vectors are better suited to POS tagging and parsing, at the cost of 2*WindowSkipLen times the output weights.
+*/
+type Architecture int
+
+const (
+	// ArchCBOW predicts a word from the mean of the vectors of its window (continuous bag of words).
+	ArchCBOW Architecture = iota
+	// ArchSkipGram predicts a word from each word of its window in turn.
+	ArchSkipGram
+	// ArchStructuredSkipGram is skip-gram with separate output weights for every relative position of the window, so the prediction of a word depends on where in the window the predicting word is.
+	ArchStructuredSkipGram
+	// ArchCWindow predicts a word from the concatenation of the vectors of its window, in window order, instead of their mean. Positions outside the sentence stay zero.
+	ArchCWindow
+)
+
+// String returns the name of the architecture as used in model configs.
+func (a Architecture) String() string {
+	switch a {
+	case ArchCBOW:
+		return "cbow"
+	case ArchSkipGram:
+		return "skip-gram"
+	case ArchStructuredSkipGram:
+		return "structured-skip-gram"
+	case ArchCWindow:
+		return "cwindow"
+	}
+	return fmt.Sprintf("Architecture(%d)", int(a))
+}
+
+// ParseArchitecture returns the architecture with the given name: "cbow", "skip-gram", "structured-skip-gram" or "cwindow". The names of the wang2vec -type flag values ("0" to "3") are accepted too.
+func ParseArchitecture(name string) (Architecture, error) {
+	switch strings.ToLower(name) {
+	case "cbow", "0":
+		return ArchCBOW, nil
+	case "skip-gram", "skipgram", "sg", "1":
+		return ArchSkipGram, nil
+	case "structured-skip-gram", "structured-skipgram", "ssg", "2":
+		return ArchStructuredSkipGram, nil
+	case "cwindow", "3":
+		return ArchCWindow, nil
+	}
+	return 0, fmt.Errorf("Unknown architecture %q", name)
+}
+
+// positional reports whether the architecture keeps output weights per relative window position.
+func (a Architecture) positional() bool {
+	return a == ArchStructuredSkipGram || a == ArchCWindow
+}
+
+// defaultAlpha is the learning rate of the architecture when none was set: ALPHA_CBOW for the architectures predicting from the whole window, ALPHA_SKIP_GRAM for the others.
+func (a Architecture) defaultAlpha() float64 {
+	if a == ArchCBOW || a == ArchCWindow {
+		return ALPHA_CBOW
+	}
+	return ALPHA_SKIP_GRAM
+}
+
+// outputSize is the number of output weights per word in Syn1 and Syn1neg: Layer1VecSize, or a row of Layer1VecSize for each of the 2*WindowSkipLen window positions of a positional architecture.
+func (v *VectorModel) outputSize() int {
+	if v.Architecture.positional() {
+		return 2 * v.WindowSkipLen * v.Layer1VecSize
+	}
+	return v.Layer1VecSize
+}
+
+// windowOffset is the offset of the relative window position a (0 to 2*WindowSkipLen, the word itself excluded) in the output weights of a word, or in the hidden layer of CWindow.
+func (v *VectorModel) windowOffset(a int) int {
+	if a > v.WindowSkipLen {
+		a--
+	}
+	return a * v.Layer1VecSize
+}
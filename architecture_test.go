@@ -0,0 +1,118 @@
+package wordvec
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseArchitecture(t *testing.T) {
+	for _, a := range []Architecture{ArchCBOW, ArchSkipGram, ArchStructuredSkipGram, ArchCWindow} {
+		got, err := ParseArchitecture(a.String())
+		if err != nil || got != a {
+			t.Errorf("expected %v back from %q, got %v (%v)", a, a.String(), got, err)
+		}
+	}
+	if a, err := ParseArchitecture("2"); err != nil || a != ArchStructuredSkipGram {
+		t.Errorf("expected the wang2vec type 2 to be structured skip-gram, got %v (%v)", a, err)
+	}
+	if _, err := ParseArchitecture("glove"); err == nil {
+		t.Error("expected an error for an unknown architecture")
+	}
+}
+
+func TestArchitectureOption(t *testing.T) {
+	for a, alpha := range map[Architecture]float64{
+		ArchCBOW:               ALPHA_CBOW,
+		ArchSkipGram:           ALPHA_SKIP_GRAM,
+		ArchStructuredSkipGram: ALPHA_SKIP_GRAM,
+		ArchCWindow:            ALPHA_CBOW,
+	} {
+		m, err := NewWord2VecModel("train.txt", "out.txt", VocabHashSizeOption(10), ArchitectureOption(a))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Architecture != a || m.Alpha != alpha {
+			t.Errorf("expected %v with alpha %f, got %v with %f", a, alpha, m.Architecture, m.Alpha)
+		}
+	}
+	m, _ := NewWord2VecModel("train.txt", "out.txt", VocabHashSizeOption(10), AlphaOption(0.01), ArchitectureOption(ArchCWindow))
+	if m.Alpha != 0.01 {
+		t.Error("ArchitectureOption should keep an alpha that was set, got", m.Alpha)
+	}
+	if _, err := NewWord2VecModel("train.txt", "out.txt", VocabHashSizeOption(10), ArchitectureOption(Architecture(7))); err == nil {
+		t.Error("expected an error for an unknown architecture")
+	}
+	if _, err := NewWord2VecModel("train.txt", "out.txt", VocabHashSizeOption(10), ArchitectureOption(ArchStructuredSkipGram), ParagraphVectorsTrue); err == nil {
+		t.Error("expected an error for paragraph vectors with a positional architecture")
+	}
+}
+
+func TestArchitectureConfig(t *testing.T) {
+	m, err := NewWord2VecModel("train.txt", "out.txt", VocabHashSizeOption(10), ArchitectureOption(ArchCWindow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(m.Config(), "Architecture = cwindow\n") || strings.Contains(m.Config(), "Cbow") {
+		t.Errorf("expected the architecture and no Cbow field in the config, got\n%s", m.Config())
+	}
+	params, err := LoadModelConfig(strings.NewReader(m.Config()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, _ := NewWord2VecModel("", "", params...)
+	if replayed.Architecture != ArchCWindow {
+		t.Error("expected cwindow back from the config, got", replayed.Architecture)
+	}
+
+	// configs and native models written before Architecture still have Cbow
+	params, err = LoadModelConfig(strings.NewReader("VocabHashSize = 10\nCbow = false\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, _ := NewWord2VecModel("", "", params...)
+	if legacy.Architecture != ArchSkipGram || legacy.Alpha != ALPHA_SKIP_GRAM {
+		t.Errorf("expected Cbow = false to select skip-gram, got %v with alpha %f", legacy.Architecture, legacy.Alpha)
+	}
+	params, _ = configParams(map[string]string{"VocabHashSize": "10", "Cbow": "false", "Architecture": "structured-skip-gram"})
+	both, _ := NewWord2VecModel("", "", params...)
+	if both.Architecture != ArchStructuredSkipGram {
+		t.Error("Architecture should override Cbow, got", both.Architecture)
+	}
+}
+
+func TestTrainPositionalArchitectures(t *testing.T) {
+	for _, a := range []Architecture{ArchStructuredSkipGram, ArchCWindow} {
+		t.Run(a.String(), func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "vectors.txt")
+			mv := newSmallTrainingModel(t, out, ArchitectureOption(a), WindowSkipLenOption(3), SoftMaxOptionTrue)
+			report, err := mv.TrainModel()
+			if err != nil {
+				t.Fatal(err)
+			}
+			losses := report.EpochLosses()
+			if losses[len(losses)-1] >= losses[0] {
+				t.Errorf("loss should go down during training, got %v", losses)
+			}
+			// one row of output weights per position of the window
+			if len(mv.Syn1neg) != mv.VocabSize*6*20 || len(mv.Syn1) != mv.VocabSize*6*20 {
+				t.Errorf("expected %d output weights, got %d and %d", mv.VocabSize*6*20, len(mv.Syn1neg), len(mv.Syn1))
+			}
+			if e := readTextEmbeddings(t, out); e.Len() != mv.VocabSize || e.Dim() != 20 {
+				t.Errorf("expected %d vectors of size 20, got %d of size %d", mv.VocabSize, e.Len(), e.Dim())
+			}
+
+			name := filepath.Join(t.TempDir(), "model.wv")
+			if err := mv.SaveFile(name); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := LoadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.Architecture != a || len(loaded.Syn1neg) != len(mv.Syn1neg) || len(loaded.Syn1) != len(mv.Syn1) {
+				t.Errorf("expected %v with all output weights after loading, got %v with %d and %d", a, loaded.Architecture, len(loaded.Syn1neg), len(loaded.Syn1))
+			}
+		})
+	}
+}
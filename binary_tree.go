@@ -12,14 +12,14 @@ Huffman encoding is a type of variable-length encoding that is based on the actu
 
 For Huffman trees: http://mathworld.wolfram.com/HuffmanCoding.html, http://www.fas.harvard.edu/~cscie119/lectures/trees.pdf, https://home.cse.ust.hk/~dekai/271/notes/L15/L15.pdf... and many more.
 
-Frequent words will have short unique binary codes
+Frequent words will have short unique binary codes. A code as long as MaxCodeLen does not fit the Code and Point of a word and returns an error.
 */
-func (v *VectorModel) CreateBinaryTree() {
+func (v *VectorModel) CreateBinaryTree() error {
 	//fmt.Fprintf(os.Stdout, "Create Binary Tree %v", time.Now())
 	fmt.Fprintf(os.Stdout, "Create Binary Tree\n")
 	var min1i, min2i, pos1, pos2 int
-	var point []int = make([]int, MAX_CODE_LENGTH)
-	var code []byte = make([]byte, MAX_CODE_LENGTH)
+	var point []int = make([]int, v.MaxCodeLen)
+	var code []byte = make([]byte, v.MaxCodeLen)
 	var count []int64 = make([]int64, v.VocabSize*2+1)
 	var binaryt []int = make([]int, v.VocabSize*2+1)
 	var parentNode []int = make([]int, v.VocabSize*2+1)
@@ -73,6 +73,9 @@ func (v *VectorModel) CreateBinaryTree() {
 			code[i] = byte(binaryt[e])
 			point[i] = e
 			i++
+			if i >= v.MaxCodeLen {
+				return fmt.Errorf("Huffman code of word %q does not fit in MaxCodeLen %d; raise MaxCodeLen", v.Vocab[d].Word, v.MaxCodeLen)
+			}
 			e = parentNode[e]
 			if e == (v.VocabSize*2)-2 {
 				break
@@ -85,4 +88,5 @@ func (v *VectorModel) CreateBinaryTree() {
 			v.Vocab[d].Point[i-j] = point[j] - v.VocabSize
 		}
 	}
+	return nil
 }
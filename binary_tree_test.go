@@ -1,6 +1,10 @@
 package wordvec
 
-import "testing"
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
 
 func TestCreateBinaryTree(t *testing.T) {
 	mv, _ := NewWord2VecModel(
@@ -13,3 +17,31 @@ func TestCreateBinaryTree(t *testing.T) {
 		t.Error("vocab shoudl be 1000, got", len(mv.Vocab))
 	}
 }
+
+// Eight words of equal count need codes of 3 bits, more than a MaxCodeLen of 2 holds.
+func TestCreateBinaryTreeMaxCodeLen(t *testing.T) {
+	for _, maxCodeLen := range []int{2, MAX_CODE_LENGTH} {
+		mv, err := NewWord2VecModel("training_data.txt", "word2vec_output.txt", VocabHashSizeOption(100), MaxCodeLenOption(maxCodeLen))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mv.resetVocabHashIndices()
+		mv.addWordToVocab("</s>")
+		for _, word := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+			mv.Vocab[mv.addWordToVocab(word)].Count = 10
+		}
+		mv.sortVocab()
+		err = mv.CreateBinaryTree()
+		if maxCodeLen == 2 && err == nil {
+			t.Error("expected an error for codes longer than MaxCodeLen 2")
+		}
+		if maxCodeLen == MAX_CODE_LENGTH && err != nil {
+			t.Error(err)
+		}
+	}
+
+	mv := newSmallTrainingModel(t, filepath.Join(t.TempDir(), "out.txt"), SoftMaxOptionTrue, MaxCodeLenOption(2))
+	if _, err := mv.TrainModel(); err == nil || !strings.Contains(err.Error(), "MaxCodeLen") {
+		t.Error("training should fail naming MaxCodeLen, got", err)
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Command distance is the distance tool of the original word2vec: it loads word vectors and answers queries from stdin with the N_DISTANCE nearest words and their cosine similarities.
+
+	distance vectors.bin
+	echo "paris france" | distance -json -n 10 vectors.bin
+
+A query of several words looks up the words nearest to all of them, the normalized sum of their vectors; the query words are left out. EXIT or the end of the input stops. With -json every query is answered with one JSON object per line, {"query": [...], "neighbors": [{"word": ..., "similarity": ...}]}, or {"query": [...], "error": ...} for unknown words, and no prompt is printed.
+*/
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jbowles/wordvec"
+)
+
+// answer is a query answer of the -json mode.
+type answer struct {
+	Query     []string           `json:"query"`
+	Neighbors []wordvec.Neighbor `json:"neighbors,omitempty"`
+	Error     string             `json:"error,omitempty"`
+}
+
+// repl answers the queries read from in until EXIT or the end of the input.
+func repl(e *wordvec.Embeddings, in io.Reader, out io.Writer, n int, jsonOut bool) error {
+	scanner := bufio.NewScanner(in)
+	enc := json.NewEncoder(out)
+	for {
+		if !jsonOut {
+			fmt.Fprintf(out, "Enter word or sentence (EXIT to break): ")
+		}
+		if !scanner.Scan() {
+			break
+		}
+		words := strings.Fields(scanner.Text())
+		if len(words) == 1 && words[0] == "EXIT" {
+			break
+		}
+		if len(words) == 0 {
+			continue
+		}
+		if jsonOut {
+			a := answer{Query: words}
+			neighbors, err := query(e, words, n)
+			if err != nil {
+				a.Error = err.Error()
+			} else {
+				a.Neighbors = neighbors
+			}
+			if err := enc.Encode(a); err != nil {
+				return err
+			}
+			continue
+		}
+		known := true
+		for _, word := range words {
+			i, ok := e.Index(word)
+			if !ok {
+				i = -1
+			}
+			fmt.Fprintf(out, "\nWord: %s  Position in vocabulary: %d\n", word, i)
+			if !ok {
+				fmt.Fprintf(out, "Out of dictionary word!\n")
+				known = false
+				break
+			}
+		}
+		if !known {
+			continue
+		}
+		neighbors, _ := query(e, words, n)
+		fmt.Fprintf(out, "\n                                              Word       Cosine distance\n------------------------------------------------------------------------\n")
+		for _, nb := range neighbors {
+			fmt.Fprintf(out, "%50s\t\t%f\n", nb.Word, nb.Similarity)
+		}
+	}
+	return scanner.Err()
+}
+
+// query returns the n words nearest to the words together; all of them must be known.
+func query(e *wordvec.Embeddings, words []string, n int) ([]wordvec.Neighbor, error) {
+	for _, word := range words {
+		if _, ok := e.Index(word); !ok {
+			return nil, fmt.Errorf("Out of dictionary word: %s", word)
+		}
+	}
+	return e.RecommendSimilar(words, nil, n)
+}
+
+func main() {
+	n := flag.Int("n", wordvec.N_DISTANCE, "Number of closest words to show")
+	format := flag.String("format", "", "Format of FILE: native, text, glove or binary; detected when empty")
+	jsonOut := flag.Bool("json", false, "Answer every query with a line of JSON")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: distance [options] <FILE>\nwhere FILE contains word projections\n\nOptions:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	e, _, err := wordvec.LoadEmbeddingsFile(flag.Arg(0), *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if err := repl(e, os.Stdin, os.Stdout, *n, *jsonOut); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
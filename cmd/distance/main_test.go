@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jbowles/wordvec"
+)
+
+func testEmbeddings(t *testing.T) *wordvec.Embeddings {
+	t.Helper()
+	e, err := wordvec.NewEmbeddings([]string{"cat", "dog", "car", "truck"}, 3, []float32{
+		1, 0.1, 0,
+		0.9, 0.2, 0,
+		0, 0.1, 1,
+		0, 0.3, 0.9,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestRepl(t *testing.T) {
+	var out bytes.Buffer
+	if err := repl(testEmbeddings(t), strings.NewReader("cat\ncat bus\n\ncar truck\nEXIT\ndog\n"), &out, 2, false); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"Enter word or sentence (EXIT to break): \nWord: cat  Position in vocabulary: 0\n",
+		"\n                                              Word       Cosine distance\n------------------------------------------------------------------------\n",
+		strings.Repeat(" ", 47) + "dog\t\t0.",
+		"Word: bus  Position in vocabulary: -1\nOut of dictionary word!\n",
+		"Word: car  Position in vocabulary: 2\n\nWord: truck  Position in vocabulary: 3\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Position in vocabulary: 1\n") {
+		t.Errorf("nothing after EXIT should be answered, got:\n%s", got)
+	}
+	if n := strings.Count(got, "Enter word"); n != 5 {
+		t.Errorf("expected 5 prompts, got %d", n)
+	}
+}
+
+func TestReplJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := repl(testEmbeddings(t), strings.NewReader("cat\ncar bus\n"), &out, 2, true); err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(&out)
+	var a answer
+	if err := dec.Decode(&a); err != nil || len(a.Neighbors) != 2 || a.Neighbors[0].Word != "dog" || a.Error != "" {
+		t.Errorf("expected dog nearest to cat, got %+v, %v", a, err)
+	}
+	a = answer{}
+	if err := dec.Decode(&a); err != nil || a.Error == "" || len(a.Query) != 2 || a.Neighbors != nil {
+		t.Errorf("an unknown word should be an error, got %+v, %v", a, err)
+	}
+	if dec.More() {
+		t.Error("expected one line per query and no prompt")
+	}
+}
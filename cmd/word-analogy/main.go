@@ -0,0 +1,113 @@
+/*
+Command word-analogy is the word-analogy tool of the original word2vec: it loads word vectors and, for three words "a b c" read from stdin, prints the N_ANALOGY words nearest to b - a + c, the answers to "a is to b as c is to ?".
+
+	word-analogy vectors.bin
+	echo "man king woman" | word-analogy -json -n 1 vectors.bin
+
+The query words are left out of the answers. EXIT or the end of the input stops. With -json every query is answered with one JSON object per line, {"query": [...], "neighbors": [{"word": ..., "similarity": ...}]}, or {"query": [...], "error": ...} for unknown words and queries of other than three words, and no prompt is printed.
+*/
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jbowles/wordvec"
+)
+
+// answer is a query answer of the -json mode.
+type answer struct {
+	Query     []string           `json:"query"`
+	Neighbors []wordvec.Neighbor `json:"neighbors,omitempty"`
+	Error     string             `json:"error,omitempty"`
+}
+
+// repl answers the queries read from in until EXIT or the end of the input.
+func repl(e *wordvec.Embeddings, in io.Reader, out io.Writer, n int, jsonOut bool) error {
+	scanner := bufio.NewScanner(in)
+	enc := json.NewEncoder(out)
+	for {
+		if !jsonOut {
+			fmt.Fprintf(out, "Enter three words (EXIT to break): ")
+		}
+		if !scanner.Scan() {
+			break
+		}
+		words := strings.Fields(scanner.Text())
+		if len(words) == 1 && words[0] == "EXIT" {
+			break
+		}
+		if len(words) == 0 {
+			continue
+		}
+		if jsonOut {
+			a := answer{Query: words}
+			if len(words) != 3 {
+				a.Error = fmt.Sprintf("Got %d words, three words are needed", len(words))
+			} else if neighbors, err := e.Analogy(words[0], words[1], words[2], n); err != nil {
+				a.Error = err.Error()
+			} else {
+				a.Neighbors = neighbors
+			}
+			if err := enc.Encode(a); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(words) != 3 {
+			fmt.Fprintf(out, "Only %d words were entered.. three words are needed at the input to perform the calculation\n", len(words))
+			continue
+		}
+		known := true
+		for _, word := range words {
+			i, ok := e.Index(word)
+			if !ok {
+				i = -1
+			}
+			fmt.Fprintf(out, "\nWord: %s  Position in vocabulary: %d\n", word, i)
+			if !ok {
+				fmt.Fprintf(out, "Out of dictionary word!\n")
+				known = false
+				break
+			}
+		}
+		if !known {
+			continue
+		}
+		neighbors, _ := e.Analogy(words[0], words[1], words[2], n)
+		fmt.Fprintf(out, "\n                                              Word              Distance\n------------------------------------------------------------------------\n")
+		for _, nb := range neighbors {
+			fmt.Fprintf(out, "%50s\t\t%f\n", nb.Word, nb.Similarity)
+		}
+	}
+	return scanner.Err()
+}
+
+func main() {
+	n := flag.Int("n", wordvec.N_ANALOGY, "Number of closest words to show")
+	format := flag.String("format", "", "Format of FILE: native, text, glove or binary; detected when empty")
+	jsonOut := flag.Bool("json", false, "Answer every query with a line of JSON")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: word-analogy [options] <FILE>\nwhere FILE contains word projections\n\nOptions:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	e, _, err := wordvec.LoadEmbeddingsFile(flag.Arg(0), *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if err := repl(e, os.Stdin, os.Stdout, *n, *jsonOut); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
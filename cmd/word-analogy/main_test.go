@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jbowles/wordvec"
+)
+
+func testEmbeddings(t *testing.T) *wordvec.Embeddings {
+	t.Helper()
+	e, err := wordvec.NewEmbeddings([]string{"man", "woman", "king", "queen", "apple"}, 3, []float32{
+		1, 0, 0,
+		0, 0, 1,
+		1, 1, 0,
+		0, 1, 1,
+		0.5, -1, 0.2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestRepl(t *testing.T) {
+	var out bytes.Buffer
+	if err := repl(testEmbeddings(t), strings.NewReader("man king woman\nman king\nman king prince\nEXIT\n"), &out, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"Enter three words (EXIT to break): \nWord: man  Position in vocabulary: 0\n\nWord: king  Position in vocabulary: 2\n\nWord: woman  Position in vocabulary: 1\n",
+		"\n                                              Word              Distance\n------------------------------------------------------------------------\n" + strings.Repeat(" ", 45) + "queen\t\t0.",
+		"Only 2 words were entered.. three words are needed at the input to perform the calculation\n",
+		"Word: prince  Position in vocabulary: -1\nOut of dictionary word!\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "apple") {
+		t.Errorf("only the nearest word should be shown, got:\n%s", got)
+	}
+}
+
+func TestReplJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := repl(testEmbeddings(t), strings.NewReader("man king woman\nman king\n"), &out, 2, true); err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(&out)
+	var a answer
+	if err := dec.Decode(&a); err != nil || len(a.Neighbors) != 2 || a.Neighbors[0].Word != "queen" {
+		t.Errorf("expected queen, got %+v, %v", a, err)
+	}
+	a = answer{}
+	if err := dec.Decode(&a); err != nil || a.Error == "" {
+		t.Errorf("two words should be an error, got %+v, %v", a, err)
+	}
+}
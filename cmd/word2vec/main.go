@@ -0,0 +1,147 @@
+/*
+Command word2vec trains word vectors with the flags of the original C word2vec tool, so scripts written for it run unchanged:
+
+	word2vec -train text8 -output vectors.bin -cbow 1 -size 200 -window 8 -negative 25 -hs 0 -sample 1e-4 -threads 20 -binary 1 -iter 15
+
+As in the C tool, boolean flags take 0 or 1, -alpha defaults to 0.05 for CBOW and 0.025 for skip-gram, and without -output only the vocabulary is learned (and saved with -save-vocab).
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jbowles/wordvec"
+)
+
+// command holds the parsed flags of a word2vec run.
+type command struct {
+	trainFile string
+	outFile   string
+	params    []wordvec.ModelParams
+}
+
+// parseFlags maps the flags of the C word2vec tool onto the options of the model. Errors are also printed to output, with the usage for unknown flags.
+func parseFlags(args []string, output io.Writer) (*command, error) {
+	fs := flag.NewFlagSet("word2vec", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintf(output, "WORD VECTOR estimation toolkit\n\nOptions:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(output, "\nExamples:\n./word2vec -train data.txt -output vec.txt -size 200 -window 5 -sample 1e-4 -negative 5 -hs 0 -binary 0 -cbow 1 -iter 3\n")
+	}
+	c := &command{}
+	fs.StringVar(&c.trainFile, "train", "", "Use text data from <file> to train the model")
+	fs.StringVar(&c.outFile, "output", "", "Use <file> to save the resulting word vectors / word clusters")
+	size := fs.Int("size", wordvec.LAYER1_VEC_SIZE, "Set size of word vectors")
+	window := fs.Int("window", wordvec.WINDOW_SKIP_LEN, "Set max skip length between words")
+	sample := fs.Float64("sample", wordvec.SAMPLE, "Set threshold for occurrence of words. Those that appear with higher frequency in the training data will be randomly down-sampled; useful range is (0, 1e-5)")
+	hs := fs.Int("hs", 0, "Use Hierarchical Softmax (0 = not used)")
+	negative := fs.Int("negative", wordvec.NEG_SAMPLING, "Number of negative examples; common values are 3 - 10 (0 = not used)")
+	threads := fs.Int("threads", wordvec.NUM_THREADS, "Use <int> threads")
+	iter := fs.Int("iter", wordvec.ITER, "Run more training iterations")
+	minCount := fs.Int("min-count", wordvec.MIN_COUNT, "This will discard words that appear less than <int> times")
+	alpha := fs.Float64("alpha", wordvec.ALPHA_CBOW, "Set the starting learning rate; default is 0.025 for skip-gram and 0.05 for CBOW")
+	classes := fs.Int("classes", wordvec.KMEANS_CLASSES, "Output word classes rather than word vectors; default number of classes is 0 (vectors are written)")
+	debug := fs.Int("debug", wordvec.DEBUG_MODE, "Set the debug mode (2 = more info during training)")
+	binary := fs.Int("binary", 0, "Save the resulting vectors in binary mode (0 = off)")
+	saveVocab := fs.String("save-vocab", "", "The vocabulary will be saved to <file>")
+	readVocab := fs.String("read-vocab", "", "The vocabulary will be read from <file>, not constructed from the training data")
+	cbow := fs.Int("cbow", 1, "Use the continuous bag of words model; 0 uses skip-gram")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		err := fmt.Errorf("Unexpected argument %q", fs.Arg(0))
+		fmt.Fprintln(output, err)
+		return nil, err
+	}
+	if c.trainFile == "" {
+		err := errors.New("No training file given with -train")
+		fmt.Fprintln(output, err)
+		return nil, err
+	}
+
+	architecture := wordvec.ArchCBOW
+	if *cbow == 0 {
+		architecture = wordvec.ArchSkipGram
+	}
+	c.params = []wordvec.ModelParams{
+		wordvec.ArchitectureOption(architecture),
+		wordvec.Layer1VecSizeOption(*size),
+		wordvec.WindowSkipLenOption(*window),
+		wordvec.SampleOption(*sample),
+		wordvec.NegSamplingOption(*negative),
+		wordvec.NumThreadsOption(*threads),
+		wordvec.IterOption(*iter),
+		wordvec.MinCountOption(*minCount),
+		wordvec.KmeansClassesOption(*classes),
+		wordvec.DebugModeOption(*debug),
+		wordvec.VocabOutFileOption(*saveVocab),
+		wordvec.VocabInFileOption(*readVocab),
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "alpha" {
+			c.params = append(c.params, wordvec.AlphaOption(*alpha))
+		}
+	})
+	if *hs != 0 {
+		c.params = append(c.params, wordvec.SoftMaxOptionTrue)
+	}
+	if *binary != 0 {
+		// BinaryFileTrue insists on a .bin output file; the C tool writes binary vectors to any name.
+		c.params = append(c.params, func(v *wordvec.VectorModel) error {
+			v.Binaryf = true
+			return nil
+		})
+	}
+	return c, nil
+}
+
+// run trains the model, or only learns and saves the vocabulary without an output file, as the C tool does.
+func (c *command) run() error {
+	model, err := wordvec.NewWord2VecModel(c.trainFile, c.outFile, c.params...)
+	if err != nil {
+		return err
+	}
+	if c.outFile != "" {
+		_, err := model.TrainModel()
+		return err
+	}
+	if err := model.Validate(); err != nil {
+		return err
+	}
+	if model.VocabInFile != "" {
+		err = model.ReadVocab()
+	} else {
+		err = model.LearnVocabFromTrainFile()
+	}
+	if err != nil {
+		return err
+	}
+	if model.VocabOutFile != "" {
+		return model.SaveVocab()
+	}
+	return nil
+}
+
+func main() {
+	if len(os.Args) == 1 {
+		parseFlags([]string{"-h"}, os.Stdout)
+		return
+	}
+	c, err := parseFlags(os.Args[1:], os.Stderr)
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+	if err := c.run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
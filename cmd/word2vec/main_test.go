@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jbowles/wordvec"
+)
+
+func newModel(t *testing.T, c *command) *wordvec.VectorModel {
+	t.Helper()
+	v, err := wordvec.NewWord2VecModel(c.trainFile, c.outFile, append(c.params, wordvec.VocabHashSizeOption(1000))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestParseFlags(t *testing.T) {
+	var out bytes.Buffer
+	c, err := parseFlags(strings.Fields("-train text8 -output vectors.txt -cbow 0 -size 200 -window 8 -negative 25 -hs 1 -sample 1e-4 -threads 20 -binary 1 -iter 15 -min-count 3 -classes 500 -debug 1 -save-vocab vocab.txt -read-vocab in.txt"), &out)
+	if err != nil {
+		t.Fatal(err, out.String())
+	}
+	v := newModel(t, c)
+	if v.TrainFile != "text8" || v.OutputFile != "vectors.txt" || v.Architecture != wordvec.ArchSkipGram || v.Layer1VecSize != 200 || v.WindowSkipLen != 8 ||
+		v.NegSampling != 25 || !v.SoftMax || v.Sample != 1e-4 || v.NumThreads != 20 || !v.Binaryf || v.Iter != 15 || v.MinCount != 3 ||
+		v.KmeansClasses != 500 || v.DebugMode != 1 || v.VocabOutFile != "vocab.txt" || v.VocabInFile != "in.txt" {
+		t.Errorf("flags not mapped onto the model: %+v", v)
+	}
+	if v.Alpha != wordvec.ALPHA_SKIP_GRAM {
+		t.Errorf("-cbow 0 should default alpha to %g, got %g", wordvec.ALPHA_SKIP_GRAM, v.Alpha)
+	}
+
+	c, err = parseFlags(strings.Fields("-train text8 -alpha 0.01 -cbow 1"), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := newModel(t, c); v.Alpha != 0.01 || v.Architecture != wordvec.ArchCBOW || v.Binaryf || v.SoftMax {
+		t.Errorf("expected CBOW with alpha 0.01 and the defaults, got %+v", v)
+	}
+
+	for _, args := range []string{"-output vectors.txt", "-train text8 -bogus 1", "-train text8 extra"} {
+		out.Reset()
+		if _, err := parseFlags(strings.Fields(args), &out); err == nil || out.Len() == 0 {
+			t.Errorf("%q should be an error printed to the output, got %v %q", args, err, out.String())
+		}
+	}
+}
+
+func TestSaveVocabOnly(t *testing.T) {
+	vocab := filepath.Join(t.TempDir(), "vocab.txt")
+	var out bytes.Buffer
+	c, err := parseFlags([]string{"-train", "../../testdata/train_small.txt", "-save-vocab", vocab, "-min-count", "1", "-debug", "0"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.params = append(c.params, wordvec.VocabHashSizeOption(5000))
+	if err := c.run(); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.ReadFile(vocab)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(saved), "\n"); lines < 10 {
+		t.Errorf("without -output the vocabulary should still be saved, got %d words", lines)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Command wordvec-server serves the vectors of a model file over HTTP, see package server for the endpoints.
+
+	wordvec-server -model vectors.bin -addr :8080 -reload-interval 30s
+	wordvec-server -job-dir jobs -workers 2
+
+With -job-dir models can be trained with POST /jobs and the vectors of every finished job are served; -model is optional then.
+
+SIGHUP reloads the model file; SIGINT and SIGTERM stop accepting connections and exit once running requests are done.
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jbowles/wordvec/server"
+)
+
+func main() {
+	var cfg server.Config
+	addr := flag.String("addr", ":8080", "Address to listen on")
+	flag.StringVar(&cfg.ModelFile, "model", "", "Model file to serve")
+	flag.StringVar(&cfg.Format, "format", "", "Format of the model file: native, text, glove or binary; detected when empty")
+	flag.Int64Var(&cfg.MaxBodyBytes, "max-body", server.DEFAULT_MAX_BODY_BYTES, "Largest request body in bytes")
+	flag.IntVar(&cfg.MaxResults, "max-results", server.DEFAULT_MAX_RESULTS, "Largest number of neighbors a query may ask for")
+	flag.DurationVar(&cfg.ReloadInterval, "reload-interval", 0, "How often to check the model file for changes; 0 disables the check")
+	flag.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", server.DEFAULT_SHUTDOWN_TIMEOUT, "How long to wait for running requests on shutdown")
+	flag.StringVar(&cfg.JobDir, "job-dir", "", "Directory for the vectors of training jobs; empty disables the jobs endpoints")
+	flag.IntVar(&cfg.Workers, "workers", server.DEFAULT_WORKERS, "Training jobs run at the same time")
+	flag.IntVar(&cfg.MaxQueuedJobs, "max-queued-jobs", server.DEFAULT_MAX_QUEUED_JOBS, "Training jobs that can wait for a worker")
+	flag.IntVar(&cfg.JobThreads, "job-threads", 0, "Most threads of a training job; 0 shares GOMAXPROCS between the workers")
+	flag.Parse()
+
+	s, err := server.New(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := s.Reload(); err != nil {
+				fmt.Fprintf(os.Stderr, "Reload failed: %v\n", err)
+			}
+		}
+	}()
+
+	fmt.Fprintf(os.Stdout, "Listening on %s\n", *addr)
+	if err := s.ListenAndServe(ctx, *addr); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
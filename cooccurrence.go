@@ -0,0 +1,459 @@
+package wordvec
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+)
+
+const (
+	// COOCCURRENCE_MAX_ENTRIES is the number of distinct co-occurrences counted in memory before they are spilled to disk; each takes about 50 bytes.
+	COOCCURRENCE_MAX_ENTRIES int = 10000000
+	// cooccurrenceRecordSize is the size of a record on disk: two int32 word ids and a float64 count, little endian.
+	cooccurrenceRecordSize = 16
+)
+
+// CooccurrenceRecord is one nonzero entry of a word-context co-occurrence matrix, Word and Context are vocabulary indices.
+type CooccurrenceRecord struct {
+	Word    int32
+	Context int32
+	Count   float64
+}
+
+/*
+CooccurrenceConfig holds the parameters of CountCooccurrences. Zero values take the defaults.
+
+	Window				Words on each side of a word counted as its contexts; default is WindowSkipLen of the model.
+	DistanceWeighting	Counts a context d words away as 1/d instead of 1, as GloVe does.
+	MaxEntries			Distinct co-occurrences kept in memory before spilling a sorted run to disk; default is COOCCURRENCE_MAX_ENTRIES.
+	TempDir				Directory of the spill files; default is os.TempDir().
+*/
+type CooccurrenceConfig struct {
+	Window            int
+	DistanceWeighting bool
+	MaxEntries        int
+	TempDir           string
+}
+
+func (cfg CooccurrenceConfig) withDefaults(v *VectorModel) CooccurrenceConfig {
+	if cfg.Window == 0 {
+		cfg.Window = v.WindowSkipLen
+	}
+	if cfg.MaxEntries == 0 {
+		cfg.MaxEntries = COOCCURRENCE_MAX_ENTRIES
+	}
+	return cfg
+}
+
+/*
+CooccurrenceFile is a co-occurrence matrix stored on disk as fixed size records, so that it can be larger than memory. CountCooccurrences writes it sorted by word and context with every pair once; Shuffle writes a copy in random order for stochastic training. Remove it when done.
+*/
+type CooccurrenceFile struct {
+	Path string
+	len  int64
+}
+
+// Len returns the number of records.
+func (cf *CooccurrenceFile) Len() int64 { return cf.len }
+
+// Remove deletes the file.
+func (cf *CooccurrenceFile) Remove() error { return os.Remove(cf.Path) }
+
+// Each calls fn for every record in file order and stops at the first error.
+func (cf *CooccurrenceFile) Each(fn func(r CooccurrenceRecord) error) error {
+	return cf.EachRange(0, cf.len, fn)
+}
+
+// EachRange calls fn for the records from index start up to end, so that goroutines can work on separate parts of the file.
+func (cf *CooccurrenceFile) EachRange(start, end int64, fn func(r CooccurrenceRecord) error) error {
+	f, err := os.Open(cf.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err = f.Seek(start*cooccurrenceRecordSize, io.SeekStart); err != nil {
+		return err
+	}
+	r := &cooccurrenceReader{r: bufio.NewReader(f)}
+	for i := start; i < end; i++ {
+		rec, err := r.read()
+		if err == io.EOF {
+			return fmt.Errorf("Co-occurrence file %s ends after %d records, expected %d", cf.Path, i, cf.len)
+		}
+		if err != nil {
+			return err
+		}
+		if err = fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type cooccurrenceReader struct {
+	r   *bufio.Reader
+	buf [cooccurrenceRecordSize]byte
+}
+
+func (cr *cooccurrenceReader) read() (CooccurrenceRecord, error) {
+	if _, err := io.ReadFull(cr.r, cr.buf[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return CooccurrenceRecord{}, errors.New("Co-occurrence file ends in the middle of a record")
+		}
+		return CooccurrenceRecord{}, err
+	}
+	return CooccurrenceRecord{
+		Word:    int32(binary.LittleEndian.Uint32(cr.buf[0:])),
+		Context: int32(binary.LittleEndian.Uint32(cr.buf[4:])),
+		Count:   math.Float64frombits(binary.LittleEndian.Uint64(cr.buf[8:])),
+	}, nil
+}
+
+// cooccurrenceWriter writes records to a new temporary file.
+type cooccurrenceWriter struct {
+	f   *os.File
+	w   *bufio.Writer
+	n   int64
+	buf [cooccurrenceRecordSize]byte
+}
+
+func newCooccurrenceWriter(dir, pattern string) (*cooccurrenceWriter, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &cooccurrenceWriter{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (cw *cooccurrenceWriter) write(r CooccurrenceRecord) error {
+	binary.LittleEndian.PutUint32(cw.buf[0:], uint32(r.Word))
+	binary.LittleEndian.PutUint32(cw.buf[4:], uint32(r.Context))
+	binary.LittleEndian.PutUint64(cw.buf[8:], math.Float64bits(r.Count))
+	cw.n++
+	_, err := cw.w.Write(cw.buf[:])
+	return err
+}
+
+// close finishes the file and returns it, or removes it on error.
+func (cw *cooccurrenceWriter) close() (*CooccurrenceFile, error) {
+	err := cw.w.Flush()
+	if cerr := cw.f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(cw.f.Name())
+		return nil, err
+	}
+	return &CooccurrenceFile{Path: cw.f.Name(), len: cw.n}, nil
+}
+
+// abort closes and removes an unfinished file.
+func (cw *cooccurrenceWriter) abort() {
+	cw.f.Close()
+	os.Remove(cw.f.Name())
+}
+
+func cooccurrenceKey(word, context int) uint64 {
+	return uint64(word)<<32 | uint64(uint32(context))
+}
+
+// spillCooccurrences writes the counts sorted by word and context to a temporary file.
+func spillCooccurrences(counts map[uint64]float64, dir string) (*CooccurrenceFile, error) {
+	keys := make([]uint64, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	cw, err := newCooccurrenceWriter(dir, "wordvec-cooccur-*.bin")
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		if err = cw.write(CooccurrenceRecord{Word: int32(k >> 32), Context: int32(uint32(k)), Count: counts[k]}); err != nil {
+			cw.abort()
+			return nil, err
+		}
+	}
+	return cw.close()
+}
+
+/*
+CountCooccurrences counts how often every pair of vocabulary words occurs within Window words of each other in TrainFile, using the vocabulary of the model (learn or read it first). Counts are symmetric, windows do not cross sentence ends ("</s>", i.e. line ends) and words missing from the vocabulary are skipped.
+
+Counts are accumulated in memory; whenever MaxEntries distinct pairs are held they are spilled to disk as a sorted run and the runs are merged at the end, so the memory use does not grow with the corpus. The returned file is sorted by word and context.
+*/
+func (v *VectorModel) CountCooccurrences(cfg CooccurrenceConfig) (*CooccurrenceFile, error) {
+	cfg = cfg.withDefaults(v)
+	if cfg.Window < 1 || cfg.MaxEntries < 1 {
+		return nil, fmt.Errorf("Co-occurrence window and MaxEntries must be at least 1, got %d and %d", cfg.Window, cfg.MaxEntries)
+	}
+	if v.VocabSize < 2 {
+		return nil, errors.New("Model has no vocabulary to count co-occurrences over")
+	}
+	fmt.Fprintf(os.Stdout, "Counting co-occurrences in file: %s\n", v.TrainFile)
+	f, err := os.Open(v.TrainFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fin := bufio.NewReader(f)
+
+	var runs []*CooccurrenceFile
+	removeRuns := func() {
+		for _, run := range runs {
+			run.Remove()
+		}
+	}
+	counts := make(map[uint64]float64)
+	history := make([]int, 0, cfg.Window) // the last Window words of the sentence, most recent last
+	var words int64
+	for {
+		word, rerr := v.ReadWordIndex(fin)
+		if rerr == io.EOF {
+			break
+		}
+		if word == -1 {
+			continue
+		}
+		if word == 0 {
+			history = history[:0]
+			continue
+		}
+		words++
+		if (v.DebugMode > 1) && (words%1000000 == 0) {
+			fmt.Fprintf(os.Stdout, "%dM words, %d runs%c", words/1000000, len(runs), 13)
+		}
+		for i, context := range history {
+			weight := 1.0
+			if cfg.DistanceWeighting {
+				weight = 1 / float64(len(history)-i)
+			}
+			counts[cooccurrenceKey(word, context)] += weight
+			counts[cooccurrenceKey(context, word)] += weight
+		}
+		if len(history) == cfg.Window {
+			copy(history, history[1:])
+			history = history[:cfg.Window-1]
+		}
+		history = append(history, word)
+		if len(counts) >= cfg.MaxEntries {
+			run, err := spillCooccurrences(counts, cfg.TempDir)
+			if err != nil {
+				removeRuns()
+				return nil, err
+			}
+			runs = append(runs, run)
+			counts = make(map[uint64]float64)
+		}
+	}
+	if len(runs) == 0 || len(counts) > 0 {
+		run, err := spillCooccurrences(counts, cfg.TempDir)
+		if err != nil {
+			removeRuns()
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	counts = nil
+	if len(runs) == 1 {
+		return runs[0], nil
+	}
+	merged, err := mergeCooccurrenceRuns(runs, cfg.TempDir)
+	removeRuns()
+	if err == nil && v.DebugMode > 0 {
+		fmt.Fprintf(os.Stdout, "Co-occurrences: %d from %d runs\n", merged.Len(), len(runs))
+	}
+	return merged, err
+}
+
+// runHead is the next record of a sorted run during a merge.
+type runHead struct {
+	rec CooccurrenceRecord
+	run int
+}
+
+type runHeap []runHead
+
+func (h runHeap) Len() int { return len(h) }
+func (h runHeap) Less(i, j int) bool {
+	return cooccurrenceKey(int(h[i].rec.Word), int(h[i].rec.Context)) < cooccurrenceKey(int(h[j].rec.Word), int(h[j].rec.Context))
+}
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(runHead)) }
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// mergeCooccurrenceRuns merges sorted runs into one sorted file, summing the counts of pairs found in several runs.
+func mergeCooccurrenceRuns(runs []*CooccurrenceFile, dir string) (*CooccurrenceFile, error) {
+	readers := make([]*cooccurrenceReader, len(runs))
+	h := make(runHeap, 0, len(runs))
+	for i, run := range runs {
+		f, err := os.Open(run.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		readers[i] = &cooccurrenceReader{r: bufio.NewReader(f)}
+		rec, err := readers[i].read()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		h = append(h, runHead{rec: rec, run: i})
+	}
+	heap.Init(&h)
+	cw, err := newCooccurrenceWriter(dir, "wordvec-cooccur-*.bin")
+	if err != nil {
+		return nil, err
+	}
+	var current CooccurrenceRecord
+	started := false
+	for h.Len() > 0 {
+		head := h[0]
+		if started && head.rec.Word == current.Word && head.rec.Context == current.Context {
+			current.Count += head.rec.Count
+		} else {
+			if started {
+				if err = cw.write(current); err != nil {
+					cw.abort()
+					return nil, err
+				}
+			}
+			current, started = head.rec, true
+		}
+		rec, rerr := readers[head.run].read()
+		if rerr == io.EOF {
+			heap.Pop(&h)
+			continue
+		}
+		if rerr != nil {
+			cw.abort()
+			return nil, rerr
+		}
+		h[0].rec = rec
+		heap.Fix(&h, 0)
+	}
+	if started {
+		if err = cw.write(current); err != nil {
+			cw.abort()
+			return nil, err
+		}
+	}
+	return cw.close()
+}
+
+/*
+Shuffle writes the records in random order to a new file in dir, as GloVe does before training so that stochastic updates do not follow the order of the vocabulary. Blocks of maxEntries records are shuffled in memory and spilled, then the blocks are interleaved at random in proportion to the records they have left, which gives a uniform shuffle with bounded memory.
+*/
+func (cf *CooccurrenceFile) Shuffle(seed int64, maxEntries int, dir string) (*CooccurrenceFile, error) {
+	if maxEntries < 1 {
+		maxEntries = COOCCURRENCE_MAX_ENTRIES
+	}
+	rnd := rand.New(rand.NewSource(seed))
+	var blocks []*CooccurrenceFile
+	removeBlocks := func() {
+		for _, b := range blocks {
+			b.Remove()
+		}
+	}
+	block := make([]CooccurrenceRecord, 0, minInt64(int64(maxEntries), cf.len))
+	spill := func() error {
+		rnd.Shuffle(len(block), func(i, j int) { block[i], block[j] = block[j], block[i] })
+		cw, err := newCooccurrenceWriter(dir, "wordvec-shuffle-*.bin")
+		if err != nil {
+			return err
+		}
+		for _, r := range block {
+			if err = cw.write(r); err != nil {
+				cw.abort()
+				return err
+			}
+		}
+		b, err := cw.close()
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, b)
+		block = block[:0]
+		return nil
+	}
+	err := cf.Each(func(r CooccurrenceRecord) error {
+		block = append(block, r)
+		if len(block) == maxEntries {
+			return spill()
+		}
+		return nil
+	})
+	if err == nil && (len(block) > 0 || len(blocks) == 0) {
+		err = spill()
+	}
+	if err != nil {
+		removeBlocks()
+		return nil, err
+	}
+	if len(blocks) == 1 {
+		return blocks[0], nil
+	}
+	shuffled, err := interleaveBlocks(blocks, rnd, dir)
+	removeBlocks()
+	return shuffled, err
+}
+
+// interleaveBlocks writes the records of the shuffled blocks to one file, drawing the block of every record at random weighted by the records each block has left.
+func interleaveBlocks(blocks []*CooccurrenceFile, rnd *rand.Rand, dir string) (*CooccurrenceFile, error) {
+	readers := make([]*cooccurrenceReader, len(blocks))
+	left := make([]int64, len(blocks))
+	var total int64
+	for i, b := range blocks {
+		f, err := os.Open(b.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		readers[i] = &cooccurrenceReader{r: bufio.NewReader(f)}
+		left[i] = b.len
+		total += b.len
+	}
+	cw, err := newCooccurrenceWriter(dir, "wordvec-shuffle-*.bin")
+	if err != nil {
+		return nil, err
+	}
+	for ; total > 0; total-- {
+		n := rnd.Int63n(total)
+		i := 0
+		for n >= left[i] {
+			n -= left[i]
+			i++
+		}
+		left[i]--
+		rec, rerr := readers[i].read()
+		if rerr == nil {
+			rerr = cw.write(rec)
+		}
+		if rerr != nil {
+			cw.abort()
+			return nil, rerr
+		}
+	}
+	return cw.close()
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
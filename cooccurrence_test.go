@@ -0,0 +1,145 @@
+package wordvec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newCooccurrenceModel returns a model with the vocabulary of a small text.
+func newCooccurrenceModel(t *testing.T, text string) *VectorModel {
+	name := filepath.Join(t.TempDir(), "corpus.txt")
+	if err := os.WriteFile(name, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mv, err := NewWord2VecModel(name, "", VocabHashSizeOption(1000), MinCountOption(1), DebugModeOption(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mv.LearnVocabFromTrainFile(); err != nil {
+		t.Fatal(err)
+	}
+	return mv
+}
+
+func readCooccurrences(t *testing.T, mv *VectorModel, cf *CooccurrenceFile) map[[2]string]float64 {
+	counts := make(map[[2]string]float64)
+	err := cf.Each(func(r CooccurrenceRecord) error {
+		counts[[2]string{mv.Vocab[r.Word].Word, mv.Vocab[r.Context].Word}] += r.Count
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return counts
+}
+
+func TestCountCooccurrences(t *testing.T) {
+	mv := newCooccurrenceModel(t, "a b c\na b\n")
+	cf, err := mv.CountCooccurrences(CooccurrenceConfig{Window: 2, DistanceWeighting: true, TempDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cf.Remove()
+	counts := readCooccurrences(t, mv, cf)
+	want := map[[2]string]float64{
+		{"a", "b"}: 2, {"b", "a"}: 2,
+		{"b", "c"}: 1, {"c", "b"}: 1,
+		{"a", "c"}: 0.5, {"c", "a"}: 0.5,
+	}
+	if len(counts) != len(want) || cf.Len() != int64(len(want)) {
+		t.Fatalf("expected %v, got %v", want, counts)
+	}
+	for pair, n := range want {
+		if counts[pair] != n {
+			t.Errorf("expected %v for %v, got %v", n, pair, counts[pair])
+		}
+	}
+
+	// the file is sorted by word and context, every pair once
+	var last uint64
+	first := true
+	cf.Each(func(r CooccurrenceRecord) error {
+		key := cooccurrenceKey(int(r.Word), int(r.Context))
+		if !first && key <= last {
+			t.Errorf("records are not sorted and unique at %v", r)
+		}
+		last, first = key, false
+		return nil
+	})
+}
+
+func TestCountCooccurrencesSpills(t *testing.T) {
+	mv := newCooccurrenceModel(t, "")
+	mv.TrainFile = testFileForTraining
+	if err := mv.LearnVocabFromTrainFile(); err != nil {
+		t.Fatal(err)
+	}
+	inMemory, err := mv.CountCooccurrences(CooccurrenceConfig{TempDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inMemory.Remove()
+	dir := t.TempDir()
+	spilled, err := mv.CountCooccurrences(CooccurrenceConfig{MaxEntries: 50, TempDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spilled.Remove()
+	if files, _ := os.ReadDir(dir); len(files) != 1 {
+		t.Errorf("expected the spilled runs to be removed after merging, got %d files", len(files))
+	}
+	want, got := readCooccurrences(t, mv, inMemory), readCooccurrences(t, mv, spilled)
+	if inMemory.Len() != spilled.Len() || len(want) != len(got) {
+		t.Fatalf("expected %d records after merging, got %d", inMemory.Len(), spilled.Len())
+	}
+	for pair, n := range want {
+		if got[pair] != n {
+			t.Errorf("expected %v for %v after merging, got %v", n, pair, got[pair])
+		}
+	}
+}
+
+func TestCooccurrenceShuffle(t *testing.T) {
+	mv := newCooccurrenceModel(t, "")
+	mv.TrainFile = testFileForTraining
+	if err := mv.LearnVocabFromTrainFile(); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	cf, err := mv.CountCooccurrences(CooccurrenceConfig{TempDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cf.Remove()
+	shuffled, err := cf.Shuffle(1, 100, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shuffled.Remove()
+	if shuffled.Len() != cf.Len() {
+		t.Fatalf("expected %d shuffled records, got %d", cf.Len(), shuffled.Len())
+	}
+	want, got := readCooccurrences(t, mv, cf), readCooccurrences(t, mv, shuffled)
+	for pair, n := range want {
+		if got[pair] != n {
+			t.Fatalf("shuffling should keep every record, %v has %v instead of %v", pair, got[pair], n)
+		}
+	}
+	inOrder := 0
+	var last uint64
+	shuffled.Each(func(r CooccurrenceRecord) error {
+		key := cooccurrenceKey(int(r.Word), int(r.Context))
+		if key > last {
+			inOrder++
+		}
+		last = key
+		return nil
+	})
+	if inOrder > int(cf.Len())*3/4 {
+		t.Errorf("records should not stay in order, %d of %d are", inOrder, cf.Len())
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 2 {
+		t.Errorf("expected only the counts and the shuffled file, got %d files", len(files))
+	}
+}
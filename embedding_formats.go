@@ -0,0 +1,266 @@
+package wordvec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// EmbeddingFormat is a file format for word vectors.
+type EmbeddingFormat int
+
+const (
+	// FormatText is the word2vec text format, also used by fastText .vec files: a "<words> <dim>" header line, then one word and its values per line.
+	FormatText EmbeddingFormat = iota
+	// FormatGloVe is the GloVe text format: like FormatText but without the header line.
+	FormatGloVe
+	// FormatBinary is the word2vec binary format: the header line, then per word the word, a space and dim little endian float32 values.
+	FormatBinary
+)
+
+// String returns the name of the format.
+func (f EmbeddingFormat) String() string {
+	switch f {
+	case FormatText:
+		return "text"
+	case FormatGloVe:
+		return "glove"
+	case FormatBinary:
+		return "binary"
+	}
+	return fmt.Sprintf("EmbeddingFormat(%d)", int(f))
+}
+
+// ParseEmbeddingFormat returns the format with the given name: "text" (or "vec", "word2vec", "fasttext"), "glove" or "binary" (or "bin").
+func ParseEmbeddingFormat(name string) (EmbeddingFormat, error) {
+	switch strings.ToLower(name) {
+	case "text", "vec", "txt", "word2vec", "fasttext":
+		return FormatText, nil
+	case "glove":
+		return FormatGloVe, nil
+	case "binary", "bin":
+		return FormatBinary, nil
+	}
+	return 0, fmt.Errorf("Unknown embedding format %q", name)
+}
+
+// isTextSpace reports the ASCII whitespace separating fields of a text vector file. Other unicode spaces can be part of a token.
+func isTextSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\v' || r == '\f'
+}
+
+// parseTextHeader reports whether fields are a "<words> <dim>" header line.
+func parseTextHeader(fields []string) (words, dim int, ok bool) {
+	if len(fields) != 2 {
+		return 0, 0, false
+	}
+	words, err := strconv.Atoi(fields[0])
+	if err != nil || words < 0 {
+		return 0, 0, false
+	}
+	dim, err = strconv.Atoi(fields[1])
+	if err != nil || dim < 1 {
+		return 0, 0, false
+	}
+	return words, dim, true
+}
+
+/*
+ReadTextEmbeddings reads word vectors in a text format. A "<words> <dim>" header line (word2vec and fastText .vec files) is detected and checked against the data; without one (GloVe files) the dimension is taken from the first line.
+
+Fields may be separated by any mix of spaces and tabs; trailing whitespace, carriage returns, blank lines and a leading byte order mark are ignored. Tokens are kept as is, including non-ASCII ones; a token containing spaces is recognised because the last dim fields of a line are always the values.
+*/
+func ReadTextEmbeddings(r io.Reader) (*Embeddings, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	var words []string
+	var vectors []float32
+	dim, expected := 0, -1
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		fields := strings.FieldsFunc(text, isTextSpace)
+		if len(fields) == 0 {
+			continue
+		}
+		if dim == 0 {
+			if n, d, ok := parseTextHeader(fields); ok {
+				// The header is only checked against the data, a corrupt one must not size the buffers
+				expected, dim = n, d
+				continue
+			}
+			dim = len(fields) - 1
+			if dim < 1 {
+				return nil, fmt.Errorf("line %d: expected a word followed by its vector", line)
+			}
+		}
+		if len(fields) < dim+1 {
+			return nil, fmt.Errorf("line %d: expected %d values, got %d", line, dim, len(fields)-1)
+		}
+		split := len(fields) - dim
+		for _, field := range fields[split:] {
+			f, err := strconv.ParseFloat(field, 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			vectors = append(vectors, float32(f))
+		}
+		words = append(words, strings.Join(fields[:split], " "))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if expected >= 0 && len(words) != expected {
+		return nil, fmt.Errorf("header announces %d words, got %d", expected, len(words))
+	}
+	if dim == 0 {
+		return nil, errors.New("No word vectors found")
+	}
+	return NewEmbeddings(words, dim, vectors)
+}
+
+// ReadBinaryEmbeddings reads word vectors in the word2vec binary format. The buffers grow with the data read, so a header announcing more words or a larger dimension than the file holds is a truncated file error.
+func ReadBinaryEmbeddings(r io.Reader) (*Embeddings, error) {
+	br := bufio.NewReader(r)
+	header, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("reading word2vec header line: %v", err)
+	}
+	size, dim, ok := parseTextHeader(strings.FieldsFunc(header, isTextSpace))
+	if !ok || dim > math.MaxInt32 {
+		return nil, fmt.Errorf("bad word2vec header line %q", strings.TrimSpace(header))
+	}
+	var words []string
+	var vectors []float32
+	var row bytes.Buffer
+	for a := 0; a < size; a++ {
+		var word bytes.Buffer
+		for {
+			c, err := br.ReadByte()
+			if err != nil {
+				return nil, fmt.Errorf("word2vec file is truncated after %d words", a)
+			}
+			if c == ' ' {
+				if word.Len() > 0 {
+					break
+				}
+				continue
+			}
+			if c != '\n' {
+				word.WriteByte(c)
+			}
+		}
+		words = append(words, word.String())
+		row.Reset()
+		if _, err := io.CopyN(&row, br, 4*int64(dim)); err != nil {
+			return nil, fmt.Errorf("word2vec file is truncated after %d words", a)
+		}
+		buf := row.Bytes()
+		for d := 0; d < dim; d++ {
+			vectors = append(vectors, math.Float32frombits(binary.LittleEndian.Uint32(buf[4*d:])))
+		}
+	}
+	return NewEmbeddings(words, dim, vectors)
+}
+
+// ReadEmbeddings reads word vectors in the given format. FormatText and FormatGloVe both detect whether there is a header line.
+func ReadEmbeddings(r io.Reader, format EmbeddingFormat) (*Embeddings, error) {
+	switch format {
+	case FormatText, FormatGloVe:
+		return ReadTextEmbeddings(r)
+	case FormatBinary:
+		return ReadBinaryEmbeddings(r)
+	}
+	return nil, fmt.Errorf("Unknown embedding format %v", format)
+}
+
+// WriteEmbeddings writes word vectors in the given format. Text values are written with the fewest digits that read back to the same float32.
+func WriteEmbeddings(w io.Writer, e *Embeddings, format EmbeddingFormat) error {
+	if format != FormatText && format != FormatGloVe && format != FormatBinary {
+		return fmt.Errorf("Unknown embedding format %v", format)
+	}
+	bw := bufio.NewWriter(w)
+	if format != FormatGloVe {
+		fmt.Fprintf(bw, "%d %d\n", e.Len(), e.Dim())
+	}
+	var num []byte
+	var buf [4]byte
+	for i := 0; i < e.Len(); i++ {
+		bw.WriteString(e.Word(i))
+		for j, x := range e.row(i, nil) {
+			if format == FormatBinary {
+				if j == 0 {
+					bw.WriteByte(' ')
+				}
+				binary.LittleEndian.PutUint32(buf[:], math.Float32bits(x))
+				bw.Write(buf[:])
+				continue
+			}
+			bw.WriteByte(' ')
+			num = strconv.AppendFloat(num[:0], float64(x), 'g', -1, 32)
+			bw.Write(num)
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ConvertEmbeddings reads word vectors in one format and writes them in another, e.g. GloVe files to word2vec binary.
+func ConvertEmbeddings(r io.Reader, from EmbeddingFormat, w io.Writer, to EmbeddingFormat) error {
+	e, err := ReadEmbeddings(r, from)
+	if err != nil {
+		return err
+	}
+	return WriteEmbeddings(w, e, to)
+}
+
+/*
+LoadEmbeddingsFile reads the word vectors of a file for querying and returns them with the name of the format read. The format is NATIVE_FORMAT_NAME for a model written by SaveFile, whose word vectors are kept and the rest dropped, or a name accepted by ParseEmbeddingFormat. An empty format detects native models by their magic and binary files by a .bin extension and reads anything else as text.
+*/
+func LoadEmbeddingsFile(name, format string) (*Embeddings, string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	if format == "" {
+		format = FormatText.String()
+		magic := make([]byte, len(NATIVE_FORMAT_MAGIC))
+		if _, err := io.ReadFull(f, magic); err == nil && string(magic) == NATIVE_FORMAT_MAGIC {
+			format = NATIVE_FORMAT_NAME
+		} else if strings.EqualFold(filepath.Ext(name), ".bin") {
+			format = FormatBinary.String()
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, "", err
+		}
+	}
+	if format == NATIVE_FORMAT_NAME {
+		v, err := Load(f)
+		if err != nil {
+			return nil, "", err
+		}
+		e, err := v.Embeddings()
+		return e, format, err
+	}
+	ef, err := ParseEmbeddingFormat(format)
+	if err != nil {
+		return nil, "", err
+	}
+	e, err := ReadEmbeddings(f, ef)
+	return e, ef.String(), err
+}
@@ -0,0 +1,154 @@
+package wordvec
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testGloVe string = "the 0.1 0.2 0.3\n" +
+	"café\t-0.5  0.25\t1e-3   \r\n" +
+	"\n" +
+	". . . 1 2 3\n"
+
+var testFastTextVec string = "\ufeff3 3 \n" +
+	"the 0.1 0.2 0.3 \n" +
+	"日本語 -0.5 0.25 0.001\n" +
+	"naïve 1 2 3\n"
+
+func TestReadTextEmbeddings(t *testing.T) {
+	for name, input := range map[string]string{"glove": testGloVe, "vec": testFastTextVec} {
+		e, err := ReadTextEmbeddings(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if e.Len() != 3 || e.Dim() != 3 {
+			t.Errorf("%s: expected 3 words of dim 3, got %d of dim %d", name, e.Len(), e.Dim())
+		}
+		vec, _ := e.Vector(e.Word(1))
+		if vec[0] != -0.5 || vec[2] != 0.001 {
+			t.Errorf("%s: second vector should be [-0.5 0.25 0.001], got %v", name, vec)
+		}
+	}
+	glove, _ := ReadTextEmbeddings(strings.NewReader(testGloVe))
+	if glove.Word(1) != "café" || glove.Word(2) != ". . ." {
+		t.Errorf("tokens should be kept, got %q and %q", glove.Word(1), glove.Word(2))
+	}
+	vec, _ := ReadTextEmbeddings(strings.NewReader(testFastTextVec))
+	if vec.Word(0) != "the" || vec.Word(1) != "日本語" {
+		t.Errorf("header should be skipped and tokens kept, got %q and %q", vec.Word(0), vec.Word(1))
+	}
+}
+
+var badtexttests = []struct {
+	input string
+	err   string
+}{
+	{"the 0.1 0.2\nand 0.3\n", "line 2: expected 2 values"},
+	{"the 0.1 zero\n", "line 1"},
+	{"3 2\nthe 0.1 0.2\n", "header announces 3 words, got 1"},
+	{"", "No word vectors"},
+}
+
+func TestReadTextEmbeddingsErrors(t *testing.T) {
+	for _, bt := range badtexttests {
+		_, err := ReadTextEmbeddings(strings.NewReader(bt.input))
+		if err == nil || !strings.Contains(err.Error(), bt.err) {
+			t.Errorf("ReadTextEmbeddings(%q) error = %v, want %q", bt.input, err, bt.err)
+		}
+	}
+}
+
+func TestReadEmbeddingsHugeHeader(t *testing.T) {
+	for _, format := range []EmbeddingFormat{FormatText, FormatBinary} {
+		for _, input := range []string{"10000000000000 100\nab", "2 4611686018427387904\nab"} {
+			if _, err := ReadEmbeddings(strings.NewReader(input), format); err == nil {
+				t.Errorf("%v: a header of %q on a tiny file should be an error", format, input)
+			}
+		}
+	}
+}
+
+func TestConvertEmbeddings(t *testing.T) {
+	original, _ := ReadTextEmbeddings(strings.NewReader(testFastTextVec))
+	formats := []EmbeddingFormat{FormatGloVe, FormatBinary, FormatText}
+	input := testFastTextVec
+	from := FormatText
+	for _, to := range formats {
+		var out bytes.Buffer
+		if err := ConvertEmbeddings(strings.NewReader(input), from, &out, to); err != nil {
+			t.Fatalf("%v -> %v: %v", from, to, err)
+		}
+		converted, err := ReadEmbeddings(bytes.NewReader(out.Bytes()), to)
+		if err != nil {
+			t.Fatalf("reading %v: %v", to, err)
+		}
+		for i := 0; i < original.Len(); i++ {
+			want, _ := original.Vector(original.Word(i))
+			got, ok := converted.Vector(original.Word(i))
+			if !ok {
+				t.Fatalf("%v should contain %q", to, original.Word(i))
+			}
+			for d := range want {
+				if got[d] != want[d] {
+					t.Errorf("%v: %q differs at %d: %v != %v", to, original.Word(i), d, got[d], want[d])
+				}
+			}
+		}
+		input, from = out.String(), to
+	}
+	if !strings.HasPrefix(input, "3 3\n") {
+		t.Errorf("text output should start with a header line, got %q", input)
+	}
+	if _, err := ParseEmbeddingFormat("glove"); err != nil {
+		t.Error(err)
+	}
+	if _, err := ParseEmbeddingFormat("xml"); err == nil {
+		t.Error("an unknown format name should be an error")
+	}
+}
+
+func TestLoadEmbeddingsFile(t *testing.T) {
+	dir := t.TempDir()
+	e, _ := NewEmbeddings(testEmbeddingWords, 3, testEmbeddingVectors)
+	write := func(name string, format EmbeddingFormat) string {
+		var buf bytes.Buffer
+		if err := WriteEmbeddings(&buf, e, format); err != nil {
+			t.Fatal(err)
+		}
+		name = filepath.Join(dir, name)
+		if err := os.WriteFile(name, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	mv := trainSmallModel(t, IterOption(1))
+	native := filepath.Join(dir, "model")
+	if err := mv.SaveFile(native); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name, format, want string
+		words              int
+	}{
+		{write("vectors.bin", FormatBinary), "", "binary", 5},
+		{write("vectors.txt", FormatText), "", "text", 5},
+		{write("vectors.glove", FormatGloVe), "glove", "glove", 5},
+		{write("vectors.w2v", FormatBinary), "bin", "binary", 5},
+		{native, "", NATIVE_FORMAT_NAME, mv.VocabSize},
+	} {
+		got, format, err := LoadEmbeddingsFile(c.name, c.format)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if format != c.want || got.Len() != c.words {
+			t.Errorf("%s: expected %d words read as %s, got %d as %s", c.name, c.words, c.want, got.Len(), format)
+		}
+	}
+	if _, _, err := LoadEmbeddingsFile(native, "parquet"); err == nil {
+		t.Error("an unknown format should be an error")
+	}
+}
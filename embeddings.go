@@ -0,0 +1,225 @@
+package wordvec
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Neighbor is a word found by a similarity query together with its cosine similarity to the query.
+type Neighbor struct {
+	Word       string  `json:"word"`
+	Similarity float64 `json:"similarity"`
+}
+
+/*
+vectorRows is the read access to a matrix of word vectors that the similarity queries work on. Embeddings keeps the rows in memory, MmapEmbeddings reads them from a mapped file.
+*/
+type vectorRows interface {
+	Len() int
+	Dim() int
+	Word(i int) string
+	Index(word string) (int, bool)
+	// row returns the vector of word i, either as a view of the underlying storage or decoded into buf (of length Dim). The result must not be modified.
+	row(i int, buf []float32) []float32
+	norm(i int) float32
+}
+
+// Embeddings is a read-only, in-memory set of word vectors for querying. Vectors are stored as float32 in one row-major matrix.
+type Embeddings struct {
+	words   []string
+	dim     int
+	vectors []float32
+	norms   []float32
+	index   map[string]int
+}
+
+/*
+NewEmbeddings wraps words and their row-major vectors (len(words)*dim values) for querying. The vectors are used as is, not copied; they must not be modified afterwards. Repeated words keep their first vector.
+*/
+func NewEmbeddings(words []string, dim int, vectors []float32) (*Embeddings, error) {
+	if dim < 1 {
+		return nil, fmt.Errorf("Embedding dimension must be at least 1, got %d", dim)
+	}
+	if len(vectors) != len(words)*dim {
+		return nil, fmt.Errorf("Expected %d vector values for %d words of dimension %d, got %d", len(words)*dim, len(words), dim, len(vectors))
+	}
+	e := &Embeddings{
+		words:   words,
+		dim:     dim,
+		vectors: vectors,
+		index:   make(map[string]int, len(words)),
+	}
+	for i, w := range words {
+		if _, ok := e.index[w]; !ok {
+			e.index[w] = i
+		}
+	}
+	e.norms = rowNorms(e)
+	return e, nil
+}
+
+// Embeddings copies the trained word vectors (Syn0, with the n-grams of a subword model, see WordVector) of the model into an Embeddings for querying.
+func (v *VectorModel) Embeddings() (*Embeddings, error) {
+	if len(v.Syn0) < v.VocabSize*v.Layer1VecSize {
+		return nil, errors.New("Model has no trained word vectors")
+	}
+	words := make([]string, v.VocabSize)
+	vectors := make([]float32, v.VocabSize*v.Layer1VecSize)
+	for a := 0; a < v.VocabSize; a++ {
+		words[a] = v.Vocab[a].Word
+	}
+	for i, x := range v.wordVectors()[:len(vectors)] {
+		vectors[i] = float32(x)
+	}
+	return NewEmbeddings(words, v.Layer1VecSize, vectors)
+}
+
+// Len returns the number of words.
+func (e *Embeddings) Len() int { return len(e.words) }
+
+// Dim returns the size of the vectors.
+func (e *Embeddings) Dim() int { return e.dim }
+
+// Word returns the i-th word.
+func (e *Embeddings) Word(i int) string { return e.words[i] }
+
+// Words returns all words in row order. The slice must not be modified.
+func (e *Embeddings) Words() []string { return e.words }
+
+// Index returns the row of word.
+func (e *Embeddings) Index(word string) (int, bool) {
+	i, ok := e.index[word]
+	return i, ok
+}
+
+func (e *Embeddings) row(i int, buf []float32) []float32 {
+	return e.vectors[i*e.dim : (i+1)*e.dim]
+}
+
+func (e *Embeddings) norm(i int) float32 { return e.norms[i] }
+
+// Matrix returns the row-major matrix of all vectors. It must not be modified.
+func (e *Embeddings) Matrix() []float32 { return e.vectors }
+
+// Vector returns a copy of the vector of word, or false if the word is unknown.
+func (e *Embeddings) Vector(word string) ([]float32, bool) {
+	return vectorOf(e, word)
+}
+
+// MostSimilar returns the n words most similar to word by cosine similarity, the word itself excluded.
+func (e *Embeddings) MostSimilar(word string, n int) ([]Neighbor, error) {
+	return mostSimilar(e, word, n)
+}
+
+// MostSimilarVector returns the n words most similar to vec by cosine similarity, skipping the words in exclude.
+func (e *Embeddings) MostSimilarVector(vec []float32, n int, exclude ...string) []Neighbor {
+	return mostSimilarVector(e, vec, n, exclude)
+}
+
+// Similarity returns the cosine similarity of two words.
+func (e *Embeddings) Similarity(a, b string) (float64, error) {
+	return similarity(e, a, b)
+}
+
+func rowNorms(rows vectorRows) []float32 {
+	norms := make([]float32, rows.Len())
+	buf := make([]float32, rows.Dim())
+	for i := range norms {
+		var sum float64
+		for _, x := range rows.row(i, buf) {
+			sum += float64(x) * float64(x)
+		}
+		norms[i] = float32(math.Sqrt(sum))
+	}
+	return norms
+}
+
+func vectorOf(rows vectorRows, word string) ([]float32, bool) {
+	i, ok := rows.Index(word)
+	if !ok {
+		return nil, false
+	}
+	vec := make([]float32, rows.Dim())
+	copy(vec, rows.row(i, vec))
+	return vec, true
+}
+
+func mostSimilar(rows vectorRows, word string, n int) ([]Neighbor, error) {
+	i, ok := rows.Index(word)
+	if !ok {
+		return nil, fmt.Errorf("Out of dictionary word: %s", word)
+	}
+	vec := make([]float32, rows.Dim())
+	copy(vec, rows.row(i, vec))
+	return mostSimilarVector(rows, vec, n, []string{word}), nil
+}
+
+func similarity(rows vectorRows, a, b string) (float64, error) {
+	i, ok := rows.Index(a)
+	if !ok {
+		return 0, fmt.Errorf("Out of dictionary word: %s", a)
+	}
+	j, ok := rows.Index(b)
+	if !ok {
+		return 0, fmt.Errorf("Out of dictionary word: %s", b)
+	}
+	if rows.norm(i) == 0 || rows.norm(j) == 0 {
+		return 0, nil
+	}
+	bufA := make([]float32, rows.Dim())
+	bufB := make([]float32, rows.Dim())
+	return float64(dot32(rows.row(i, bufA), rows.row(j, bufB))) / (float64(rows.norm(i)) * float64(rows.norm(j))), nil
+}
+
+// mostSimilarVector scans all rows keeping the n best in a sorted slice, as the original distance tool does.
+func mostSimilarVector(rows vectorRows, vec []float32, n int, exclude []string) []Neighbor {
+	if n <= 0 || len(vec) != rows.Dim() {
+		return nil
+	}
+	skip := make(map[int]bool, len(exclude))
+	for _, w := range exclude {
+		if i, ok := rows.Index(w); ok {
+			skip[i] = true
+		}
+	}
+	var qnorm float64
+	for _, x := range vec {
+		qnorm += float64(x) * float64(x)
+	}
+	qnorm = math.Sqrt(qnorm)
+	if qnorm == 0 {
+		return nil
+	}
+
+	best := make([]Neighbor, 0, n+1)
+	buf := make([]float32, rows.Dim())
+	for i := 0; i < rows.Len(); i++ {
+		if skip[i] || rows.norm(i) == 0 {
+			continue
+		}
+		sim := float64(dot32(vec, rows.row(i, buf))) / (qnorm * float64(rows.norm(i)))
+		if len(best) == n && sim <= best[n-1].Similarity {
+			continue
+		}
+		pos := len(best)
+		for pos > 0 && best[pos-1].Similarity < sim {
+			pos--
+		}
+		best = append(best, Neighbor{})
+		copy(best[pos+1:], best[pos:])
+		best[pos] = Neighbor{Word: rows.Word(i), Similarity: sim}
+		if len(best) > n {
+			best = best[:n]
+		}
+	}
+	return best
+}
+
+func dot32(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
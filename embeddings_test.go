@@ -0,0 +1,78 @@
+package wordvec
+
+import (
+	"math"
+	"testing"
+)
+
+var testEmbeddingWords = []string{"cat", "dog", "car", "truck", "zero"}
+
+var testEmbeddingVectors = []float32{
+	1, 0.1, 0,
+	0.9, 0.2, 0,
+	0, 0.1, 1,
+	0, 0.3, 0.9,
+	0, 0, 0,
+}
+
+func TestEmbeddingsMostSimilar(t *testing.T) {
+	e, err := NewEmbeddings(testEmbeddingWords, 3, testEmbeddingVectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nn, err := e.MostSimilar("cat", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nn) != 2 || nn[0].Word != "dog" || nn[1].Word == "cat" {
+		t.Errorf("nearest to cat should be dog and never cat itself, got %+v", nn)
+	}
+	if nn[0].Similarity < nn[1].Similarity {
+		t.Errorf("neighbors should be sorted by similarity, got %+v", nn)
+	}
+	if _, err := e.MostSimilar("unicorn", 2); err == nil {
+		t.Error("an unknown word should be an error")
+	}
+	nn = e.MostSimilarVector([]float32{0, 0.2, 1}, 3, "car")
+	if len(nn) != 3 || nn[0].Word != "truck" {
+		t.Errorf("nearest to the vector without car should be truck, got %+v", nn)
+	}
+	for _, n := range nn {
+		if n.Word == "zero" {
+			t.Error("zero vectors should never be returned")
+		}
+	}
+}
+
+func TestEmbeddingsVectorAndSimilarity(t *testing.T) {
+	e, _ := NewEmbeddings(testEmbeddingWords, 3, testEmbeddingVectors)
+	vec, ok := e.Vector("car")
+	if !ok || vec[2] != 1 {
+		t.Errorf("vector of car should be [0 0.1 1], got %v", vec)
+	}
+	vec[2] = 5
+	if again, _ := e.Vector("car"); again[2] != 1 {
+		t.Error("Vector should return a copy")
+	}
+	sim, err := e.Similarity("cat", "cat")
+	if err != nil || math.Abs(sim-1) > 1e-6 {
+		t.Errorf("similarity of a word with itself should be 1, got %v %v", sim, err)
+	}
+	if _, err := NewEmbeddings(testEmbeddingWords, 4, testEmbeddingVectors); err == nil {
+		t.Error("vectors not matching words*dim should be an error")
+	}
+}
+
+func TestModelEmbeddings(t *testing.T) {
+	mv := trainSmallModel(t, IterOption(1))
+	e, err := mv.Embeddings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Len() != mv.VocabSize || e.Dim() != mv.Layer1VecSize {
+		t.Errorf("embeddings should be %dx%d, got %dx%d", mv.VocabSize, mv.Layer1VecSize, e.Len(), e.Dim())
+	}
+	if i, ok := e.Index(mv.Vocab[3].Word); !ok || i != 3 {
+		t.Errorf("word %s should be at row 3, got %d", mv.Vocab[3].Word, i)
+	}
+}
@@ -0,0 +1,306 @@
+package wordvec
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+const (
+	// EXACT_ROW_BLOCK rows of the matrix are scored against all queries of a batch before moving on, so the block stays in cache while it is reused (64 rows of 300 float32 are 75KB).
+	EXACT_ROW_BLOCK int = 64
+	// EXACT_MIN_PARTITION is the fewest rows worth handing to a goroutine of their own.
+	EXACT_MIN_PARTITION int = 4096
+)
+
+// Hit is a row of an ExactIndex matched by a search and its score: the dot product with the query, the cosine similarity when both are normalized.
+type Hit struct {
+	Index int
+	Score float32
+}
+
+/*
+ExactIndex answers exact k nearest neighbour queries by brute force over a contiguous matrix of normalized float32 rows, so that a dot product is the cosine similarity.
+
+The scan keeps the best k rows in a bounded min-heap, scores blocks of EXACT_ROW_BLOCK rows against up to four queries at a time with unrolled dot products, and splits the rows among Threads goroutines. An ExactIndex is safe for concurrent use.
+*/
+type ExactIndex struct {
+	words   []string
+	dim     int
+	matrix  []float32
+	Threads int
+}
+
+// NewExactIndex wraps a row-major matrix of normalized vectors (see NormalizeRows) with one row per word. The matrix is used as is, not copied.
+func NewExactIndex(words []string, dim int, matrix []float32) (*ExactIndex, error) {
+	if dim < 1 {
+		return nil, fmt.Errorf("Embedding dimension must be at least 1, got %d", dim)
+	}
+	if len(matrix) != len(words)*dim {
+		return nil, fmt.Errorf("Expected %d matrix values for %d words of dimension %d, got %d", len(words)*dim, len(words), dim, len(matrix))
+	}
+	return &ExactIndex{words: words, dim: dim, matrix: matrix, Threads: runtime.GOMAXPROCS(0)}, nil
+}
+
+// NormalizeRows scales every row of a row-major matrix to unit length in place. Zero rows stay zero.
+func NormalizeRows(matrix []float32, dim int) {
+	for r := 0; r+dim <= len(matrix); r += dim {
+		row := matrix[r : r+dim]
+		norm := math.Sqrt(float64(dotUnrolled(row, row)))
+		if norm == 0 {
+			continue
+		}
+		inv := float32(1 / norm)
+		for i := range row {
+			row[i] *= inv
+		}
+	}
+}
+
+// ExactIndex builds an exact search index over a normalized copy of the vectors.
+func (e *Embeddings) ExactIndex() (*ExactIndex, error) {
+	matrix := make([]float32, len(e.vectors))
+	copy(matrix, e.vectors)
+	NormalizeRows(matrix, e.dim)
+	return NewExactIndex(e.words, e.dim, matrix)
+}
+
+// Len returns the number of rows.
+func (x *ExactIndex) Len() int { return len(x.words) }
+
+// Dim returns the size of the rows.
+func (x *ExactIndex) Dim() int { return x.dim }
+
+// Word returns the word of row i.
+func (x *ExactIndex) Word(i int) string { return x.words[i] }
+
+// Row returns the normalized vector of row i. It must not be modified.
+func (x *ExactIndex) Row(i int) []float32 { return x.matrix[i*x.dim : (i+1)*x.dim] }
+
+// Neighbors turns hits into words and similarities.
+func (x *ExactIndex) Neighbors(hits []Hit) []Neighbor {
+	nn := make([]Neighbor, len(hits))
+	for i, h := range hits {
+		nn[i] = Neighbor{Word: x.words[h.Index], Similarity: float64(h.Score)}
+	}
+	return nn
+}
+
+// Search returns the k rows with the highest dot product with query, best first. Rows listed in exclude are skipped. Normalize the query to get cosine similarities.
+func (x *ExactIndex) Search(query []float32, k int, exclude ...int) []Hit {
+	hits, _ := x.SearchBatch(query, k, exclude)
+	if len(hits) == 0 {
+		return nil
+	}
+	return hits[0]
+}
+
+/*
+SearchBatch answers len(queries)/Dim queries at once, queries being a row-major Q×Dim matrix, which amounts to the Q×V matrix product with the index followed by a top-k selection per query. Rows in exclude are skipped for every query.
+
+This is much faster than Q calls to Search because every block of rows is loaded once for all queries.
+*/
+func (x *ExactIndex) SearchBatch(queries []float32, k int, exclude []int) ([][]Hit, error) {
+	if len(queries)%x.dim != 0 {
+		return nil, errors.New("Query matrix size is not a multiple of the index dimension")
+	}
+	nq := len(queries) / x.dim
+	if nq == 0 || k <= 0 {
+		return make([][]Hit, nq), nil
+	}
+	var skip map[int]bool
+	if len(exclude) > 0 {
+		skip = make(map[int]bool, len(exclude))
+		for _, i := range exclude {
+			skip[i] = true
+		}
+	}
+
+	parts := x.Threads
+	if parts < 1 {
+		parts = 1
+	}
+	if max := (x.Len() + EXACT_MIN_PARTITION - 1) / EXACT_MIN_PARTITION; parts > max {
+		parts = max
+	}
+	if parts < 1 {
+		parts = 1
+	}
+	heaps := make([][]hitHeap, parts)
+	var wg sync.WaitGroup
+	chunk := (x.Len() + parts - 1) / parts
+	for p := 0; p < parts; p++ {
+		start, end := p*chunk, (p+1)*chunk
+		if end > x.Len() {
+			end = x.Len()
+		}
+		heaps[p] = make([]hitHeap, nq)
+		for q := range heaps[p] {
+			heaps[p][q] = make(hitHeap, 0, k)
+		}
+		wg.Add(1)
+		go func(hs []hitHeap, start, end int) {
+			defer wg.Done()
+			x.scan(queries, nq, start, end, k, skip, hs)
+		}(heaps[p], start, end)
+	}
+	wg.Wait()
+
+	results := make([][]Hit, nq)
+	for q := 0; q < nq; q++ {
+		merged := heaps[0][q]
+		for p := 1; p < parts; p++ {
+			for _, h := range heaps[p][q] {
+				merged.offer(h, k)
+			}
+		}
+		results[q] = merged.sorted()
+	}
+	return results, nil
+}
+
+// scan scores rows [start, end) against all queries, block by block, feeding the per query heaps.
+func (x *ExactIndex) scan(queries []float32, nq, start, end, k int, skip map[int]bool, heaps []hitHeap) {
+	dim := x.dim
+	var scores [4]float32
+	for b := start; b < end; b += EXACT_ROW_BLOCK {
+		blockEnd := b + EXACT_ROW_BLOCK
+		if blockEnd > end {
+			blockEnd = end
+		}
+		q := 0
+		for ; q+4 <= nq; q += 4 {
+			q0 := queries[q*dim : (q+1)*dim]
+			q1 := queries[(q+1)*dim : (q+2)*dim]
+			q2 := queries[(q+2)*dim : (q+3)*dim]
+			q3 := queries[(q+3)*dim : (q+4)*dim]
+			for r := b; r < blockEnd; r++ {
+				if skip != nil && skip[r] {
+					continue
+				}
+				scores = dot4(x.matrix[r*dim:(r+1)*dim], q0, q1, q2, q3)
+				for j := 0; j < 4; j++ {
+					heaps[q+j].offer(Hit{Index: r, Score: scores[j]}, k)
+				}
+			}
+		}
+		for ; q < nq; q++ {
+			query := queries[q*dim : (q+1)*dim]
+			for r := b; r < blockEnd; r++ {
+				if skip != nil && skip[r] {
+					continue
+				}
+				heaps[q].offer(Hit{Index: r, Score: dotUnrolled(x.matrix[r*dim:(r+1)*dim], query)}, k)
+			}
+		}
+	}
+}
+
+// dotUnrolled is the dot product of a and b, unrolled by eight with independent accumulators so the additions can overlap.
+func dotUnrolled(a, b []float32) float32 {
+	var s0, s1, s2, s3, s4, s5, s6, s7 float32
+	n := len(a)
+	b = b[:n]
+	i := 0
+	for ; i+8 <= n; i += 8 {
+		s0 += a[i] * b[i]
+		s1 += a[i+1] * b[i+1]
+		s2 += a[i+2] * b[i+2]
+		s3 += a[i+3] * b[i+3]
+		s4 += a[i+4] * b[i+4]
+		s5 += a[i+5] * b[i+5]
+		s6 += a[i+6] * b[i+6]
+		s7 += a[i+7] * b[i+7]
+	}
+	for ; i < n; i++ {
+		s0 += a[i] * b[i]
+	}
+	return ((s0 + s1) + (s2 + s3)) + ((s4 + s5) + (s6 + s7))
+}
+
+// dot4 scores one row against four queries in a single pass over the row, unrolled by two.
+func dot4(row, q0, q1, q2, q3 []float32) [4]float32 {
+	var a0, a1, a2, a3, b0, b1, b2, b3 float32
+	n := len(row)
+	q0, q1, q2, q3 = q0[:n], q1[:n], q2[:n], q3[:n]
+	i := 0
+	for ; i+2 <= n; i += 2 {
+		r0, r1 := row[i], row[i+1]
+		a0 += r0 * q0[i]
+		a1 += r0 * q1[i]
+		a2 += r0 * q2[i]
+		a3 += r0 * q3[i]
+		b0 += r1 * q0[i+1]
+		b1 += r1 * q1[i+1]
+		b2 += r1 * q2[i+1]
+		b3 += r1 * q3[i+1]
+	}
+	if i < n {
+		r0 := row[i]
+		a0 += r0 * q0[i]
+		a1 += r0 * q1[i]
+		a2 += r0 * q2[i]
+		a3 += r0 * q3[i]
+	}
+	return [4]float32{a0 + b0, a1 + b1, a2 + b2, a3 + b3}
+}
+
+// hitHeap is a bounded min-heap of hits: the root is the worst of the best k seen so far.
+type hitHeap []Hit
+
+// offer adds h if the heap holds fewer than k hits or h beats the worst of them.
+func (h *hitHeap) offer(hit Hit, k int) {
+	s := *h
+	if len(s) < k {
+		s = append(s, hit)
+		// sift up
+		i := len(s) - 1
+		for i > 0 {
+			parent := (i - 1) / 2
+			if s[parent].Score <= s[i].Score {
+				break
+			}
+			s[parent], s[i] = s[i], s[parent]
+			i = parent
+		}
+		*h = s
+		return
+	}
+	if hit.Score <= s[0].Score {
+		return
+	}
+	s[0] = hit
+	// sift down
+	i := 0
+	for {
+		left := 2*i + 1
+		if left >= len(s) {
+			break
+		}
+		smallest := left
+		if right := left + 1; right < len(s) && s[right].Score < s[left].Score {
+			smallest = right
+		}
+		if s[i].Score <= s[smallest].Score {
+			break
+		}
+		s[i], s[smallest] = s[smallest], s[i]
+		i = smallest
+	}
+}
+
+// sorted returns the hits best first, ties broken by row.
+func (h hitHeap) sorted() []Hit {
+	hits := make([]Hit, len(h))
+	copy(hits, h)
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Index < hits[j].Index
+	})
+	return hits
+}
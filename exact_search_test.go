@@ -0,0 +1,156 @@
+package wordvec
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func randomMatrix(rows, dim int, seed int64) ([]string, []float32) {
+	rnd := rand.New(rand.NewSource(seed))
+	words := make([]string, rows)
+	matrix := make([]float32, rows*dim)
+	for i := range words {
+		words[i] = "w" + string(rune('a'+i%26))
+	}
+	for i := range matrix {
+		matrix[i] = float32(rnd.NormFloat64())
+	}
+	NormalizeRows(matrix, dim)
+	return words, matrix
+}
+
+// naiveSearch scores every row and sorts them all.
+func naiveSearch(matrix []float32, dim int, query []float32, k int) []Hit {
+	hits := make([]Hit, len(matrix)/dim)
+	for r := range hits {
+		var s float32
+		for d := 0; d < dim; d++ {
+			s += matrix[r*dim+d] * query[d]
+		}
+		hits[r] = Hit{Index: r, Score: s}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	return hits[:k]
+}
+
+func sameHits(t *testing.T, want, got []Hit) {
+	if len(got) != len(want) {
+		t.Fatalf("expected %d hits, got %d", len(want), len(got))
+	}
+	for i := range want {
+		diff := want[i].Score - got[i].Score
+		if got[i].Index != want[i].Index || diff > 1e-4 || diff < -1e-4 {
+			t.Fatalf("hit %d should be %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestExactIndexSearch(t *testing.T) {
+	words, matrix := randomMatrix(10000, 37, 1)
+	x, err := NewExactIndex(words, 37, matrix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x.Threads = 3
+	for q := 0; q < 5; q++ {
+		query := x.Row(q * 17)
+		sameHits(t, naiveSearch(matrix, 37, query, 10), x.Search(query, 10))
+	}
+	hits := x.Search(x.Row(42), 5, 42)
+	for _, h := range hits {
+		if h.Index == 42 {
+			t.Error("excluded rows should not be returned")
+		}
+	}
+	if len(x.Search(x.Row(0), 0)) != 0 {
+		t.Error("k = 0 should return no hits")
+	}
+}
+
+func TestExactIndexSearchBatch(t *testing.T) {
+	words, matrix := randomMatrix(3000, 50, 2)
+	x, _ := NewExactIndex(words, 50, matrix)
+	// 7 queries exercise both the four-query kernel and the remainder
+	queries := matrix[:7*50]
+	batch, err := x.SearchBatch(queries, 8, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for q := 0; q < 7; q++ {
+		sameHits(t, x.Search(queries[q*50:(q+1)*50], 8), batch[q])
+		if batch[q][0].Index != q {
+			t.Errorf("a row should be its own nearest neighbour, got %+v", batch[q][0])
+		}
+	}
+	if _, err := x.SearchBatch(queries[:49], 8, nil); err == nil {
+		t.Error("a query matrix of the wrong size should be an error")
+	}
+}
+
+func TestEmbeddingsExactIndex(t *testing.T) {
+	e, _ := NewEmbeddings(testEmbeddingWords, 3, testEmbeddingVectors)
+	x, err := e.ExactIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	i, _ := e.Index("cat")
+	nn := x.Neighbors(x.Search(x.Row(i), 2, i))
+	want, _ := e.MostSimilar("cat", 2)
+	checkSameNeighbors(t, want, nn)
+}
+
+const (
+	benchRows = 1000000
+	benchDim  = 300
+)
+
+var benchMatrixOnce sync.Once
+var benchWords []string
+var benchMatrix []float32
+
+func benchIndex(b *testing.B) *ExactIndex {
+	if testing.Short() {
+		b.Skip("skipping the 1M x 300 matrix in short mode")
+	}
+	benchMatrixOnce.Do(func() { benchWords, benchMatrix = randomMatrix(benchRows, benchDim, 3) })
+	x, _ := NewExactIndex(benchWords, benchDim, benchMatrix)
+	return x
+}
+
+func BenchmarkNaiveSearch1Mx300(b *testing.B) {
+	x := benchIndex(b)
+	query := x.Row(12345)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		best := Hit{Score: -2}
+		for r := 0; r < benchRows; r++ {
+			var s float32
+			for d := 0; d < benchDim; d++ {
+				s += benchMatrix[r*benchDim+d] * query[d]
+			}
+			if s > best.Score {
+				best = Hit{Index: r, Score: s}
+			}
+		}
+	}
+}
+
+func BenchmarkExactSearch1Mx300(b *testing.B) {
+	x := benchIndex(b)
+	query := x.Row(12345)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = x.Search(query, 10)
+	}
+}
+
+func BenchmarkExactSearchBatch16x1Mx300(b *testing.B) {
+	x := benchIndex(b)
+	queries := benchMatrix[:16*benchDim]
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = x.SearchBatch(queries, 10, nil)
+	}
+}
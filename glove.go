@@ -0,0 +1,214 @@
+package wordvec
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// GLOVE_X_MAX is the co-occurrence count above which the GloVe weighting function is 1.
+	GLOVE_X_MAX float64 = 100.0
+	// GLOVE_POWER is the exponent of the GloVe weighting function (x/XMax)^Power.
+	GLOVE_POWER float64 = 0.75
+	// GLOVE_ALPHA is the initial AdaGrad learning rate of GloVe.
+	GLOVE_ALPHA float64 = 0.05
+)
+
+/*
+GloVeModel trains GloVe vectors (Pennington et al., https://nlp.stanford.edu/pubs/glove.pdf) over the same corpus and vocabulary code as word2vec, so the two methods can be compared on an identical vocabulary: Model holds the training options, learns (or reads) the vocabulary like TrainModel and writes the vectors with SaveOutput.
+
+The weighted least squares objective is fit to the log co-occurrence counts of CountCooccurrences with AdaGrad, NumThreads goroutines updating the shared weights without locks on separate parts of the shuffled co-occurrences, for Iter epochs. Alpha is the initial AdaGrad learning rate, GLOVE_ALPHA unless set; GloVe usually needs more epochs (25 or more) than word2vec. After training Model.Syn0 holds the sum of the word and context vectors, which the GloVe paper recommends as output.
+*/
+type GloVeModel struct {
+	Model        *VectorModel
+	XMax         float64            // count where the weighting function reaches 1, default GLOVE_X_MAX
+	Power        float64            // exponent of the weighting function, default GLOVE_POWER
+	Cooccurrence CooccurrenceConfig // counting, DistanceWeighting is on by default
+	Context      []float64          // context vectors, VocabSize rows
+	Bias         []float64          // word biases
+	ContextBias  []float64          // context biases
+	gradsq       []float64          // AdaGrad sums of squared gradients of Syn0 and Context, then of Bias and ContextBias
+}
+
+/*
+NewGloVeModel creates a GloVeModel with the model params of NewWord2VecModel; WindowSkipLen is the co-occurrence window. Subwords and paragraph vectors have no GloVe counterpart and are errors.
+*/
+func NewGloVeModel(trainFile, outFile string, modelParams ...ModelParams) (*GloVeModel, error) {
+	model, err := NewWord2VecModel(trainFile, outFile, modelParams...)
+	if err != nil {
+		return &GloVeModel{}, err
+	}
+	if !model.alphaSet {
+		model.Alpha = GLOVE_ALPHA
+	}
+	if model.Buckets > 0 || model.ParagraphVectors {
+		return &GloVeModel{}, errors.New("GloVe does not support subwords or paragraph vectors")
+	}
+	return &GloVeModel{
+		Model:        model,
+		XMax:         GLOVE_X_MAX,
+		Power:        GLOVE_POWER,
+		Cooccurrence: CooccurrenceConfig{DistanceWeighting: true},
+	}, nil
+}
+
+// InitNet seeds the word and context vectors and biases with small random values like InitNet of word2vec and starts the AdaGrad sums at 1.
+func (g *GloVeModel) InitNet() {
+	v := g.Model
+	fmt.Fprintf(os.Stdout, "Init Net\n")
+	nextRandom := v.initInputVectors()
+	size := v.VocabSize * v.Layer1VecSize
+	g.Context = make([]float64, size)
+	g.Bias = make([]float64, v.VocabSize)
+	g.ContextBias = make([]float64, v.VocabSize)
+	for _, values := range [][]float64{g.Context, g.Bias, g.ContextBias} {
+		for i := range values {
+			nextRandom = nextRandom*25214903917 + 11
+			values[i] = ((float64(nextRandom&0xFFFF) / 65536) - 0.5) / float64(v.Layer1VecSize)
+		}
+	}
+	g.gradsq = make([]float64, 2*size+2*v.VocabSize)
+	for i := range g.gradsq {
+		g.gradsq[i] = 1
+	}
+}
+
+// trainGloVeThread runs one epoch of AdaGrad over the records start to end of the shuffled co-occurrences.
+func (g *GloVeModel) trainGloVeThread(cf *CooccurrenceFile, start, end int64) (stats threadStats, err error) {
+	v := g.Model
+	layer1Size := v.Layer1VecSize
+	size := v.VocabSize * layer1Size
+	gradW, gradC := g.gradsq[:size], g.gradsq[size:2*size]
+	gradB, gradCB := g.gradsq[2*size:2*size+v.VocabSize], g.gradsq[2*size+v.VocabSize:]
+	err = cf.EachRange(start, end, func(r CooccurrenceRecord) error {
+		if r.Count <= 0 {
+			return nil
+		}
+		l1 := int(r.Word) * layer1Size
+		l2 := int(r.Context) * layer1Size
+		diff := g.Bias[r.Word] + g.ContextBias[r.Context] - math.Log(r.Count)
+		for d := 0; d < layer1Size; d++ {
+			diff += v.Syn0[l1+d] * g.Context[l2+d]
+		}
+		fdiff := diff
+		if r.Count < g.XMax {
+			fdiff *= math.Pow(r.Count/g.XMax, g.Power)
+		}
+		if math.IsNaN(diff) || math.IsInf(diff, 0) {
+			return errors.New("GloVe training diverged, lower Alpha")
+		}
+		stats.GloVeLoss += 0.5 * fdiff * diff
+		stats.GloVeObs++
+		stats.Words++
+		fdiff *= v.Alpha
+		// AdaGrad updates of both vectors
+		for d := 0; d < layer1Size; d++ {
+			g1 := fdiff * g.Context[l2+d]
+			g2 := fdiff * v.Syn0[l1+d]
+			v.Syn0[l1+d] -= g1 / math.Sqrt(gradW[l1+d])
+			g.Context[l2+d] -= g2 / math.Sqrt(gradC[l2+d])
+			gradW[l1+d] += g1 * g1
+			gradC[l2+d] += g2 * g2
+		}
+		g.Bias[r.Word] -= fdiff / math.Sqrt(gradB[r.Word])
+		g.ContextBias[r.Context] -= fdiff / math.Sqrt(gradCB[r.Context])
+		fdiff *= fdiff
+		gradB[r.Word] += fdiff
+		gradCB[r.Context] += fdiff
+		return nil
+	})
+	return stats, err
+}
+
+/*
+Train builds (or reads) the vocabulary, counts and shuffles the co-occurrences in temporary files, trains for Iter epochs and writes the vectors to OutputFile with SaveOutput, text, binary or k-means classes as for word2vec. The returned TrainingReport has the GloVe loss of every epoch; it is written next to the output when WriteReport is set.
+*/
+func (g *GloVeModel) Train() (*TrainingReport, error) {
+	v := g.Model
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+	if g.XMax <= 0 || g.Power < 0 {
+		return nil, fmt.Errorf("GloVe needs XMax > 0 and Power >= 0, got %g and %g", g.XMax, g.Power)
+	}
+	if v.TrainFile == "" {
+		return nil, errors.New("No training file specified")
+	}
+	if v.OutputFile == "" {
+		return nil, errors.New("No output file specified")
+	}
+	fmt.Fprintf(os.Stdout, "Starting GloVe training using file %s\n", v.TrainFile)
+	if err := v.prepareVocab(); err != nil {
+		return nil, err
+	}
+
+	counts, err := v.CountCooccurrences(g.Cooccurrence)
+	if err != nil {
+		return nil, err
+	}
+	shuffled, err := counts.Shuffle(int64(v.NextRandom), g.Cooccurrence.MaxEntries, g.Cooccurrence.TempDir)
+	counts.Remove()
+	if err != nil {
+		return nil, err
+	}
+	defer shuffled.Remove()
+	g.InitNet()
+
+	report := &TrainingReport{
+		StartingAlpha: v.Alpha,
+		TrainWords:    v.TrainWords,
+		VocabSize:     v.VocabSize,
+	}
+	v.Start = time.Now()
+	chunk := shuffled.Len() / int64(v.NumThreads)
+	for epoch := 0; epoch < v.Iter; epoch++ {
+		epochStart := time.Now()
+		stats := make([]threadStats, v.NumThreads)
+		errs := make([]error, v.NumThreads)
+		var wg sync.WaitGroup
+		for id := 0; id < v.NumThreads; id++ {
+			end := int64(id+1) * chunk
+			if id == v.NumThreads-1 {
+				end = shuffled.Len()
+			}
+			wg.Add(1)
+			go func(id int, start, end int64) {
+				defer wg.Done()
+				defer recoverThread(&errs[id])
+				stats[id], errs[id] = g.trainGloVeThread(shuffled, start, end)
+			}(id, int64(id)*chunk, end)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			if err != nil {
+				return nil, err
+			}
+		}
+		report.addEpoch(epoch+1, stats, v.Alpha, time.Since(epochStart))
+		if v.DebugMode > 1 {
+			fmt.Fprintf(os.Stdout, "Epoch: %d, cost: %f\n", epoch+1, report.Epochs[epoch].GloVeLoss)
+		}
+	}
+	report.finish(time.Since(v.Start))
+	v.TrainingTime = report.Elapsed
+	for i := range v.Syn0 {
+		v.Syn0[i] += g.Context[i]
+	}
+	if v.DebugMode > 0 {
+		fmt.Fprintf(os.Stdout, "Trained %d co-occurrences in %v, final loss %f\n", shuffled.Len(), report.Elapsed, report.FinalLoss())
+	}
+
+	if err := v.SaveOutput(); err != nil {
+		return report, err
+	}
+	if v.WriteReport {
+		if err := report.WriteJSONFile(v.ReportFile()); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,71 @@
+package wordvec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewGloVeModel(t *testing.T) {
+	g, err := NewGloVeModel(testFileForTraining, "out.txt", VocabHashSizeOption(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Model.Alpha != GLOVE_ALPHA || g.XMax != GLOVE_X_MAX || g.Power != GLOVE_POWER || !g.Cooccurrence.DistanceWeighting {
+		t.Errorf("expected the GloVe defaults, got alpha %f, x max %f, power %f", g.Model.Alpha, g.XMax, g.Power)
+	}
+	if _, err := NewGloVeModel(testFileForTraining, "out.txt", VocabHashSizeOption(10), BucketsOption(100)); err == nil {
+		t.Error("expected an error for a subword GloVe model")
+	}
+}
+
+func TestGloVeTrain(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "vectors.txt")
+	g, err := NewGloVeModel(testFileForTraining, out,
+		VocabHashSizeOption(5000),
+		MinCountOption(1),
+		Layer1VecSizeOption(20),
+		DebugModeOption(0),
+		IterOption(30),
+		NumThreadsOption(2),
+		TrainingReportTrue,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Cooccurrence.TempDir = dir
+	report, err := g.Train()
+	if err != nil {
+		t.Fatal(err)
+	}
+	losses := report.EpochLosses()
+	if len(losses) != 30 || losses[len(losses)-1] >= losses[0]/2 {
+		t.Errorf("loss should go down during training, got %v", losses)
+	}
+	if report.Epochs[0].GloVeLoss == 0 || report.Epochs[0].NegSamplingLoss != 0 {
+		t.Errorf("expected only the GloVe loss, got %+v", report.Epochs[0])
+	}
+	if _, err := os.Stat(g.Model.ReportFile()); err != nil {
+		t.Error("expected the report to be written:", err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 2 {
+		t.Errorf("expected the co-occurrence files to be removed, got %d files", len(files))
+	}
+
+	// written like word2vec vectors over the same vocabulary
+	e := readTextEmbeddings(t, out)
+	if e.Len() != g.Model.VocabSize || e.Dim() != 20 {
+		t.Fatalf("expected %d vectors of size 20, got %d of size %d", g.Model.VocabSize, e.Len(), e.Dim())
+	}
+	for a := 0; a < g.Model.VocabSize; a++ {
+		if e.Word(a) != g.Model.Vocab[a].Word {
+			t.Fatalf("expected the vocabulary order of the model, got %q at %d", e.Word(a), a)
+		}
+	}
+	same, _ := e.Similarity("horse", "dog")
+	other, _ := e.Similarity("horse", "bread")
+	if same <= other {
+		t.Errorf("horse should be closer to dog than to bread, got %f and %f", same, other)
+	}
+}
@@ -0,0 +1,53 @@
+package graph
+
+import "math/rand"
+
+// aliasTable samples from a discrete distribution in constant time with Walker's alias method (Vose's construction).
+type aliasTable struct {
+	prob  []float64
+	alias []int32
+}
+
+// newAliasTable builds the table of the distribution proportional to weights, which must be positive.
+func newAliasTable(weights []float64) aliasTable {
+	n := len(weights)
+	a := aliasTable{prob: make([]float64, n), alias: make([]int32, n)}
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	small := make([]int32, 0, n)
+	large := make([]int32, 0, n)
+	for i, w := range weights {
+		a.prob[i] = w * float64(n) / total
+		if a.prob[i] < 1 {
+			small = append(small, int32(i))
+		} else {
+			large = append(large, int32(i))
+		}
+	}
+	for len(small) > 0 && len(large) > 0 {
+		s, l := small[len(small)-1], large[len(large)-1]
+		small = small[:len(small)-1]
+		a.alias[s] = l
+		a.prob[l] += a.prob[s] - 1
+		if a.prob[l] < 1 {
+			large = large[:len(large)-1]
+			small = append(small, l)
+		}
+	}
+	// what is left is 1 up to rounding
+	for _, i := range append(small, large...) {
+		a.prob[i] = 1
+	}
+	return a
+}
+
+// sample returns an index with probability proportional to its weight.
+func (a aliasTable) sample(rnd *rand.Rand) int {
+	i := rnd.Intn(len(a.prob))
+	if rnd.Float64() < a.prob[i] {
+		return i
+	}
+	return int(a.alias[i])
+}
@@ -0,0 +1,195 @@
+/*
+Package graph learns node embeddings of weighted graphs with DeepWalk (Perozzi, Al-Rfou and Skiena, https://arxiv.org/abs/1403.6652) and node2vec (Grover and Leskovec, https://arxiv.org/abs/1607.00653). Random walks over the graph are the sentences of a skip-gram model that is trained on them in memory with TrainSentences, without writing a corpus file; the node IDs are the words of the vocabulary, so a node's vector is found like any word's.
+
+	g, _ := graph.LoadEdgeList("copurchases.tsv", false)
+	walks, _ := g.Node2VecWalks(graph.WalkConfig{P: 1, Q: 0.5})
+	model, _, _ := graph.Train(walks, "products.txt", wordvec.Layer1VecSizeOption(64))
+	e, _ := model.Embeddings()
+	similar, _ := e.MostSimilar("B000123", 10)
+*/
+package graph
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+Graph is a weighted graph of string node IDs stored as adjacency lists. An undirected graph stores every edge in both directions; adding an edge again adds its weight to the existing edge. A Graph is not safe for concurrent modification, but the walks of a finished graph run in parallel.
+*/
+type Graph struct {
+	Directed bool
+
+	nodes     []string
+	ids       map[string]int
+	neighbors [][]int32   // node -> neighbours, sorted by node once prepared
+	weights   [][]float64 // node -> weights of the edges to neighbors
+	prepared  bool
+}
+
+// New returns an empty graph.
+func New(directed bool) *Graph {
+	return &Graph{Directed: directed, ids: make(map[string]int)}
+}
+
+// Len returns the number of nodes.
+func (g *Graph) Len() int { return len(g.nodes) }
+
+// Node returns the ID of the i-th node, nodes are numbered in the order they were added.
+func (g *Graph) Node(i int) string { return g.nodes[i] }
+
+// Index returns the number of a node or -1 when it is not in the graph.
+func (g *Graph) Index(node string) int {
+	if i, ok := g.ids[node]; ok {
+		return i
+	}
+	return -1
+}
+
+// AddNode adds a node without edges, if it is not in the graph yet, and returns its number.
+func (g *Graph) AddNode(node string) int {
+	if i, ok := g.ids[node]; ok {
+		return i
+	}
+	g.ids[node] = len(g.nodes)
+	g.nodes = append(g.nodes, node)
+	g.neighbors = append(g.neighbors, nil)
+	g.weights = append(g.weights, nil)
+	return len(g.nodes) - 1
+}
+
+// AddEdge adds an edge of a positive weight from src to dst, and from dst to src in an undirected graph.
+func (g *Graph) AddEdge(src, dst string, weight float64) error {
+	if src == "" || dst == "" {
+		return errors.New("Node IDs must not be empty")
+	}
+	if !(weight > 0) || math.IsInf(weight, 0) {
+		return fmt.Errorf("Edge weights must be positive and finite, got %g for %s %s", weight, src, dst)
+	}
+	s, d := g.AddNode(src), g.AddNode(dst)
+	g.neighbors[s] = append(g.neighbors[s], int32(d))
+	g.weights[s] = append(g.weights[s], weight)
+	if !g.Directed && s != d {
+		g.neighbors[d] = append(g.neighbors[d], int32(s))
+		g.weights[d] = append(g.weights[d], weight)
+	}
+	g.prepared = false
+	return nil
+}
+
+// Edges returns the number of distinct edges, counting both directions of an undirected edge once.
+func (g *Graph) Edges() int {
+	g.prepare()
+	n, loops := 0, 0
+	for i, nbrs := range g.neighbors {
+		n += len(nbrs)
+		if g.hasEdge(i, i) {
+			loops++
+		}
+	}
+	if g.Directed {
+		return n
+	}
+	return (n-loops)/2 + loops
+}
+
+// Neighbors returns the IDs and edge weights of the neighbours of a node (its successors in a directed graph), sorted by node number.
+func (g *Graph) Neighbors(node string) ([]string, []float64) {
+	i := g.Index(node)
+	if i == -1 {
+		return nil, nil
+	}
+	g.prepare()
+	ids := make([]string, len(g.neighbors[i]))
+	for k, n := range g.neighbors[i] {
+		ids[k] = g.nodes[n]
+	}
+	return ids, append([]float64(nil), g.weights[i]...)
+}
+
+// prepare sorts the adjacency lists and merges repeated edges so hasEdge can binary search them.
+func (g *Graph) prepare() {
+	if g.prepared {
+		return
+	}
+	for i, nbrs := range g.neighbors {
+		ws := g.weights[i]
+		order := make([]int, len(nbrs))
+		for k := range order {
+			order[k] = k
+		}
+		sort.SliceStable(order, func(a, b int) bool { return nbrs[order[a]] < nbrs[order[b]] })
+		merged := make([]int32, 0, len(nbrs))
+		mergedWeights := make([]float64, 0, len(nbrs))
+		for _, k := range order {
+			if last := len(merged) - 1; last >= 0 && merged[last] == nbrs[k] {
+				mergedWeights[last] += ws[k]
+				continue
+			}
+			merged = append(merged, nbrs[k])
+			mergedWeights = append(mergedWeights, ws[k])
+		}
+		g.neighbors[i], g.weights[i] = merged, mergedWeights
+	}
+	g.prepared = true
+}
+
+// hasEdge reports whether the prepared graph has an edge from a to b.
+func (g *Graph) hasEdge(a, b int) bool {
+	nbrs := g.neighbors[a]
+	k := sort.Search(len(nbrs), func(k int) bool { return nbrs[k] >= int32(b) })
+	return k < len(nbrs) && nbrs[k] == int32(b)
+}
+
+/*
+ReadEdgeList reads a graph from lines of "src dst" or "src dst weight" separated by spaces or tabs; edges without a weight weigh 1. Empty lines and lines starting with "#" are skipped.
+*/
+func ReadEdgeList(r io.Reader, directed bool) (*Graph, error) {
+	g := New(directed)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, fmt.Errorf("Line %d of the edge list is not \"src dst [weight]\": %q", line, text)
+		}
+		weight := 1.0
+		if len(fields) == 3 {
+			w, err := strconv.ParseFloat(fields[2], 64)
+			if err != nil {
+				return nil, fmt.Errorf("Bad weight on line %d of the edge list: %v", line, err)
+			}
+			weight = w
+		}
+		if err := g.AddEdge(fields[0], fields[1], weight); err != nil {
+			return nil, fmt.Errorf("Line %d of the edge list: %v", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	g.prepare()
+	return g, nil
+}
+
+// LoadEdgeList reads a graph from an edge list file, see ReadEdgeList.
+func LoadEdgeList(name string, directed bool) (*Graph, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadEdgeList(f, directed)
+}
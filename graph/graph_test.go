@@ -0,0 +1,198 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jbowles/wordvec"
+)
+
+func TestReadEdgeList(t *testing.T) {
+	g, err := ReadEdgeList(strings.NewReader("# co-purchases\na b 2\nb c\n\na b 0.5\nc c 3\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Len() != 3 || g.Edges() != 3 {
+		t.Fatalf("expected 3 nodes and 3 edges, got %d and %d", g.Len(), g.Edges())
+	}
+	ids, weights := g.Neighbors("b")
+	if !reflect.DeepEqual(ids, []string{"a", "c"}) || !reflect.DeepEqual(weights, []float64{2.5, 1}) {
+		t.Errorf("expected the repeated edge to be merged, got %v %v", ids, weights)
+	}
+	if ids, _ = g.Neighbors("c"); !reflect.DeepEqual(ids, []string{"b", "c"}) {
+		t.Errorf("expected the self loop once, got %v", ids)
+	}
+
+	d, err := ReadEdgeList(strings.NewReader("a b\nb c\n"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids, _ = d.Neighbors("b"); !reflect.DeepEqual(ids, []string{"c"}) || d.Edges() != 2 {
+		t.Errorf("expected only the successors in a directed graph, got %v", ids)
+	}
+
+	for _, bad := range []string{"a\n", "a b c d\n", "a b x\n", "a b -1\n"} {
+		if _, err := ReadEdgeList(strings.NewReader(bad), false); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestAliasTable(t *testing.T) {
+	weights := []float64{1, 2, 3, 4}
+	a := newAliasTable(weights)
+	rnd := rand.New(rand.NewSource(1))
+	counts := make([]float64, len(weights))
+	n := 100000
+	for i := 0; i < n; i++ {
+		counts[a.sample(rnd)]++
+	}
+	for i, w := range weights {
+		if got := counts[i] / float64(n); math.Abs(got-w/10) > 0.01 {
+			t.Errorf("expected %d with probability %.2f, got %.3f", i, w/10, got)
+		}
+	}
+}
+
+// twoCliques returns two cliques of n nodes joined by a single edge.
+func twoCliques(n int) *Graph {
+	g := New(false)
+	for _, prefix := range []string{"a", "b"} {
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				g.AddEdge(fmt.Sprint(prefix, i), fmt.Sprint(prefix, j), 1)
+			}
+		}
+	}
+	g.AddEdge("a0", "b0", 1)
+	return g
+}
+
+func TestWalks(t *testing.T) {
+	g := twoCliques(5)
+	g.AddNode("lonely")
+	cfg := WalkConfig{WalksPerNode: 3, WalkLength: 10, Seed: 7, Threads: 1}
+	walks, err := g.DeepWalkWalks(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(walks) != 3*g.Len() {
+		t.Fatalf("expected %d walks, got %d", 3*g.Len(), len(walks))
+	}
+	starts := make(map[string]int)
+	for _, walk := range walks {
+		starts[walk[0]]++
+		if walk[0] == "lonely" {
+			if len(walk) != 1 {
+				t.Errorf("a walk from a node without edges should end at once, got %v", walk)
+			}
+			continue
+		}
+		if len(walk) != 10 {
+			t.Fatalf("expected walks of 10 nodes, got %v", walk)
+		}
+		for i := 1; i < len(walk); i++ {
+			if !g.hasEdge(g.Index(walk[i-1]), g.Index(walk[i])) {
+				t.Fatalf("walk %v steps over a missing edge", walk)
+			}
+		}
+	}
+	for i := 0; i < g.Len(); i++ {
+		if starts[g.Node(i)] != 3 {
+			t.Errorf("expected 3 walks from %s, got %d", g.Node(i), starts[g.Node(i)])
+		}
+	}
+
+	cfg.Threads = 4
+	again, _ := g.DeepWalkWalks(cfg)
+	if !reflect.DeepEqual(walks, again) {
+		t.Error("walks should only depend on the seed, not on the threads")
+	}
+	if _, err := g.Node2VecWalks(WalkConfig{P: -1}); err == nil {
+		t.Error("expected an error for a negative P")
+	}
+}
+
+// returnRate is the share of node2vec steps that go back to the node before.
+func returnRate(t *testing.T, g *Graph, p, q float64) float64 {
+	walks, err := g.Node2VecWalks(WalkConfig{WalksPerNode: 5, WalkLength: 20, P: p, Q: q, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back, steps float64
+	for _, walk := range walks {
+		for i := 2; i < len(walk); i++ {
+			if walk[i] == walk[i-2] {
+				back++
+			}
+			steps++
+		}
+	}
+	return back / steps
+}
+
+func TestNode2VecBias(t *testing.T) {
+	g := twoCliques(6)
+	// in a clique of 6 an unbiased step returns with probability 1/5
+	low, unbiased, high := returnRate(t, g, 0.05, 1), returnRate(t, g, 1, 1), returnRate(t, g, 20, 1)
+	if !(low > 0.7 && math.Abs(unbiased-0.2) < 0.05 && high < 0.05) {
+		t.Errorf("P should control returning, got %f for a low P, %f unbiased and %f for a high P", low, unbiased, high)
+	}
+
+	// a weighted first step follows the weights
+	w := New(true)
+	w.AddEdge("s", "heavy", 99)
+	w.AddEdge("s", "light", 1)
+	walks, _ := w.Node2VecWalks(WalkConfig{WalksPerNode: 200, WalkLength: 2})
+	heavy := 0
+	for _, walk := range walks {
+		if walk[0] == "s" && walk[1] == "heavy" {
+			heavy++
+		}
+	}
+	if heavy < 180 {
+		t.Errorf("expected most walks to take the heavy edge, got %d of 200", heavy)
+	}
+}
+
+func TestTrain(t *testing.T) {
+	g := twoCliques(8)
+	walks, err := g.Node2VecWalks(WalkConfig{WalksPerNode: 20, WalkLength: 20, Q: 2, Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "nodes.txt")
+	model, report, err := Train(walks, out,
+		wordvec.VocabHashSizeOption(1000),
+		wordvec.Layer1VecSizeOption(16),
+		wordvec.WindowSkipLenOption(3),
+		wordvec.DebugModeOption(0),
+		wordvec.NumThreadsOption(2),
+		func(v *wordvec.VectorModel) error { v.TableSize = 1e5; return nil },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.VocabSize != g.Len()+1 || report.WordsProcessed == 0 {
+		t.Fatalf("expected a vector for every node, got a vocabulary of %d", model.VocabSize)
+	}
+	e, err := model.Embeddings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var same, other float64
+	for i := 1; i < 8; i++ {
+		s, _ := e.Similarity("a1", fmt.Sprint("a", (i+1)%8))
+		o, _ := e.Similarity("a1", fmt.Sprint("b", i))
+		same += s
+		other += o
+	}
+	if same <= other {
+		t.Errorf("nodes of a clique should be closer to each other, got %f within and %f across", same/7, other/7)
+	}
+}
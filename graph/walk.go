@@ -0,0 +1,218 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+
+	"github.com/jbowles/wordvec"
+)
+
+const (
+	// DEFAULT_WALKS_PER_NODE is the number of walks started from every node.
+	DEFAULT_WALKS_PER_NODE int = 10
+	// DEFAULT_WALK_LENGTH is the number of nodes of a walk, including its start.
+	DEFAULT_WALK_LENGTH int = 80
+)
+
+/*
+WalkConfig holds the parameters of the random walks. Zero values take the defaults.
+
+	WalksPerNode	Walks started from every node, in rounds over all nodes in random order.
+	WalkLength		Nodes per walk; a walk ends early at a node without (outgoing) edges.
+	P				node2vec return parameter: 1/P is the weight of going back to the previous node. Default 1.
+	Q				node2vec in-out parameter: 1/Q is the weight of moving away from the previous node. Default 1.
+	Seed			Seed of the random walks; the walks only depend on the graph and the seed, not on Threads.
+	Threads			Goroutines generating walks, default GOMAXPROCS.
+*/
+type WalkConfig struct {
+	WalksPerNode int
+	WalkLength   int
+	P            float64
+	Q            float64
+	Seed         int64
+	Threads      int
+}
+
+func (cfg WalkConfig) withDefaults() (WalkConfig, error) {
+	if cfg.WalksPerNode == 0 {
+		cfg.WalksPerNode = DEFAULT_WALKS_PER_NODE
+	}
+	if cfg.WalkLength == 0 {
+		cfg.WalkLength = DEFAULT_WALK_LENGTH
+	}
+	if cfg.P == 0 {
+		cfg.P = 1
+	}
+	if cfg.Q == 0 {
+		cfg.Q = 1
+	}
+	if cfg.Threads == 0 {
+		cfg.Threads = runtime.GOMAXPROCS(0)
+	}
+	if cfg.WalksPerNode < 1 || cfg.WalkLength < 1 || cfg.Threads < 1 {
+		return cfg, fmt.Errorf("WalksPerNode, WalkLength and Threads must be at least 1, got %d, %d and %d", cfg.WalksPerNode, cfg.WalkLength, cfg.Threads)
+	}
+	if cfg.P < 0 || cfg.Q < 0 {
+		return cfg, fmt.Errorf("P and Q must be positive, got %g and %g", cfg.P, cfg.Q)
+	}
+	return cfg, nil
+}
+
+/*
+DeepWalkWalks returns WalksPerNode uniform random walks from every node: each step moves to a neighbour chosen uniformly, ignoring edge weights and P and Q. The walks of one round over the nodes follow each other, so walk r*Len()+i is the i-th walk of round r.
+*/
+func (g *Graph) DeepWalkWalks(cfg WalkConfig) ([][]string, error) {
+	return g.walks(cfg, false)
+}
+
+/*
+Node2VecWalks returns WalksPerNode biased second order random walks from every node, laid out like DeepWalkWalks. The first step of a walk follows the edge weights; after that, stepping from v to x having come from t weighs w(v,x)/P if x is t, w(v,x) if x is a neighbour of t and w(v,x)/Q otherwise. The transition probabilities are precomputed into alias tables, one per node and, unless P and Q are both 1, one per edge, so sampling a step takes constant time; the edge tables hold a value per pair of adjacent edges, which is the memory cost of node2vec on high degree nodes.
+*/
+func (g *Graph) Node2VecWalks(cfg WalkConfig) ([][]string, error) {
+	return g.walks(cfg, true)
+}
+
+// walker holds the alias tables of node2vec walks.
+type walker struct {
+	g     *Graph
+	nodes []aliasTable   // node -> table over its neighbours by edge weight
+	edges [][]aliasTable // node t -> k-th edge (t,v) -> table over the neighbours of v
+}
+
+func (g *Graph) newWalker(p, q float64, threads int) *walker {
+	w := &walker{g: g, nodes: make([]aliasTable, g.Len())}
+	for i, ws := range g.weights {
+		if len(ws) > 0 {
+			w.nodes[i] = newAliasTable(ws)
+		}
+	}
+	if p == 1 && q == 1 {
+		return w
+	}
+	w.edges = make([][]aliasTable, g.Len())
+	parallel(g.Len(), threads, func(t int) {
+		w.edges[t] = make([]aliasTable, len(g.neighbors[t]))
+		for k, v := range g.neighbors[t] {
+			nbrs := g.neighbors[v]
+			if len(nbrs) == 0 {
+				continue
+			}
+			biased := make([]float64, len(nbrs))
+			for j, x := range nbrs {
+				switch {
+				case int(x) == t:
+					biased[j] = g.weights[v][j] / p
+				case g.hasEdge(int(x), t):
+					biased[j] = g.weights[v][j]
+				default:
+					biased[j] = g.weights[v][j] / q
+				}
+			}
+			w.edges[t][k] = newAliasTable(biased)
+		}
+	})
+	return w
+}
+
+// walk appends a node2vec walk from start to path.
+func (w *walker) walk(start, length int, rnd *rand.Rand, path []string) []string {
+	g := w.g
+	path = append(path, g.nodes[start])
+	cur, prev, edge := start, -1, -1 // edge is the index of cur among the neighbours of prev
+	for len(path) < length && len(g.neighbors[cur]) > 0 {
+		var k int
+		if prev == -1 || w.edges == nil {
+			k = w.nodes[cur].sample(rnd)
+		} else {
+			k = w.edges[prev][edge].sample(rnd)
+		}
+		prev, edge, cur = cur, k, int(g.neighbors[cur][k])
+		path = append(path, g.nodes[cur])
+	}
+	return path
+}
+
+// uniformWalk appends a DeepWalk walk from start to path.
+func (g *Graph) uniformWalk(start, length int, rnd *rand.Rand, path []string) []string {
+	cur := start
+	path = append(path, g.nodes[cur])
+	for len(path) < length && len(g.neighbors[cur]) > 0 {
+		cur = int(g.neighbors[cur][rnd.Intn(len(g.neighbors[cur]))])
+		path = append(path, g.nodes[cur])
+	}
+	return path
+}
+
+// walks runs the rounds of walks in parallel, every round with its own random source so the result does not depend on the number of threads.
+func (g *Graph) walks(cfg WalkConfig, biased bool) ([][]string, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	if g.Len() == 0 {
+		return nil, errors.New("Graph has no nodes")
+	}
+	g.prepare()
+	var w *walker
+	if biased {
+		w = g.newWalker(cfg.P, cfg.Q, cfg.Threads)
+	}
+	n := g.Len()
+	walks := make([][]string, cfg.WalksPerNode*n)
+	parallel(cfg.WalksPerNode, cfg.Threads, func(round int) {
+		rnd := rand.New(rand.NewSource(cfg.Seed + int64(round)))
+		for i, start := range rnd.Perm(n) {
+			path := make([]string, 0, cfg.WalkLength)
+			if biased {
+				path = w.walk(start, cfg.WalkLength, rnd, path)
+			} else {
+				path = g.uniformWalk(start, cfg.WalkLength, rnd, path)
+			}
+			walks[round*n+i] = path
+		}
+	})
+	return walks, nil
+}
+
+// parallel calls fn for 0 to n-1 on up to threads goroutines.
+func parallel(n, threads int, fn func(i int)) {
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for t := 0; t < threads && t < n; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+/*
+Train trains skip-gram node vectors on walks with TrainSentences and returns the model, whose vocabulary words are the node IDs. The model starts with the settings of DeepWalk and node2vec, skip-gram with MinCount 1 (every node gets a vector) and no subsampling, and modelParams are applied after them. The vectors are written to outFile unless it is empty.
+*/
+func Train(walks [][]string, outFile string, modelParams ...wordvec.ModelParams) (*wordvec.VectorModel, *wordvec.TrainingReport, error) {
+	params := append([]wordvec.ModelParams{
+		wordvec.ArchitectureOption(wordvec.ArchSkipGram),
+		wordvec.MinCountOption(1),
+		wordvec.SampleOption(0),
+	}, modelParams...)
+	model, err := wordvec.NewWord2VecModel("", outFile, params...)
+	if err != nil {
+		return nil, nil, err
+	}
+	report, err := model.TrainSentences(walks)
+	if err != nil {
+		return nil, nil, err
+	}
+	return model, report, nil
+}
@@ -0,0 +1,101 @@
+package hnsw
+
+import "sort"
+
+// candidate is a node of the graph and its similarity to the query.
+type candidate struct {
+	id  int
+	sim float32
+}
+
+// sortCandidates sorts best first, ties broken by node.
+func sortCandidates(c []candidate) {
+	sort.Slice(c, func(i, j int) bool {
+		if c[i].sim != c[j].sim {
+			return c[i].sim > c[j].sim
+		}
+		return c[i].id < c[j].id
+	})
+}
+
+// maxHeap pops the most similar candidate first: the frontier of a layer search.
+type maxHeap []candidate
+
+func (h *maxHeap) push(c candidate) {
+	s := append(*h, c)
+	for i := len(s) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if s[parent].sim >= s[i].sim {
+			break
+		}
+		s[parent], s[i] = s[i], s[parent]
+		i = parent
+	}
+	*h = s
+}
+
+func (h *maxHeap) pop() candidate {
+	s := *h
+	top := s[0]
+	last := len(s) - 1
+	s[0] = s[last]
+	s = s[:last]
+	for i := 0; ; {
+		left := 2*i + 1
+		if left >= len(s) {
+			break
+		}
+		best := left
+		if right := left + 1; right < len(s) && s[right].sim > s[left].sim {
+			best = right
+		}
+		if s[i].sim >= s[best].sim {
+			break
+		}
+		s[i], s[best] = s[best], s[i]
+		i = best
+	}
+	*h = s
+	return top
+}
+
+// minHeap pops the least similar candidate first: the root is the worst of the results kept so far.
+type minHeap []candidate
+
+func (h *minHeap) push(c candidate) {
+	s := append(*h, c)
+	for i := len(s) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if s[parent].sim <= s[i].sim {
+			break
+		}
+		s[parent], s[i] = s[i], s[parent]
+		i = parent
+	}
+	*h = s
+}
+
+func (h *minHeap) pop() candidate {
+	s := *h
+	top := s[0]
+	last := len(s) - 1
+	s[0] = s[last]
+	s = s[:last]
+	for i := 0; ; {
+		left := 2*i + 1
+		if left >= len(s) {
+			break
+		}
+		worst := left
+		if right := left + 1; right < len(s) && s[right].sim < s[left].sim {
+			worst = right
+		}
+		if s[i].sim <= s[worst].sim {
+			break
+		}
+		s[i], s[worst] = s[worst], s[i]
+		i = worst
+	}
+	*h = s
+	return top
+}
@@ -0,0 +1,405 @@
+/*
+Package hnsw is an approximate nearest neighbour index over word vectors using Hierarchical Navigable Small World graphs (Malkov and Yashunin, https://arxiv.org/abs/1603.09320).
+
+Vectors are normalized when they are added, so similarities are cosine similarities as everywhere else in wordvec. Build an index from trained vectors, tune EfSearch against the exact search with Recall, and Save it next to the model:
+
+	e, _ := model.Embeddings()
+	index, _ := hnsw.Build(e, hnsw.Config{M: 16, EfConstruction: 200})
+	exact, _ := e.ExactIndex()
+	fmt.Println(index.Recall(exact, queries, 10))
+	neighbors := index.Search(vec, 10)
+*/
+package hnsw
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+
+	"github.com/jbowles/wordvec"
+)
+
+const (
+	// DEFAULT_M is the number of links a node keeps per layer (twice that on the bottom layer).
+	DEFAULT_M int = 16
+	// DEFAULT_EF_CONSTRUCTION is the size of the candidate list searched when a node is inserted.
+	DEFAULT_EF_CONSTRUCTION int = 200
+	// DEFAULT_EF_SEARCH is the size of the candidate list searched by a query; it is raised to k when smaller.
+	DEFAULT_EF_SEARCH int = 64
+)
+
+/*
+Config holds the build parameters of an Index. Zero values take the defaults.
+
+	M				Links per node and layer; higher gives better recall for more memory and slower inserts.
+	EfConstruction	Candidate list size while inserting; higher gives a better graph and slower inserts.
+	EfSearch		Candidate list size while searching; can be changed on the Index at any time.
+	Seed			Seed of the random layer assignment.
+*/
+type Config struct {
+	M              int
+	EfConstruction int
+	EfSearch       int
+	Seed           int64
+}
+
+// Index is an HNSW graph over normalized vectors. It is safe for concurrent use: searches run in parallel, Add takes an exclusive lock.
+type Index struct {
+	M              int
+	EfConstruction int
+	EfSearch       int
+
+	mu        sync.RWMutex
+	dim       int
+	words     []string
+	ids       map[string]int
+	vectors   []float32
+	links     [][][]int32 // node -> layer -> neighbours
+	entry     int
+	maxLevel  int
+	levelMult float64
+	rnd       *rand.Rand
+}
+
+// New returns an empty index for vectors of size dim.
+func New(dim int, cfg Config) (*Index, error) {
+	if dim < 1 {
+		return nil, fmt.Errorf("Vector dimension must be at least 1, got %d", dim)
+	}
+	if cfg.M == 0 {
+		cfg.M = DEFAULT_M
+	}
+	if cfg.EfConstruction == 0 {
+		cfg.EfConstruction = DEFAULT_EF_CONSTRUCTION
+	}
+	if cfg.EfSearch == 0 {
+		cfg.EfSearch = DEFAULT_EF_SEARCH
+	}
+	if cfg.M < 2 {
+		return nil, fmt.Errorf("M must be at least 2, got %d", cfg.M)
+	}
+	if cfg.EfConstruction < 1 || cfg.EfSearch < 1 {
+		return nil, fmt.Errorf("EfConstruction and EfSearch must be at least 1, got %d and %d", cfg.EfConstruction, cfg.EfSearch)
+	}
+	return &Index{
+		M:              cfg.M,
+		EfConstruction: cfg.EfConstruction,
+		EfSearch:       cfg.EfSearch,
+		dim:            dim,
+		ids:            make(map[string]int),
+		entry:          -1,
+		levelMult:      1 / math.Log(float64(cfg.M)),
+		rnd:            rand.New(rand.NewSource(cfg.Seed)),
+	}, nil
+}
+
+// Build returns an index over all vectors of e.
+func Build(e *wordvec.Embeddings, cfg Config) (*Index, error) {
+	x, err := New(e.Dim(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	matrix := e.Matrix()
+	for i := 0; i < e.Len(); i++ {
+		if err = x.Add(e.Word(i), matrix[i*e.Dim():(i+1)*e.Dim()]); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+// Len returns the number of vectors in the index.
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.words)
+}
+
+// Dim returns the size of the vectors.
+func (x *Index) Dim() int { return x.dim }
+
+// Contains reports whether word is in the index.
+func (x *Index) Contains(word string) bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	_, ok := x.ids[word]
+	return ok
+}
+
+func (x *Index) vector(i int) []float32 {
+	return x.vectors[i*x.dim : (i+1)*x.dim]
+}
+
+func (x *Index) similarity(q []float32, i int) float32 {
+	v := x.vector(i)
+	var s float32
+	for d := range q {
+		s += q[d] * v[d]
+	}
+	return s
+}
+
+func normalized(vec []float32) []float32 {
+	var sum float64
+	for _, f := range vec {
+		sum += float64(f) * float64(f)
+	}
+	out := make([]float32, len(vec))
+	if sum == 0 {
+		return out
+	}
+	inv := float32(1 / math.Sqrt(sum))
+	for i, f := range vec {
+		out[i] = f * inv
+	}
+	return out
+}
+
+func (x *Index) maxLinks(level int) int {
+	if level == 0 {
+		return 2 * x.M
+	}
+	return x.M
+}
+
+/*
+Add inserts a word and its vector, e.g. a word learned by online training after the index was built. The vector is copied and normalized. Adding a word that is already in the index is an error.
+*/
+func (x *Index) Add(word string, vec []float32) error {
+	if len(vec) != x.dim {
+		return fmt.Errorf("Expected a vector of size %d, got %d", x.dim, len(vec))
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if _, ok := x.ids[word]; ok {
+		return fmt.Errorf("Word %q is already in the index", word)
+	}
+	q := normalized(vec)
+	id := len(x.words)
+	level := int(-math.Log(1-x.rnd.Float64()) * x.levelMult)
+	x.words = append(x.words, word)
+	x.ids[word] = id
+	x.vectors = append(x.vectors, q...)
+	x.links = append(x.links, make([][]int32, level+1))
+
+	if x.entry < 0 {
+		x.entry, x.maxLevel = id, level
+		return nil
+	}
+	ep := []candidate{{id: x.entry, sim: x.similarity(q, x.entry)}}
+	for l := x.maxLevel; l > level; l-- {
+		ep = x.searchLayer(q, ep, 1, l)
+	}
+	for l := minInt(level, x.maxLevel); l >= 0; l-- {
+		found := x.searchLayer(q, ep, x.EfConstruction, l)
+		neighbours := x.selectNeighbours(found, x.M)
+		x.links[id][l] = neighbours
+		for _, n := range neighbours {
+			x.connect(int(n), int32(id), l)
+		}
+		ep = found
+	}
+	if level > x.maxLevel {
+		x.entry, x.maxLevel = id, level
+	}
+	return nil
+}
+
+// connect links node to n on a layer, pruning the links of node with the selection heuristic when it has too many.
+func (x *Index) connect(node int, n int32, level int) {
+	links := append(x.links[node][level], n)
+	if len(links) <= x.maxLinks(level) {
+		x.links[node][level] = links
+		return
+	}
+	v := x.vector(node)
+	cands := make([]candidate, len(links))
+	for i, l := range links {
+		cands[i] = candidate{id: int(l), sim: x.similarity(v, int(l))}
+	}
+	sortCandidates(cands)
+	x.links[node][level] = x.selectNeighbours(cands, x.maxLinks(level))
+}
+
+/*
+selectNeighbours picks up to m links from candidates sorted best first with the heuristic of the HNSW paper: a candidate is skipped when it is closer to an already selected neighbour than to the new node, which keeps links pointing in diverse directions. Skipped candidates fill up the remaining slots.
+*/
+func (x *Index) selectNeighbours(cands []candidate, m int) []int32 {
+	selected := make([]int32, 0, m)
+	var skipped []int32
+	for _, c := range cands {
+		if len(selected) >= m {
+			break
+		}
+		good := true
+		cv := x.vector(c.id)
+		for _, s := range selected {
+			if x.similarity(cv, int(s)) > c.sim {
+				good = false
+				break
+			}
+		}
+		if good {
+			selected = append(selected, int32(c.id))
+		} else {
+			skipped = append(skipped, int32(c.id))
+		}
+	}
+	for _, s := range skipped {
+		if len(selected) >= m {
+			break
+		}
+		selected = append(selected, s)
+	}
+	return selected
+}
+
+// searchLayer is a best-first search of one layer from the entry points, returning up to ef nodes sorted best first.
+func (x *Index) searchLayer(q []float32, entry []candidate, ef int, level int) []candidate {
+	visited := make(map[int]bool, ef*4)
+	var cands maxHeap
+	var found minHeap
+	for _, e := range entry {
+		visited[e.id] = true
+		cands.push(e)
+		found.push(e)
+		if len(found) > ef {
+			found.pop()
+		}
+	}
+	for len(cands) > 0 {
+		c := cands.pop()
+		if len(found) >= ef && c.sim < found[0].sim {
+			break
+		}
+		if level >= len(x.links[c.id]) {
+			continue
+		}
+		for _, n := range x.links[c.id][level] {
+			id := int(n)
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+			sim := x.similarity(q, id)
+			if len(found) < ef || sim > found[0].sim {
+				cands.push(candidate{id: id, sim: sim})
+				found.push(candidate{id: id, sim: sim})
+				if len(found) > ef {
+					found.pop()
+				}
+			}
+		}
+	}
+	result := make([]candidate, len(found))
+	copy(result, found)
+	sortCandidates(result)
+	return result
+}
+
+// Search returns the k nearest words to query by cosine similarity using EfSearch.
+func (x *Index) Search(query []float32, k int) []wordvec.Neighbor {
+	return x.SearchEf(query, k, x.EfSearch)
+}
+
+// SearchEf returns the k nearest words to query searching with a candidate list of size ef (raised to k when smaller).
+func (x *Index) SearchEf(query []float32, k int, ef int) []wordvec.Neighbor {
+	if len(query) != x.dim || k <= 0 {
+		return nil
+	}
+	if ef < k {
+		ef = k
+	}
+	q := normalized(query)
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if x.entry < 0 {
+		return nil
+	}
+	ep := []candidate{{id: x.entry, sim: x.similarity(q, x.entry)}}
+	for l := x.maxLevel; l > 0; l-- {
+		ep = x.searchLayer(q, ep, 1, l)
+	}
+	found := x.searchLayer(q, ep, ef, 0)
+	if len(found) > k {
+		found = found[:k]
+	}
+	nn := make([]wordvec.Neighbor, len(found))
+	for i, c := range found {
+		nn[i] = wordvec.Neighbor{Word: x.words[c.id], Similarity: float64(c.sim)}
+	}
+	return nn
+}
+
+// SearchWord returns the k nearest words to a word of the index, the word itself excluded.
+func (x *Index) SearchWord(word string, k int) ([]wordvec.Neighbor, error) {
+	x.mu.RLock()
+	id, ok := x.ids[word]
+	var vec []float32
+	if ok {
+		vec = append(vec, x.vector(id)...)
+	}
+	x.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Out of dictionary word: %s", word)
+	}
+	nn := x.Search(vec, k+1)
+	for i, n := range nn {
+		if n.Word == word {
+			return append(nn[:i], nn[i+1:]...), nil
+		}
+	}
+	if len(nn) > k {
+		nn = nn[:k]
+	}
+	return nn, nil
+}
+
+/*
+Recall measures the recall@k of the index against an exact search over the same words: the share of the exact k nearest neighbours of each query that the approximate search also returns, averaged over the queries. Use it to pick M, EfConstruction and EfSearch. Every query must have the dimension of the index; a query without exact neighbours, e.g. on an empty index, is left out of the average.
+*/
+func (x *Index) Recall(exact *wordvec.ExactIndex, queries [][]float32, k int) (float64, error) {
+	if exact.Dim() != x.dim {
+		return 0, errors.New("Exact index and HNSW index have different dimensions")
+	}
+	if len(queries) == 0 || k <= 0 {
+		return 0, errors.New("Recall needs at least one query and k > 0")
+	}
+	for i, q := range queries {
+		if len(q) != x.dim {
+			return 0, fmt.Errorf("Query %d has dimension %d, the index has %d", i, len(q), x.dim)
+		}
+	}
+	var total float64
+	counted := 0
+	for _, q := range queries {
+		want := make(map[string]bool, k)
+		for _, h := range exact.Search(normalized(q), k) {
+			want[exact.Word(h.Index)] = true
+		}
+		if len(want) == 0 {
+			continue
+		}
+		counted++
+		got := 0
+		for _, n := range x.Search(q, k) {
+			if want[n.Word] {
+				got++
+			}
+		}
+		total += float64(got) / float64(len(want))
+	}
+	if counted == 0 {
+		return 0, errors.New("No query has exact neighbours to measure recall against")
+	}
+	return total / float64(counted), nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
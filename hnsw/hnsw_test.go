@@ -0,0 +1,204 @@
+package hnsw
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/jbowles/wordvec"
+)
+
+func testEmbeddings(t *testing.T, n, dim int, seed int64) *wordvec.Embeddings {
+	rnd := rand.New(rand.NewSource(seed))
+	words := make([]string, n)
+	vectors := make([]float32, n*dim)
+	for i := range words {
+		words[i] = fmt.Sprintf("w%d", i)
+	}
+	for i := range vectors {
+		vectors[i] = float32(rnd.NormFloat64())
+	}
+	e, err := wordvec.NewEmbeddings(words, dim, vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func testQueries(n, dim int, seed int64) [][]float32 {
+	rnd := rand.New(rand.NewSource(seed))
+	queries := make([][]float32, n)
+	for i := range queries {
+		queries[i] = make([]float32, dim)
+		for d := range queries[i] {
+			queries[i][d] = float32(rnd.NormFloat64())
+		}
+	}
+	return queries
+}
+
+func TestRecallAgainstExactSearch(t *testing.T) {
+	e := testEmbeddings(t, 2000, 16, 1)
+	index, err := Build(e, Config{M: 12, EfConstruction: 100, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exact, err := e.ExactIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries := testQueries(50, 16, 2)
+
+	index.EfSearch = 10
+	low, err := index.Recall(exact, queries, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.EfSearch = 200
+	high, err := index.Recall(exact, queries, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if high < 0.95 {
+		t.Errorf("expected recall@10 >= 0.95 with EfSearch 200, got %.3f", high)
+	}
+	if high < low {
+		t.Errorf("recall dropped from %.3f to %.3f when raising EfSearch", low, high)
+	}
+	if r, err := index.Recall(exact, append(queries, make([]float32, 3)), 10); err == nil {
+		t.Errorf("a query of the wrong dimension should be an error, got recall %v", r)
+	}
+}
+
+func TestSearchFindsItself(t *testing.T) {
+	e := testEmbeddings(t, 500, 8, 3)
+	index, err := Build(e, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Len() != 500 || index.Dim() != 8 {
+		t.Fatalf("expected 500 vectors of size 8, got %d of size %d", index.Len(), index.Dim())
+	}
+	for _, word := range []string{"w0", "w123", "w499"} {
+		vec, _ := e.Vector(word)
+		nn := index.Search(vec, 1)
+		if len(nn) != 1 || nn[0].Word != word {
+			t.Errorf("expected %s as its own nearest neighbour, got %v", word, nn)
+		}
+		nn, err := index.SearchWord(word, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(nn) != 5 {
+			t.Fatalf("expected 5 neighbours, got %d", len(nn))
+		}
+		for _, n := range nn {
+			if n.Word == word {
+				t.Errorf("SearchWord(%s) returned the word itself", word)
+			}
+		}
+	}
+	if _, err := index.SearchWord("missing", 5); err == nil {
+		t.Error("expected an error for an unknown word")
+	}
+}
+
+func TestIncrementalAdd(t *testing.T) {
+	e := testEmbeddings(t, 300, 8, 4)
+	index, err := Build(e, Config{Seed: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra := testQueries(20, 8, 5)
+	for i, vec := range extra {
+		if err := index.Add(fmt.Sprintf("new%d", i), vec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := index.Add("w0", extra[0]); err == nil {
+		t.Error("expected an error adding a word twice")
+	}
+	if err := index.Add("short", []float32{1}); err == nil {
+		t.Error("expected an error for a vector of the wrong size")
+	}
+	if !index.Contains("new7") || index.Len() != 320 {
+		t.Fatalf("expected 320 words including new7, got %d", index.Len())
+	}
+	for i, vec := range extra {
+		nn := index.Search(vec, 1)
+		if len(nn) != 1 || nn[0].Word != fmt.Sprintf("new%d", i) {
+			t.Errorf("expected new%d as nearest neighbour of its vector, got %v", i, nn)
+		}
+	}
+}
+
+func TestConcurrentSearchAndAdd(t *testing.T) {
+	e := testEmbeddings(t, 300, 8, 6)
+	index, err := Build(e, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries := testQueries(40, 8, 7)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i, q := range queries {
+			index.Add(fmt.Sprintf("c%d", i), q)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for _, q := range queries {
+			index.Search(q, 5)
+		}
+	}()
+	wg.Wait()
+	if index.Len() != 340 {
+		t.Errorf("expected 340 words, got %d", index.Len())
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	e := testEmbeddings(t, 400, 8, 8)
+	index, err := Build(e, Config{M: 8, EfConstruction: 50, EfSearch: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(t.TempDir(), "index.hnsw")
+	if err := index.SaveFile(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.M != 8 || loaded.EfConstruction != 50 || loaded.EfSearch != 30 || loaded.Len() != 400 {
+		t.Fatalf("parameters not restored: M %d, EfConstruction %d, EfSearch %d, Len %d", loaded.M, loaded.EfConstruction, loaded.EfSearch, loaded.Len())
+	}
+	for _, q := range testQueries(10, 8, 9) {
+		want, got := index.Search(q, 5), loaded.Search(q, 5)
+		if fmt.Sprint(want) != fmt.Sprint(got) {
+			t.Fatalf("expected %v after loading, got %v", want, got)
+		}
+	}
+	if err := loaded.Add("after", testQueries(1, 8, 10)[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := index.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	data[len(data)/2] ^= 0xff
+	if _, err := Load(bytes.NewReader(data)); err == nil {
+		t.Error("expected an error loading a corrupted index")
+	}
+	if _, err := Load(bytes.NewReader(data[:len(data)/3])); err == nil {
+		t.Error("expected an error loading a truncated index")
+	}
+}
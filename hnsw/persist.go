@@ -0,0 +1,172 @@
+package hnsw
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+)
+
+const (
+	// FORMAT_MAGIC starts every saved index.
+	FORMAT_MAGIC string = "WVHNSW\x00\x00"
+	// FORMAT_VERSION is the version of the index file format written by Save.
+	FORMAT_VERSION uint32 = 1
+)
+
+/*
+Save writes the index in a little endian binary format followed by a CRC32 of everything before it:
+
+	magic, version, dim, M, EfConstruction, EfSearch, nodes, entry, maxLevel
+	per node: word length and bytes, level, dim float32 values, per layer a link count and the links
+
+The random layer assignment is not saved: an index loaded with Load continues with a generator seeded from its size.
+*/
+func (x *Index) Save(w io.Writer) error {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+	put := func(v uint32) {
+		var b [4]byte
+		binary.LittleEndian.PutUint32(b[:], v)
+		bw.Write(b[:])
+	}
+	bw.WriteString(FORMAT_MAGIC)
+	put(FORMAT_VERSION)
+	for _, v := range []int{x.dim, x.M, x.EfConstruction, x.EfSearch, len(x.words), x.entry, x.maxLevel} {
+		put(uint32(int32(v)))
+	}
+	for i, word := range x.words {
+		put(uint32(len(word)))
+		bw.WriteString(word)
+		put(uint32(len(x.links[i]) - 1))
+		for _, f := range x.vector(i) {
+			put(math.Float32bits(f))
+		}
+		for _, links := range x.links[i] {
+			put(uint32(len(links)))
+			for _, n := range links {
+				put(uint32(n))
+			}
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc.Sum32())
+	_, err := w.Write(sum[:])
+	return err
+}
+
+// SaveFile writes the index to a file, see Save.
+func (x *Index) SaveFile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err = x.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads an index written by Save and checks its checksum.
+func Load(r io.Reader) (*Index, error) {
+	crc := crc32.NewIEEE()
+	br := bufio.NewReader(r)
+	tr := io.TeeReader(br, crc)
+	var err error
+	get := func() int {
+		var b [4]byte
+		if err == nil {
+			_, err = io.ReadFull(tr, b[:])
+		}
+		return int(int32(binary.LittleEndian.Uint32(b[:])))
+	}
+	magic := make([]byte, len(FORMAT_MAGIC))
+	if _, err = io.ReadFull(tr, magic); err != nil || string(magic) != FORMAT_MAGIC {
+		return nil, errors.New("Not an HNSW index file")
+	}
+	if version := uint32(get()); err == nil && version > FORMAT_VERSION {
+		return nil, fmt.Errorf("HNSW index format version %d is newer than the supported version %d", version, FORMAT_VERSION)
+	}
+	dim, m, efc, efs, n, entry, maxLevel := get(), get(), get(), get(), get(), get(), get()
+	if err != nil {
+		return nil, fmt.Errorf("reading HNSW index header: %v", err)
+	}
+	x, err := New(dim, Config{M: m, EfConstruction: efc, EfSearch: efs, Seed: int64(n)})
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || entry < -1 || entry >= n || maxLevel < 0 {
+		return nil, errors.New("Corrupt HNSW index header")
+	}
+	x.entry, x.maxLevel = entry, maxLevel
+	x.words = make([]string, n)
+	x.vectors = make([]float32, n*dim)
+	x.links = make([][][]int32, n)
+	for i := 0; i < n && err == nil; i++ {
+		size := get()
+		if err != nil || size < 0 || size > 1<<20 {
+			break
+		}
+		word := make([]byte, size)
+		if _, err = io.ReadFull(tr, word); err != nil {
+			break
+		}
+		x.words[i] = string(word)
+		x.ids[x.words[i]] = i
+		level := get()
+		if level < 0 || level > maxLevel {
+			return nil, fmt.Errorf("Corrupt HNSW index: node %d has level %d", i, level)
+		}
+		for d := 0; d < dim; d++ {
+			x.vectors[i*dim+d] = math.Float32frombits(uint32(get()))
+		}
+		x.links[i] = make([][]int32, level+1)
+		for l := range x.links[i] {
+			count := get()
+			if count < 0 || count > x.maxLinks(l) {
+				return nil, fmt.Errorf("Corrupt HNSW index: node %d has %d links on layer %d", i, count, l)
+			}
+			links := make([]int32, count)
+			for j := range links {
+				if links[j] = int32(get()); int(links[j]) < 0 || int(links[j]) >= n {
+					return nil, fmt.Errorf("Corrupt HNSW index: node %d links to %d", i, links[j])
+				}
+			}
+			x.links[i][l] = links
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("HNSW index file is truncated: %v", err)
+	}
+	want := crc.Sum32()
+	var sum [4]byte
+	if _, err = io.ReadFull(br, sum[:]); err != nil {
+		return nil, fmt.Errorf("HNSW index file is truncated: %v", err)
+	}
+	if binary.LittleEndian.Uint32(sum[:]) != want {
+		return nil, errors.New("HNSW index checksum mismatch")
+	}
+	x.rnd = rand.New(rand.NewSource(int64(n)))
+	return x, nil
+}
+
+// LoadFile reads an index from a file, see Load.
+func LoadFile(name string) (*Index, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
@@ -0,0 +1,132 @@
+package wordvec
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+/*
+SessionMode selects how the window is formed over the lines of the training data, see the Sessions field of VectorModel. The session modes follow item2vec (Barkan and Koenigstein, https://arxiv.org/abs/1603.04259): every line is a set or session of item IDs, such as the items of one basket, where the order of the items carries little meaning.
+*/
+type SessionMode int
+
+const (
+	// SessionOff trains on lines as sentences with the usual random window of up to WindowSkipLen words.
+	SessionOff SessionMode = iota
+	// SessionWhole puts every item of a line in the window of every other item of the line, so training a line takes time quadratic in its length (at most MaxSentenceLen).
+	SessionWhole
+	// SessionShuffled shuffles the items of a line every time it is trained and then uses the usual window, a cheaper alternative to SessionWhole on long sessions.
+	SessionShuffled
+)
+
+// String returns the name of the session mode as used in model configs.
+func (s SessionMode) String() string {
+	switch s {
+	case SessionOff:
+		return "off"
+	case SessionWhole:
+		return "whole"
+	case SessionShuffled:
+		return "shuffled"
+	}
+	return fmt.Sprintf("SessionMode(%d)", int(s))
+}
+
+// ParseSessionMode returns the session mode with the given name: "off", "whole" or "shuffled".
+func ParseSessionMode(name string) (SessionMode, error) {
+	switch strings.ToLower(name) {
+	case "off", "":
+		return SessionOff, nil
+	case "whole":
+		return SessionWhole, nil
+	case "shuffled":
+		return SessionShuffled, nil
+	}
+	return 0, fmt.Errorf("Unknown session mode %q", name)
+}
+
+// shuffleSession shuffles the items of a session in place for SessionShuffled.
+func shuffleSession(sen []int, nextRandom *uint64) {
+	for i := len(sen) - 1; i > 0; i-- {
+		*nextRandom = *nextRandom*25214903917 + 11
+		j := int(*nextRandom % uint64(i+1))
+		sen[i], sen[j] = sen[j], sen[i]
+	}
+}
+
+// loadCatalog reads the item IDs of CatalogFile, the first field of every line, into the whitelist of items kept regardless of MinCount. It does nothing without a CatalogFile or when the catalog is already loaded.
+func (v *VectorModel) loadCatalog() error {
+	if v.CatalogFile == "" || v.catalog != nil {
+		return nil
+	}
+	f, err := os.Open(v.CatalogFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	catalog := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+			catalog[fields[0]] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	v.catalog = catalog
+	if v.DebugMode > 0 {
+		fmt.Fprintf(os.Stdout, "Catalog items: %d\n", len(catalog))
+	}
+	return nil
+}
+
+// inCatalog reports whether the word is a whitelisted catalog item.
+func (v *VectorModel) inCatalog(word string) bool {
+	return v.catalog[word]
+}
+
+/*
+recommendSimilar returns the n items most similar to the mean of the normalized vectors of itemIDs, skipping the query items and the items in exclude (e.g. those already bought). Unknown items are ignored; it is an error when none of them is known.
+*/
+func recommendSimilar(rows vectorRows, itemIDs []string, exclude []string, n int) ([]Neighbor, error) {
+	query := make([]float32, rows.Dim())
+	buf := make([]float32, rows.Dim())
+	known := 0
+	for _, item := range itemIDs {
+		i, ok := rows.Index(item)
+		if !ok || rows.norm(i) == 0 {
+			continue
+		}
+		norm := rows.norm(i)
+		for d, x := range rows.row(i, buf) {
+			query[d] += x / norm
+		}
+		known++
+	}
+	if known == 0 {
+		return nil, fmt.Errorf("None of the %d items is in the vocabulary", len(itemIDs))
+	}
+	var qnorm float64
+	for _, x := range query {
+		qnorm += float64(x) * float64(x)
+	}
+	if qnorm == 0 || math.IsNaN(qnorm) {
+		return nil, nil
+	}
+	skip := append(append(make([]string, 0, len(itemIDs)+len(exclude)), itemIDs...), exclude...)
+	return mostSimilarVector(rows, query, n, skip), nil
+}
+
+// RecommendSimilar returns the n items most similar to the items together, the items themselves and those in exclude left out; see SessionMode for training item vectors.
+func (e *Embeddings) RecommendSimilar(itemIDs []string, exclude []string, n int) ([]Neighbor, error) {
+	return recommendSimilar(e, itemIDs, exclude, n)
+}
+
+// RecommendSimilar returns the n items most similar to the items together, the items themselves and those in exclude left out.
+func (e *MmapEmbeddings) RecommendSimilar(itemIDs []string, exclude []string, n int) ([]Neighbor, error) {
+	return recommendSimilar(e, itemIDs, exclude, n)
+}
@@ -0,0 +1,173 @@
+package wordvec
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSessionMode(t *testing.T) {
+	for _, s := range []SessionMode{SessionOff, SessionWhole, SessionShuffled} {
+		if got, err := ParseSessionMode(s.String()); err != nil || got != s {
+			t.Errorf("expected %v back from %q, got %v, %v", s, s.String(), got, err)
+		}
+	}
+	if _, err := ParseSessionMode("sliding"); err == nil {
+		t.Error("expected an error for an unknown session mode")
+	}
+	if _, err := NewWord2VecModel("", "", VocabHashSizeOption(10), SessionsOption(SessionWhole), ArchitectureOption(ArchCWindow)); err == nil {
+		t.Error("expected an error for sessions with a positional architecture")
+	}
+}
+
+func TestCatalogKeepsRareItems(t *testing.T) {
+	dir := t.TempDir()
+	catalog := filepath.Join(dir, "catalog.txt")
+	if err := os.WriteFile(catalog, []byte("rare1 Some product\nunseen\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mv, err := NewWord2VecModel("", "", VocabHashSizeOption(1000), MinCountOption(2), DebugModeOption(0), CatalogFileOption(catalog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mv.LearnVocabFromSentences([][]string{{"a", "b", "rare1"}, {"a", "b", "rare2"}}); err != nil {
+		t.Fatal(err)
+	}
+	if mv.SearchVocab("rare1") == -1 || mv.SearchVocab("rare2") != -1 || mv.SearchVocab("unseen") != -1 {
+		t.Errorf("expected only the seen catalog item to be kept below MinCount, got %v", mv.Vocab[:mv.VocabSize])
+	}
+}
+
+// Rare words that sort before a catalog item are discarded; the item moves down past them and stays searchable.
+func TestCatalogItemAfterDiscardedWords(t *testing.T) {
+	catalog := filepath.Join(t.TempDir(), "catalog.txt")
+	if err := os.WriteFile(catalog, []byte("zz\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mv, err := NewWord2VecModel("", "", VocabHashSizeOption(1000), MinCountOption(2), DebugModeOption(0), CatalogFileOption(catalog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mv.LearnVocabFromSentences([][]string{{"a", "b", "x1", "x2", "x3", "zz"}, {"a", "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if mv.VocabSize != 4 {
+		t.Errorf("expected </s>, a, b and zz, got %v", mv.Vocab[:mv.VocabSize])
+	}
+	for i := 0; i < mv.VocabSize; i++ {
+		if got := mv.SearchVocab(mv.Vocab[i].Word); got != i {
+			t.Errorf("%q should be found at %d, got %d", mv.Vocab[i].Word, i, got)
+		}
+	}
+	if i := mv.SearchVocab("zz"); i == -1 || i >= mv.VocabSize {
+		t.Errorf("catalog item zz should be kept inside the vocabulary, got index %d", i)
+	}
+	for _, word := range []string{"x1", "x2", "x3"} {
+		if mv.SearchVocab(word) != -1 {
+			t.Errorf("%s should be discarded", word)
+		}
+	}
+}
+
+// writeSessions writes sessions of 6 items of either the a or the b category of n items each, in random order.
+func writeSessions(t *testing.T, n, sessions int) string {
+	rnd := rand.New(rand.NewSource(5))
+	var sb strings.Builder
+	for s := 0; s < sessions; s++ {
+		category := "ab"[s%2 : s%2+1]
+		for i, item := range rnd.Perm(n)[:6] {
+			if i > 0 {
+				sb.WriteByte(' ')
+			}
+			fmt.Fprintf(&sb, "%s%d", category, item)
+		}
+		sb.WriteByte('\n')
+	}
+	name := filepath.Join(t.TempDir(), "sessions.txt")
+	if err := os.WriteFile(name, []byte(sb.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestSessionTraining(t *testing.T) {
+	sessions := writeSessions(t, 12, 600)
+	for _, mode := range []SessionMode{SessionWhole, SessionShuffled} {
+		mv, err := NewWord2VecModel(sessions, filepath.Join(t.TempDir(), "items.txt"),
+			VocabHashSizeOption(1000),
+			MinCountOption(1),
+			Layer1VecSizeOption(16),
+			DebugModeOption(0),
+			IterOption(5),
+			NumThreadsOption(2),
+			WindowSkipLenOption(1),
+			SampleOption(0),
+			BagOfWordsFalse,
+			SessionsOption(mode),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mv.TableSize = 1e5
+		if _, err := mv.TrainModel(); err != nil {
+			t.Fatal(err)
+		}
+		e, err := mv.Embeddings()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var same, other float64
+		for i := 1; i < 12; i++ {
+			s, _ := e.Similarity("a0", fmt.Sprint("a", i))
+			o, _ := e.Similarity("a0", fmt.Sprint("b", i))
+			same += s
+			other += o
+		}
+		if same <= other {
+			t.Errorf("%v: items of a category should be closer, got %f within and %f across", mode, same/11, other/11)
+		}
+
+		recs, err := e.RecommendSimilar([]string{"a0", "a1"}, []string{"a2"}, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range recs {
+			if r.Word == "a0" || r.Word == "a1" || r.Word == "a2" {
+				t.Errorf("%v: recommendations should leave out the query and excluded items, got %v", mode, recs)
+			}
+		}
+		if len(recs) != 5 || !strings.HasPrefix(recs[0].Word, "a") {
+			t.Errorf("%v: expected 5 recommendations starting from the same category, got %v", mode, recs)
+		}
+	}
+}
+
+func TestRecommendSimilar(t *testing.T) {
+	e, err := NewEmbeddings([]string{"x", "y", "z", "w", "v"}, 2, []float32{
+		1, 0,
+		0, 10,
+		1, 1,
+		-1, 0,
+		2, 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the query is the mean of the normalized vectors, so the long y counts as much as x
+	recs, err := e.RecommendSimilar([]string{"x", "y", "unknown"}, nil, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 || recs[0].Word != "z" || recs[1].Word != "v" {
+		t.Errorf("expected z then v, got %v", recs)
+	}
+	if recs, _ = e.RecommendSimilar([]string{"x", "y"}, []string{"z"}, 1); len(recs) != 1 || recs[0].Word != "v" {
+		t.Errorf("expected the excluded z to be skipped, got %v", recs)
+	}
+	if _, err := e.RecommendSimilar([]string{"unknown"}, nil, 2); err == nil {
+		t.Error("expected an error when no item is known")
+	}
+}
@@ -0,0 +1,249 @@
+package wordvec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"unsafe"
+)
+
+// littleEndianHost is true when float32 values in a little endian file can be used in place.
+var littleEndianHost = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+/*
+MmapEmbeddings is a read-only set of word vectors backed by a memory-mapped model file. The file is mapped shared, so every process on a host serving the same file uses the same page cache, and opening it only reads the vocabulary.
+
+For a native model file (see Save) the float32 matrix of the EMBF section is used straight from the mapping. For a file in the original word2vec binary format (Binaryf) the rows are not aligned, so they are decoded from the mapping as they are read.
+
+The vector norms needed for cosine similarity are computed on the first similarity query. Close unmaps the file; the MmapEmbeddings must not be used afterwards.
+*/
+type MmapEmbeddings struct {
+	data      []byte
+	words     []string
+	index     map[string]int
+	dim       int
+	vectors   []float32 // the matrix when it can be used in place
+	offsets   []int     // otherwise the offset of each row in data
+	normsOnce sync.Once
+	norms     []float32
+}
+
+// OpenMmapEmbeddings maps a native or word2vec binary model file. The format is detected from the file header.
+func OpenMmapEmbeddings(name string) (*MmapEmbeddings, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() > math.MaxInt {
+		return nil, fmt.Errorf("%s is too large to map", name)
+	}
+	data, err := mmapFile(f, int(fi.Size()))
+	if err != nil {
+		return nil, err
+	}
+	e := &MmapEmbeddings{data: data}
+	if bytes.HasPrefix(data, []byte(NATIVE_FORMAT_MAGIC)) {
+		err = e.parseNative()
+	} else {
+		err = e.parseBinary()
+	}
+	if err != nil {
+		munmapFile(data)
+		return nil, fmt.Errorf("mapping %s: %v", name, err)
+	}
+	e.index = make(map[string]int, len(e.words))
+	for i, w := range e.words {
+		if _, ok := e.index[w]; !ok {
+			e.index[w] = i
+		}
+	}
+	return e, nil
+}
+
+// parseNative locates the metadata, vocabulary and float32 matrix sections of a mapped native model file. The checksum is not verified, that would read the whole file.
+func (e *MmapEmbeddings) parseNative() error {
+	data := e.data
+	if len(data) < 16 {
+		return errors.New("truncated native model header")
+	}
+	if version := binary.LittleEndian.Uint32(data[8:]); version > NATIVE_FORMAT_VERSION {
+		return fmt.Errorf("Native model format version %d is newer than the supported version %d", version, NATIVE_FORMAT_VERSION)
+	}
+	sections := make(map[string][2]int)
+	pos := 16
+	for {
+		if len(data)-pos < 16 {
+			return errors.New("truncated native model section")
+		}
+		tag := string(data[pos : pos+4])
+		length := binary.LittleEndian.Uint64(data[pos+8:])
+		pos += 16
+		if length > uint64(len(data)-pos) {
+			return fmt.Errorf("native model section %q is truncated", tag)
+		}
+		if tag == sectionEnd {
+			break
+		}
+		sections[tag] = [2]int{pos, pos + int(length)}
+		pos += int(length)
+		if pad := length % 8; pad != 0 {
+			pos += int(8 - pad)
+		}
+	}
+
+	metaPos, ok := sections[sectionMeta]
+	if !ok {
+		return errors.New("Native model has no metadata section")
+	}
+	var meta NativeMetadata
+	if err := json.Unmarshal(data[metaPos[0]:metaPos[1]], &meta); err != nil {
+		return fmt.Errorf("reading native model metadata: %v", err)
+	}
+	dim, err := strconv.Atoi(meta.Params["Layer1VecSize"])
+	if err != nil || dim < 1 || dim > len(data)/4 {
+		return fmt.Errorf("bad Layer1VecSize in native model metadata: %q", meta.Params["Layer1VecSize"])
+	}
+	e.dim = dim
+	vocabPos := sections[sectionVocab]
+	if e.words, err = vocabWords(data[vocabPos[0]:vocabPos[1]], meta.VocabSize); err != nil {
+		return err
+	}
+	embedPos, ok := sections[sectionEmbed]
+	if !ok {
+		return errors.New("Native model has no float32 word vectors (EMBF section)")
+	}
+	// Compared by division, 4*len(e.words)*dim of a corrupt header could overflow
+	if n := embedPos[1] - embedPos[0]; n%(4*dim) != 0 || n/(4*dim) != len(e.words) {
+		return fmt.Errorf("native model section %q has %d bytes, expected %d", sectionEmbed, embedPos[1]-embedPos[0], 4*len(e.words)*dim)
+	}
+	if littleEndianHost {
+		if len(e.words) > 0 {
+			e.vectors = unsafe.Slice((*float32)(unsafe.Pointer(&data[embedPos[0]])), len(e.words)*dim)
+		}
+		return nil
+	}
+	e.offsets = make([]int, len(e.words))
+	for i := range e.offsets {
+		e.offsets[i] = embedPos[0] + 4*i*dim
+	}
+	return nil
+}
+
+// parseBinary indexes the rows of a mapped word2vec binary file: a "<words> <dim>" header line, then per word the word, a space and dim little endian float32 values.
+func (e *MmapEmbeddings) parseBinary() error {
+	data := e.data
+	nl := bytes.IndexByte(data, '\n')
+	if nl < 0 {
+		return errors.New("missing word2vec header line")
+	}
+	var size int
+	if _, err := fmt.Sscanf(string(data[:nl]), "%d %d", &size, &e.dim); err != nil || size < 0 || e.dim < 1 {
+		return fmt.Errorf("bad word2vec header line %q", data[:nl])
+	}
+	if size > 0 && e.dim > len(data)/4 {
+		return errors.New("word2vec file is truncated after 0 words")
+	}
+	// The header is not trusted to size the index: every row takes at least its 4*dim bytes, a one byte word and a space
+	rows := size
+	if most := len(data) / (4*e.dim + 2); rows > most {
+		rows = most
+	}
+	e.words = make([]string, 0, rows)
+	e.offsets = make([]int, 0, rows)
+	pos := nl + 1
+	for len(e.words) < size {
+		for pos < len(data) && (data[pos] == '\n' || data[pos] == ' ') {
+			pos++
+		}
+		sp := bytes.IndexByte(data[pos:], ' ')
+		if sp < 0 {
+			return fmt.Errorf("word2vec file is truncated after %d words", len(e.words))
+		}
+		e.words = append(e.words, string(data[pos:pos+sp]))
+		pos += sp + 1
+		if len(data)-pos < 4*e.dim {
+			return fmt.Errorf("word2vec file is truncated after %d words", len(e.words)-1)
+		}
+		e.offsets = append(e.offsets, pos)
+		pos += 4 * e.dim
+	}
+	return nil
+}
+
+// Close unmaps the file.
+func (e *MmapEmbeddings) Close() error {
+	data := e.data
+	e.data, e.vectors = nil, nil
+	return munmapFile(data)
+}
+
+// Len returns the number of words.
+func (e *MmapEmbeddings) Len() int { return len(e.words) }
+
+// Dim returns the size of the vectors.
+func (e *MmapEmbeddings) Dim() int { return e.dim }
+
+// Word returns the i-th word.
+func (e *MmapEmbeddings) Word(i int) string { return e.words[i] }
+
+// Words returns all words in row order. The slice must not be modified.
+func (e *MmapEmbeddings) Words() []string { return e.words }
+
+// Index returns the row of word.
+func (e *MmapEmbeddings) Index(word string) (int, bool) {
+	i, ok := e.index[word]
+	return i, ok
+}
+
+func (e *MmapEmbeddings) row(i int, buf []float32) []float32 {
+	if e.vectors != nil {
+		return e.vectors[i*e.dim : (i+1)*e.dim]
+	}
+	p := e.data[e.offsets[i]:]
+	for d := range buf[:e.dim] {
+		buf[d] = math.Float32frombits(binary.LittleEndian.Uint32(p[4*d:]))
+	}
+	return buf[:e.dim]
+}
+
+func (e *MmapEmbeddings) norm(i int) float32 {
+	e.normsOnce.Do(func() { e.norms = rowNorms(e) })
+	return e.norms[i]
+}
+
+// Matrix returns the row-major matrix of all vectors as mapped from a native model file, or nil when the rows have to be decoded. It must not be modified: the mapping is read-only.
+func (e *MmapEmbeddings) Matrix() []float32 { return e.vectors }
+
+// Vector returns a copy of the vector of word, or false if the word is unknown.
+func (e *MmapEmbeddings) Vector(word string) ([]float32, bool) {
+	return vectorOf(e, word)
+}
+
+// MostSimilar returns the n words most similar to word by cosine similarity, the word itself excluded.
+func (e *MmapEmbeddings) MostSimilar(word string, n int) ([]Neighbor, error) {
+	return mostSimilar(e, word, n)
+}
+
+// MostSimilarVector returns the n words most similar to vec by cosine similarity, skipping the words in exclude.
+func (e *MmapEmbeddings) MostSimilarVector(vec []float32, n int, exclude ...string) []Neighbor {
+	return mostSimilarVector(e, vec, n, exclude)
+}
+
+// Similarity returns the cosine similarity of two words.
+func (e *MmapEmbeddings) Similarity(a, b string) (float64, error) {
+	return similarity(e, a, b)
+}
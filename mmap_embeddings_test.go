@@ -0,0 +1,86 @@
+package wordvec
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func checkSameNeighbors(t *testing.T, want, got []Neighbor) {
+	if len(got) != len(want) {
+		t.Fatalf("expected %d neighbors, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].Word != want[i].Word || got[i].Similarity-want[i].Similarity > 1e-5 || want[i].Similarity-got[i].Similarity > 1e-5 {
+			t.Errorf("neighbor %d should be %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestMmapEmbeddingsNative(t *testing.T) {
+	mv := trainSmallModel(t, IterOption(1))
+	name := filepath.Join(t.TempDir(), "model.wv")
+	if err := mv.SaveFile(name); err != nil {
+		t.Fatal(err)
+	}
+	mapped, err := OpenMmapEmbeddings(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mapped.Close()
+	inMemory, _ := mv.Embeddings()
+	if mapped.Len() != inMemory.Len() || mapped.Dim() != inMemory.Dim() {
+		t.Fatalf("mapped embeddings should be %dx%d, got %dx%d", inMemory.Len(), inMemory.Dim(), mapped.Len(), mapped.Dim())
+	}
+	if mapped.Matrix() == nil {
+		t.Error("the matrix of a native file should be used in place")
+	}
+	word := mv.Vocab[5].Word
+	want, _ := inMemory.MostSimilar(word, 5)
+	got, err := mapped.MostSimilar(word, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSameNeighbors(t, want, got)
+}
+
+func TestMmapEmbeddingsBinary(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "vectors.bin")
+	mv := newSmallTrainingModel(t, name, BinaryFileTrue, IterOption(1))
+	if _, err := mv.TrainModel(); err != nil {
+		t.Fatal(err)
+	}
+	mapped, err := OpenMmapEmbeddings(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mapped.Close()
+	inMemory, _ := mv.Embeddings()
+	if mapped.Len() != mv.VocabSize || mapped.Word(7) != mv.Vocab[7].Word {
+		t.Fatalf("mapped binary file should have the model's vocab, got %d words", mapped.Len())
+	}
+	vec, ok := mapped.Vector(mv.Vocab[7].Word)
+	want, _ := inMemory.Vector(mv.Vocab[7].Word)
+	if !ok || vec[0] != want[0] || vec[len(vec)-1] != want[len(want)-1] {
+		t.Errorf("vector should be %v, got %v", want, vec)
+	}
+	wantNN, _ := inMemory.MostSimilar(mv.Vocab[2].Word, 4)
+	gotNN, _ := mapped.MostSimilar(mv.Vocab[2].Word, 4)
+	checkSameNeighbors(t, wantNN, gotNN)
+}
+
+func TestMmapEmbeddingsBadFile(t *testing.T) {
+	if _, err := OpenMmapEmbeddings("testdata/read_word_test.txt"); err == nil {
+		t.Error("a text file should not map as embeddings")
+	}
+	for _, header := range []string{"10000000000000 100\nab", "3 1000000000000000000\nab"} {
+		name := filepath.Join(t.TempDir(), "huge.bin")
+		if err := os.WriteFile(name, []byte(header), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := OpenMmapEmbeddings(name); err == nil || !strings.Contains(err.Error(), "truncated") {
+			t.Errorf("header %q of a tiny file should be truncated, got %v", header, err)
+		}
+	}
+}
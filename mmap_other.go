@@ -0,0 +1,20 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package wordvec
+
+import (
+	"io"
+	"os"
+)
+
+// mmapFile reads the whole file into memory on platforms without syscall.Mmap; MmapEmbeddings then works the same but without shared pages.
+func mmapFile(f *os.File, size int) ([]byte, error) {
+	data := make([]byte, size)
+	_, err := io.ReadFull(f, data)
+	return data, err
+}
+
+func munmapFile(data []byte) error {
+	return nil
+}
@@ -0,0 +1,267 @@
+package wordvec
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// configField maps a key of a model config file onto a VectorModel field: get formats the field of a model, option parses a value into the ModelParams setting it.
+type configField struct {
+	key    string
+	get    func(v *VectorModel) string
+	option func(value string) (ModelParams, error)
+}
+
+/*
+configFields lists every field a model config can hold, in the order their options are applied. BinaryFileTrue checks the extension of OutputFile, so OutputFile is applied first.
+*/
+var configFields = []configField{
+	{"OutputFile", func(v *VectorModel) string { return v.OutputFile }, stringConfigOption(OutputFileOption)},
+	{"TrainFile", func(v *VectorModel) string { return v.TrainFile }, stringConfigOption(TrainFileOption)},
+	{"Buckets", func(v *VectorModel) string { return strconv.Itoa(v.Buckets) }, intConfigOption(BucketsOption)},
+	{"MinN", func(v *VectorModel) string { return strconv.Itoa(v.MinN) }, intConfigOption(MinNOption)},
+	{"MaxN", func(v *VectorModel) string { return strconv.Itoa(v.MaxN) }, intConfigOption(MaxNOption)},
+	{"Architecture", func(v *VectorModel) string { return v.Architecture.String() }, func(value string) (ModelParams, error) {
+		a, err := ParseArchitecture(value)
+		if err != nil {
+			return nil, err
+		}
+		return ArchitectureOption(a), nil
+	}},
+	{"Sessions", func(v *VectorModel) string { return v.Sessions.String() }, func(value string) (ModelParams, error) {
+		s, err := ParseSessionMode(value)
+		if err != nil {
+			return nil, err
+		}
+		return SessionsOption(s), nil
+	}},
+	{"CatalogFile", func(v *VectorModel) string { return v.CatalogFile }, stringConfigOption(CatalogFileOption)},
+	{"Alpha", func(v *VectorModel) string { return formatConfigFloat(v.Alpha) }, floatConfigOption(AlphaOption)},
+	{"Binaryf", func(v *VectorModel) string { return strconv.FormatBool(v.Binaryf) }, boolConfigOption(func(b bool) ModelParams {
+		if b {
+			return BinaryFileTrue
+		}
+		return func(v *VectorModel) error {
+			v.Binaryf = false
+			return nil
+		}
+	})},
+	{"DebugMode", func(v *VectorModel) string { return strconv.Itoa(v.DebugMode) }, intConfigOption(DebugModeOption)},
+	{"VocabInFile", func(v *VectorModel) string { return v.VocabInFile }, stringConfigOption(VocabInFileOption)},
+	{"Iter", func(v *VectorModel) string { return strconv.Itoa(v.Iter) }, intConfigOption(IterOption)},
+	{"KmeansClasses", func(v *VectorModel) string { return strconv.Itoa(v.KmeansClasses) }, intConfigOption(KmeansClassesOption)},
+	{"Layer1VecSize", func(v *VectorModel) string { return strconv.Itoa(v.Layer1VecSize) }, intConfigOption(Layer1VecSizeOption)},
+	{"MaxCodeLen", func(v *VectorModel) string { return strconv.Itoa(v.MaxCodeLen) }, intConfigOption(MaxCodeLenOption)},
+	{"MaxSentenceLen", func(v *VectorModel) string { return strconv.Itoa(v.MaxSentenceLen) }, intConfigOption(MaxSentenceLenOption)},
+	{"MaxStringLen", func(v *VectorModel) string { return strconv.Itoa(v.MaxStringLen) }, intConfigOption(MaxStringLenOption)},
+	{"MinCount", func(v *VectorModel) string { return strconv.Itoa(v.MinCount) }, intConfigOption(MinCountOption)},
+	{"MinReduce", func(v *VectorModel) string { return strconv.Itoa(v.MinReduce) }, intConfigOption(MinReduceOption)},
+	{"NegSampling", func(v *VectorModel) string { return strconv.Itoa(v.NegSampling) }, intConfigOption(NegSamplingOption)},
+	{"NextRandom", func(v *VectorModel) string { return strconv.FormatUint(v.NextRandom, 10) }, uintConfigOption(NextRandomOption)},
+	{"NumThreads", func(v *VectorModel) string { return strconv.Itoa(v.NumThreads) }, intConfigOption(NumThreadsOption)},
+	{"VocabOutFile", func(v *VectorModel) string { return v.VocabOutFile }, stringConfigOption(VocabOutFileOption)},
+	{"ParagraphVectors", func(v *VectorModel) string { return strconv.FormatBool(v.ParagraphVectors) }, boolConfigOption(func(b bool) ModelParams {
+		return func(v *VectorModel) error {
+			v.ParagraphVectors = b
+			return nil
+		}
+	})},
+	{"Sample", func(v *VectorModel) string { return formatConfigFloat(v.Sample) }, floatConfigOption(SampleOption)},
+	{"SoftMax", func(v *VectorModel) string { return strconv.FormatBool(v.SoftMax) }, boolConfigOption(func(b bool) ModelParams {
+		if b {
+			return SoftMaxOptionTrue
+		}
+		return func(v *VectorModel) error {
+			v.SoftMax = false
+			return nil
+		}
+	})},
+	{"StartingAlpha", func(v *VectorModel) string { return formatConfigFloat(v.StartingAlpha) }, floatConfigOption(StartingAlphaOption)},
+	{"PhraseScorer", func(v *VectorModel) string { return v.PhraseScorer.String() }, func(value string) (ModelParams, error) {
+		s, err := ParsePhraseScorer(value)
+		if err != nil {
+			return nil, err
+		}
+		return PhraseScorerOption(s), nil
+	}},
+	{"Threshold", func(v *VectorModel) string { return formatConfigFloat(v.Threshold) }, floatConfigOption(ThresholdOption)},
+	{"VocabHashSize", func(v *VectorModel) string { return strconv.Itoa(v.VocabHashSize) }, intConfigOption(VocabHashSizeOption)},
+	{"VocabMaxSize", func(v *VectorModel) string { return strconv.Itoa(v.VocabMaxSize) }, intConfigOption(VocabMaxSizeOption)},
+	{"WindowSkipLen", func(v *VectorModel) string { return strconv.Itoa(v.WindowSkipLen) }, intConfigOption(WindowSkipLenOption)},
+	{"WriteReport", func(v *VectorModel) string { return strconv.FormatBool(v.WriteReport) }, boolConfigOption(func(b bool) ModelParams {
+		return func(v *VectorModel) error {
+			v.WriteReport = b
+			return nil
+		}
+	})},
+}
+
+// legacyConfigFields are read but no longer written: Cbow = false in an older config or native model selects skip-gram.
+var legacyConfigFields = []configField{
+	{"Cbow", nil, boolConfigOption(func(b bool) ModelParams {
+		if b {
+			return ArchitectureOption(ArchCBOW)
+		}
+		return ArchitectureOption(ArchSkipGram)
+	})},
+}
+
+func stringConfigOption(option func(string) func(*VectorModel) error) func(string) (ModelParams, error) {
+	return func(value string) (ModelParams, error) {
+		return option(value), nil
+	}
+}
+
+func intConfigOption(option func(int) func(*VectorModel) error) func(string) (ModelParams, error) {
+	return func(value string) (ModelParams, error) {
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		return option(i), nil
+	}
+}
+
+func uintConfigOption(option func(uint64) func(*VectorModel) error) func(string) (ModelParams, error) {
+	return func(value string) (ModelParams, error) {
+		u, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return option(u), nil
+	}
+}
+
+func floatConfigOption(option func(float64) func(*VectorModel) error) func(string) (ModelParams, error) {
+	return func(value string) (ModelParams, error) {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+		return option(f), nil
+	}
+}
+
+func boolConfigOption(option func(bool) ModelParams) func(string) (ModelParams, error) {
+	return func(value string) (ModelParams, error) {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+		return option(b), nil
+	}
+}
+
+func formatConfigFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+/*
+LoadModelConfig reads a model config and returns the ModelParams that set every field it holds. Pass them to NewWord2VecModel (fields not in the config keep their defaults); TrainFile and OutputFile in the config override the constructor arguments:
+
+	params, err := LoadModelConfig(f)
+	if err != nil {
+		return err
+	}
+	wvm, err := NewWord2VecModel("", "", params...)
+
+A config has one "Field = value" pair per line, where Field is the name of a VectorModel field (case is ignored). Blank lines and lines starting with '#' are skipped. Unknown fields, repeated fields and values that don't parse are errors naming the line. The options are returned in a fixed order, not the order of the file, so that options depending on each other (e.g. OutputFile and Binaryf) always combine the same way. (*VectorModel).Config writes a config in this format.
+*/
+func LoadModelConfig(r io.Reader) ([]ModelParams, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		eq := strings.Index(text, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("config line %d: expected 'Field = value', got %q", line, text)
+		}
+		key := strings.TrimSpace(text[:eq])
+		value := strings.TrimSpace(text[eq+1:])
+		field, ok := lookupConfigField(key)
+		if !ok {
+			return nil, fmt.Errorf("config line %d: unknown field %q", line, key)
+		}
+		if _, seen := values[field.key]; seen {
+			return nil, fmt.Errorf("config line %d: field %s is set more than once", line, field.key)
+		}
+		if _, err := field.option(value); err != nil {
+			return nil, fmt.Errorf("config line %d: bad value for %s: %v", line, field.key, err)
+		}
+		values[field.key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return configParams(values)
+}
+
+// ModelConfigParams returns the ModelParams for config values keyed by field name (case is ignored), like LoadModelConfig does for the lines of a config; unknown fields, fields given twice and values that don't parse are errors.
+func ModelConfigParams(values map[string]string) ([]ModelParams, error) {
+	fields := make(map[string]string, len(values))
+	for key, value := range values {
+		field, ok := lookupConfigField(key)
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q", key)
+		}
+		if _, seen := fields[field.key]; seen {
+			return nil, fmt.Errorf("field %s is set more than once", field.key)
+		}
+		fields[field.key] = value
+	}
+	return configParams(fields)
+}
+
+// configParams returns the ModelParams for the config values keyed by field name, in the order of configFields after the legacy fields, so that Architecture overrides Cbow. Keys that are not config fields are ignored.
+func configParams(values map[string]string) ([]ModelParams, error) {
+	var params []ModelParams
+	for _, field := range append(legacyConfigFields, configFields...) {
+		value, ok := values[field.key]
+		if !ok {
+			continue
+		}
+		mp, err := field.option(value)
+		if err != nil {
+			return nil, fmt.Errorf("bad value for %s: %v", field.key, err)
+		}
+		params = append(params, mp)
+	}
+	return params, nil
+}
+
+// configValues returns every config field of the model keyed by field name.
+func (v *VectorModel) configValues() map[string]string {
+	values := make(map[string]string, len(configFields))
+	for _, field := range configFields {
+		values[field.key] = field.get(v)
+	}
+	return values
+}
+
+func lookupConfigField(key string) (configField, bool) {
+	for _, field := range append(legacyConfigFields, configFields...) {
+		if strings.EqualFold(field.key, key) {
+			return field, true
+		}
+	}
+	return configField{}, false
+}
+
+// Config exports the model's hyperparameters as a config that LoadModelConfig reads back, so the exact settings of a trained model can be saved alongside it and replayed.
+func (v *VectorModel) Config() string {
+	var buf bytes.Buffer
+	for _, field := range configFields {
+		fmt.Fprintf(&buf, "%s = %s\n", field.key, field.get(v))
+	}
+	return buf.String()
+}
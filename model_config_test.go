@@ -0,0 +1,108 @@
+package wordvec
+
+import (
+	"strings"
+	"testing"
+)
+
+var testModelConfig string = `
+# skip-gram experiment
+TrainFile = testdata/train_small.txt
+OutputFile = vectors.bin
+alpha = 0.01
+Binaryf = true
+Cbow = false
+Layer1VecSize = 50
+NegSampling = 0
+SoftMax = true
+VocabHashSize = 1000
+WindowSkipLen = 8
+`
+
+func TestLoadModelConfig(t *testing.T) {
+	params, err := LoadModelConfig(strings.NewReader(testModelConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewWord2VecModel("", "", params...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.TrainFile != "testdata/train_small.txt" || m.OutputFile != "vectors.bin" {
+		t.Errorf("config should set the train and output files, got %s and %s", m.TrainFile, m.OutputFile)
+	}
+	// Cbow = false comes after alpha in the file but must not reset it
+	if m.Architecture != ArchSkipGram || m.Alpha != 0.01 {
+		t.Errorf("expected skip-gram with alpha 0.01, got %v and alpha %v", m.Architecture, m.Alpha)
+	}
+	if !m.Binaryf || !m.SoftMax || m.NegSampling != 0 {
+		t.Errorf("expected binary output and softmax only, got %v %v %d", m.Binaryf, m.SoftMax, m.NegSampling)
+	}
+	if m.Layer1VecSize != 50 || m.WindowSkipLen != 8 || m.VocabHashSize != 1000 {
+		t.Errorf("expected sizes 50/8/1000, got %d/%d/%d", m.Layer1VecSize, m.WindowSkipLen, m.VocabHashSize)
+	}
+	if m.Iter != ITER || m.MinCount != MIN_COUNT {
+		t.Errorf("fields missing from the config should keep their defaults, got iter %d and min count %d", m.Iter, m.MinCount)
+	}
+}
+
+func TestModelConfigRoundTrip(t *testing.T) {
+	m, err := NewWord2VecModel("train.txt", "out.bin", BinaryFileTrue, BagOfWordsFalse, AlphaOption(0.0123), SampleOption(1e-5), IterOption(7), VocabOutFileOption("vocab.txt"), TrainingReportTrue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := LoadModelConfig(strings.NewReader(m.Config()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := NewWord2VecModel("", "", params...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Config() != m.Config() {
+		t.Errorf("replayed config should match, got\n%s\nwant\n%s", replayed.Config(), m.Config())
+	}
+}
+
+var badconfigtests = []struct {
+	config string
+	err    string
+}{
+	{"Alpha 0.1", "line 1: expected 'Field = value'"},
+	{"\nLearningRate = 0.1", "line 2: unknown field"},
+	{"Iter = many", "line 1: bad value for Iter"},
+	{"SoftMax = yes", "line 1: bad value for SoftMax"},
+	{"Iter = 1\niter = 2", "line 2: field Iter is set more than once"},
+}
+
+func TestLoadModelConfigErrors(t *testing.T) {
+	for _, bt := range badconfigtests {
+		_, err := LoadModelConfig(strings.NewReader(bt.config))
+		if err == nil || !strings.Contains(err.Error(), bt.err) {
+			t.Errorf("LoadModelConfig(%q) error = %v, want %q", bt.config, err, bt.err)
+		}
+	}
+}
+
+func TestModelConfigParams(t *testing.T) {
+	params, err := ModelConfigParams(map[string]string{"layer1vecsize": "50", "Architecture": "skip-gram", "Iter": "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewWord2VecModel("", "", append([]ModelParams{VocabHashSizeOption(1000)}, params...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Layer1VecSize != 50 || v.Architecture != ArchSkipGram || v.Iter != 3 {
+		t.Errorf("config values not applied: %d %v %d", v.Layer1VecSize, v.Architecture, v.Iter)
+	}
+	for _, values := range []map[string]string{
+		{"Layers": "2"},
+		{"Iter": "three"},
+		{"Iter": "3", "iter": "4"},
+	} {
+		if _, err := ModelConfigParams(values); err == nil {
+			t.Errorf("ModelConfigParams(%v) should be an error", values)
+		}
+	}
+}
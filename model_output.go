@@ -0,0 +1,145 @@
+package wordvec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// SaveOutput writes the trained model to OutputFile: word vectors, in text or binary (see Binaryf), or k-means word classes when KmeansClasses > 0. The vectors of a ParagraphVectors model's documents go to DocVectorsFile.
+func (v *VectorModel) SaveOutput() error {
+	fmt.Fprintf(os.Stdout, "Save output to file: %s\n", v.OutputFile)
+	f, err := os.Create(v.OutputFile)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(f)
+	if v.KmeansClasses == 0 {
+		err = v.WriteVectors(writer)
+	} else {
+		err = v.WriteClasses(writer)
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && v.ParagraphVectors && len(v.DocTags) > 0 {
+		err = v.saveDocVectors()
+	}
+	return err
+}
+
+func (v *VectorModel) saveDocVectors() error {
+	fmt.Fprintf(os.Stdout, "Save document vectors to file: %s\n", v.DocVectorsFile())
+	f, err := os.Create(v.DocVectorsFile())
+	if err != nil {
+		return err
+	}
+	if err = v.WriteDocVectors(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteVectors writes the word vectors in the original word2vec format: a "<vocab size> <vector size>" header line followed by one word per line with its vector either as text or, if Binaryf is set, as little endian float32 values. A subword model writes the input vectors including the n-grams (see WordVector).
+func (v *VectorModel) WriteVectors(w io.Writer) error {
+	var buf [4]byte
+	vectors := v.wordVectors()
+	if _, err := fmt.Fprintf(w, "%d %d\n", v.VocabSize, v.Layer1VecSize); err != nil {
+		return err
+	}
+	for a := 0; a < v.VocabSize; a++ {
+		if _, err := fmt.Fprintf(w, "%s ", v.Vocab[a].Word); err != nil {
+			return err
+		}
+		for b := 0; b < v.Layer1VecSize; b++ {
+			var err error
+			if v.Binaryf {
+				binary.LittleEndian.PutUint32(buf[:], math.Float32bits(float32(vectors[a*v.Layer1VecSize+b])))
+				_, err = w.Write(buf[:])
+			} else {
+				_, err = fmt.Fprintf(w, "%f ", vectors[a*v.Layer1VecSize+b])
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteClasses runs K-means on the word vectors and writes one "<word> <class id>" line per word.
+func (v *VectorModel) WriteClasses(w io.Writer) error {
+	for a, class := range v.KmeansWordClasses(10) {
+		if _, err := fmt.Fprintf(w, "%s %d\n", v.Vocab[a].Word, class); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// KmeansWordClasses clusters the word vectors into KmeansClasses classes with iter rounds of K-means and returns the class of every word in the vocabulary. Centroids are normalized to unit length after every round as in the original word2vec.
+func (v *VectorModel) KmeansWordClasses(iter int) []int {
+	clcn := v.KmeansClasses
+	size := v.Layer1VecSize
+	syn0 := v.wordVectors()
+	centcn := make([]int, clcn)
+	cl := make([]int, v.VocabSize)
+	cent := make([]float64, clcn*size)
+	for a := 0; a < v.VocabSize; a++ {
+		cl[a] = a % clcn
+	}
+	for a := 0; a < iter; a++ {
+		for b := range cent {
+			cent[b] = 0
+		}
+		for b := range centcn {
+			centcn[b] = 1
+		}
+		for c := 0; c < v.VocabSize; c++ {
+			for d := 0; d < size; d++ {
+				cent[size*cl[c]+d] += syn0[c*size+d]
+			}
+			centcn[cl[c]]++
+		}
+		for b := 0; b < clcn; b++ {
+			closev := 0.0
+			for c := 0; c < size; c++ {
+				cent[size*b+c] /= float64(centcn[b])
+				closev += cent[size*b+c] * cent[size*b+c]
+			}
+			closev = math.Sqrt(closev)
+			if closev == 0 {
+				continue
+			}
+			for c := 0; c < size; c++ {
+				cent[size*b+c] /= closev
+			}
+		}
+		for c := 0; c < v.VocabSize; c++ {
+			closev := -10.0
+			closeid := 0
+			for d := 0; d < clcn; d++ {
+				x := 0.0
+				for b := 0; b < size; b++ {
+					x += cent[size*d+b] * syn0[c*size+b]
+				}
+				if x > closev {
+					closev = x
+					closeid = d
+				}
+			}
+			cl[c] = closeid
+		}
+	}
+	return cl
+}
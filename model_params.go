@@ -10,10 +10,11 @@ import (
 type ModelParams func(*VectorModel) error
 
 // Alpha Sets the starting learning rate; default is 0.025 for skip-gram,  and 0.05 for CBOW.
-// Note, if you wanna change the learning rate for skip-gram you should do it AFTER cbow option has been set.
+// The option can be given before or after BagOfWordsFalse, the default learning rate is only used when no alpha was set.
 func AlphaOption(alphaOption float64) func(v *VectorModel) error {
 	return func(v *VectorModel) error {
 		v.Alpha = alphaOption
+		v.alphaSet = true
 		return nil
 	}
 }
@@ -29,12 +30,42 @@ func BinaryFileTrue(v *VectorModel) error {
 	return nil
 }
 
-// BagOfWordsOption Uses the continuous bag of words model; default is true (use false for skip-gram model).
-// Note, if you wanna change the learning rate for skip-gram you should do it after this option has been set.
+// ArchitectureOption Selects the training architecture (see Architecture); default is ArchCBOW.
+// Switches the learning rate to the default of the architecture unless it was set with AlphaOption.
+func ArchitectureOption(architectureOption Architecture) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		v.Architecture = architectureOption
+		if !v.alphaSet {
+			v.Alpha = architectureOption.defaultAlpha()
+		}
+		return nil
+	}
+}
+
+// BagOfWordsOption Uses the skip-gram model instead of continuous bag of words, the same as ArchitectureOption(ArchSkipGram).
+// Switches the learning rate to the skip-gram default unless it was set with AlphaOption.
 func BagOfWordsFalse(v *VectorModel) error {
-	v.Cbow = false
-	v.Alpha = ALPHA_SKIP_GRAM
-	return nil
+	return ArchitectureOption(ArchSkipGram)(v)
+}
+
+// BucketsOption Sets the number of hashed rows for character n-gram vectors and turns subwords on; default is 0 (off). fastText uses 2000000, each row takes Layer1VecSize float64 values.
+func BucketsOption(bucketsOption int) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		if bucketsOption < 0 {
+			return fmt.Errorf("Buckets must not be negative, got %d", bucketsOption)
+		}
+		v.Buckets = bucketsOption
+		return nil
+	}
+}
+
+// CatalogFileOption Sets a file of item IDs, the first field of every line, that are kept in the vocabulary even when they occur less than MinCount times; default is "" (no catalog).
+func CatalogFileOption(catalogFileOption string) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		v.CatalogFile = catalogFileOption
+		v.catalog = nil
+		return nil
+	}
 }
 
 // DebugModeOption Sets the debug mode (default = 2 = more info during training).
@@ -69,6 +100,50 @@ func Layer1VecSizeOption(layer1VecSizeOption int) func(v *VectorModel) error {
 	}
 }
 
+// MaxCodeLenOption Sets the maximum length of the Huffman codes (and so the depth of the tree) used by hierarchical softmax; default is 40. Training returns an error when the tree of the vocabulary is deeper.
+func MaxCodeLenOption(maxCodeLenOption int) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		if maxCodeLenOption < 1 {
+			return fmt.Errorf("MaxCodeLen must be at least 1, got %d", maxCodeLenOption)
+		}
+		v.MaxCodeLen = maxCodeLenOption
+		return nil
+	}
+}
+
+// MaxNOption Sets the longest character n-gram of a subword model; default is 6.
+func MaxNOption(maxNOption int) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		if maxNOption < 1 {
+			return fmt.Errorf("MaxN must be at least 1, got %d", maxNOption)
+		}
+		v.MaxN = maxNOption
+		return nil
+	}
+}
+
+// MaxSentenceLenOption Sets the maximum number of words read as one sentence, longer lines are split; default is 1000.
+func MaxSentenceLenOption(maxSentenceLenOption int) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		if maxSentenceLenOption < 1 {
+			return fmt.Errorf("MaxSentenceLen must be at least 1, got %d", maxSentenceLenOption)
+		}
+		v.MaxSentenceLen = maxSentenceLenOption
+		return nil
+	}
+}
+
+// MaxStringLenOption Sets the maximum length in bytes of a word, longer words are truncated; default is 100 (60 for word2phrase).
+func MaxStringLenOption(maxStringLenOption int) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		if maxStringLenOption < 1 {
+			return fmt.Errorf("MaxStringLen must be at least 1, got %d", maxStringLenOption)
+		}
+		v.MaxStringLen = maxStringLenOption
+		return nil
+	}
+}
+
 // MinCount This will discard words that appear less than n times; default is 5.
 func MinCountOption(minCountOption int) func(v *VectorModel) error {
 	return func(v *VectorModel) error {
@@ -77,6 +152,28 @@ func MinCountOption(minCountOption int) func(v *VectorModel) error {
 	}
 }
 
+// MinNOption Sets the shortest character n-gram of a subword model; default is 3.
+func MinNOption(minNOption int) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		if minNOption < 1 {
+			return fmt.Errorf("MinN must be at least 1, got %d", minNOption)
+		}
+		v.MinN = minNOption
+		return nil
+	}
+}
+
+// MinReduceOption Sets the count at or below which words are dropped when the vocabulary outgrows the hash table; it goes up by one after every reduction; default is 1.
+func MinReduceOption(minReduceOption int) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		if minReduceOption < 0 {
+			return fmt.Errorf("MinReduce must not be negative, got %d", minReduceOption)
+		}
+		v.MinReduce = minReduceOption
+		return nil
+	}
+}
+
 // NegSampling Number of negative examples; default is 5, common values are 3 - 10 (0 = not used).
 func NegSamplingOption(negSamplingOption int) func(v *VectorModel) error {
 	return func(v *VectorModel) error {
@@ -85,6 +182,55 @@ func NegSamplingOption(negSamplingOption int) func(v *VectorModel) error {
 	}
 }
 
+// NextRandomOption Sets the seed of the random number generator used to initialize the network, subsample and draw negative samples; default is 1. Any value is a valid seed.
+func NextRandomOption(nextRandomOption uint64) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		v.NextRandom = nextRandomOption
+		return nil
+	}
+}
+
+// NumThreadsOption Sets the number of goroutines training in parallel; default is 12.
+func NumThreadsOption(numThreadsOption int) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		if numThreadsOption < 1 {
+			return fmt.Errorf("NumThreads must be at least 1, got %d", numThreadsOption)
+		}
+		v.NumThreads = numThreadsOption
+		return nil
+	}
+}
+
+// OutputFileOption Use <file> to save the resulting word vectors / word clusters; overrides the file given to the model constructor.
+func OutputFileOption(outputFileOption string) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		v.OutputFile = outputFileOption
+		return nil
+	}
+}
+
+// ParagraphVectorsTrue Trains a vector per document tag alongside the word vectors (see DOC_TAG_PREFIX): PV-DM with CBOW, PV-DBOW with skip-gram; default is false.
+func ParagraphVectorsTrue(v *VectorModel) error {
+	v.ParagraphVectors = true
+	return nil
+}
+
+// PhraseScorerOption Selects how word2phrase scores bigrams (see PhraseScorer); default is PhraseScoreWord2Phrase, the score of the original word2phrase.
+func PhraseScorerOption(phraseScorerOption PhraseScorer) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		v.PhraseScorer = phraseScorerOption
+		return nil
+	}
+}
+
+// ProgressOption Sets the function called with the TrainingProgress while training, see TrainModelContext; default is nil.
+func ProgressOption(progressOption func(TrainingProgress)) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		v.Progress = progressOption
+		return nil
+	}
+}
+
 // Sample Sets threshold for occurrence of words. Those that appear with higher frequency in the training data will be randomly down-sampled; default is 1e-3, useful range is (0, 1e-5).
 func SampleOption(sampleOption float64) func(v *VectorModel) error {
 	return func(v *VectorModel) error {
@@ -93,17 +239,65 @@ func SampleOption(sampleOption float64) func(v *VectorModel) error {
 	}
 }
 
+// SessionsOption Selects how the window is formed over a line (see SessionMode); default is SessionOff, SessionWhole and SessionShuffled treat every line as a set or session of items.
+func SessionsOption(sessionsOption SessionMode) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		v.Sessions = sessionsOption
+		return nil
+	}
+}
+
 // SoftMax Uses Hierarchical Softmax; default is false (not used).
 func SoftMaxOptionTrue(v *VectorModel) error {
 	v.SoftMax = true
 	return nil
 }
 
-// VocabHashSizeOption Sets the size of a vocabulary hash and the hash table size
+// StartingAlphaOption Sets the learning rate the linear decay during training starts from; default is 0, which starts from Alpha.
+func StartingAlphaOption(startingAlphaOption float64) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		if startingAlphaOption < 0 {
+			return fmt.Errorf("StartingAlpha must not be negative, got %g", startingAlphaOption)
+		}
+		v.StartingAlpha = startingAlphaOption
+		return nil
+	}
+}
+
+// ThresholdOption Sets the word2phrase threshold for forming phrases, higher values mean fewer phrases; default is 100.
+func ThresholdOption(thresholdOption float64) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		if thresholdOption < 0 {
+			return fmt.Errorf("Threshold must not be negative, got %g", thresholdOption)
+		}
+		v.Threshold = thresholdOption
+		return nil
+	}
+}
+
+// TrainFileOption Use text data from <file> to train the model; overrides the file given to the model constructor.
+func TrainFileOption(trainFileOption string) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		v.TrainFile = trainFileOption
+		return nil
+	}
+}
+
+// VocabHashSizeOption Sets the size of a vocabulary hash and the hash table size; the hash is allocated by the constructor after validation.
 func VocabHashSizeOption(vocabHashSizeOption int) func(v *VectorModel) error {
 	return func(v *VectorModel) error {
 		v.VocabHashSize = vocabHashSizeOption
-		v.VocabHash = make([]int, vocabHashSizeOption)
+		return nil
+	}
+}
+
+// VocabMaxSizeOption Sets the initial capacity of the vocabulary, it grows by 1000 words whenever it fills up; default is 1000.
+func VocabMaxSizeOption(vocabMaxSizeOption int) func(v *VectorModel) error {
+	return func(v *VectorModel) error {
+		if vocabMaxSizeOption < 2 {
+			return fmt.Errorf("VocabMaxSize must be at least 2, got %d", vocabMaxSizeOption)
+		}
+		v.VocabMaxSize = vocabMaxSizeOption
 		return nil
 	}
 }
@@ -131,3 +325,97 @@ func WindowSkipLenOption(windowSkipLenOption int) func(v *VectorModel) error {
 		return nil
 	}
 }
+
+// TrainingReportTrue Writes the TrainingReport of TrainModel as JSON next to the output file (see ReportFile); default is false.
+func TrainingReportTrue(v *VectorModel) error {
+	v.WriteReport = true
+	return nil
+}
+
+/*
+Validate checks that the model parameters can be combined into a trainable model. It returns nil or the joined errors of every offending field, so all problems of a configuration are reported at once. NewWord2VecModel and NewWord2PhraseModel run it after applying their options.
+
+A phrase model only uses a few of the fields (see NewWord2PhraseModel) and only those are checked.
+*/
+func (v *VectorModel) Validate() error {
+	var errs []error
+	if v.MinCount < 0 {
+		errs = append(errs, fmt.Errorf("MinCount must not be negative, got %d", v.MinCount))
+	}
+	if v.VocabHashSize < 1 {
+		errs = append(errs, fmt.Errorf("VocabHashSize must be greater than 0, got %d", v.VocabHashSize))
+	}
+	if v.MinReduce < 0 {
+		errs = append(errs, fmt.Errorf("MinReduce must not be negative, got %d", v.MinReduce))
+	}
+	if v.MaxStringLen < 1 {
+		errs = append(errs, fmt.Errorf("MaxStringLen must be at least 1, got %d", v.MaxStringLen))
+	}
+	if v.phrase {
+		if v.Threshold < 0 {
+			errs = append(errs, fmt.Errorf("Threshold must not be negative, got %g", v.Threshold))
+		}
+		if v.PhraseScorer < PhraseScoreWord2Phrase || v.PhraseScorer > PhraseScoreChiSquare {
+			errs = append(errs, fmt.Errorf("Unknown phrase scorer %v", v.PhraseScorer))
+		}
+		return errors.Join(errs...)
+	}
+	if v.Alpha < 0 {
+		errs = append(errs, fmt.Errorf("Alpha must not be negative, got %g", v.Alpha))
+	}
+	if v.StartingAlpha < 0 {
+		errs = append(errs, fmt.Errorf("StartingAlpha must not be negative, got %g", v.StartingAlpha))
+	}
+	if v.Layer1VecSize <= 0 {
+		errs = append(errs, fmt.Errorf("Layer1VecSize must be greater than 0, got %d", v.Layer1VecSize))
+	}
+	if v.WindowSkipLen <= 0 {
+		errs = append(errs, fmt.Errorf("WindowSkipLen must be greater than 0, got %d", v.WindowSkipLen))
+	}
+	if v.NegSampling < 0 {
+		errs = append(errs, fmt.Errorf("NegSampling must not be negative, got %d", v.NegSampling))
+	}
+	if v.NegSampling == 0 && !v.SoftMax {
+		errs = append(errs, errors.New("NegSampling is 0 and SoftMax is off, the model has no training objective"))
+	}
+	if v.Sample < 0 {
+		errs = append(errs, fmt.Errorf("Sample must not be negative, got %g", v.Sample))
+	}
+	if v.Iter < 1 {
+		errs = append(errs, fmt.Errorf("Iter must be at least 1, got %d", v.Iter))
+	}
+	if v.NumThreads < 1 {
+		errs = append(errs, fmt.Errorf("NumThreads must be at least 1, got %d", v.NumThreads))
+	}
+	if v.MaxSentenceLen < 1 {
+		errs = append(errs, fmt.Errorf("MaxSentenceLen must be at least 1, got %d", v.MaxSentenceLen))
+	}
+	if v.MaxCodeLen < 1 {
+		errs = append(errs, fmt.Errorf("MaxCodeLen must be at least 1, got %d", v.MaxCodeLen))
+	}
+	if v.KmeansClasses < 0 {
+		errs = append(errs, fmt.Errorf("KmeansClasses must not be negative, got %d", v.KmeansClasses))
+	}
+	if v.Architecture < ArchCBOW || v.Architecture > ArchCWindow {
+		errs = append(errs, fmt.Errorf("Unknown architecture %v", v.Architecture))
+	}
+	if v.ParagraphVectors && v.Architecture != ArchCBOW && v.Architecture != ArchSkipGram {
+		errs = append(errs, fmt.Errorf("ParagraphVectors needs the cbow or skip-gram architecture, got %v", v.Architecture))
+	}
+	if v.Sessions < SessionOff || v.Sessions > SessionShuffled {
+		errs = append(errs, fmt.Errorf("Unknown session mode %v", v.Sessions))
+	}
+	if v.Sessions != SessionOff && (v.Architecture.positional() || v.ParagraphVectors) {
+		errs = append(errs, fmt.Errorf("Sessions need the cbow or skip-gram architecture without paragraph vectors, got %v", v.Architecture))
+	}
+	if v.Buckets < 0 {
+		errs = append(errs, fmt.Errorf("Buckets must not be negative, got %d", v.Buckets))
+	}
+	if v.Buckets > 0 && (v.MinN < 1 || v.MaxN < v.MinN) {
+		errs = append(errs, fmt.Errorf("Subword n-gram lengths need 1 <= MinN <= MaxN, got MinN %d and MaxN %d", v.MinN, v.MaxN))
+	}
+	if v.NegSampling > 0 && v.TableSize < 1 {
+		errs = append(errs, fmt.Errorf("TableSize must be greater than 0 when using negative sampling, got %d", v.TableSize))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,451 @@
+package wordvec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+/*
+The native model format keeps everything needed to query a model or continue training it, which the original word2vec text and binary formats lose: the hyperparameters, the corpus statistics, the vocabulary with word counts and Huffman codes, and all weight matrices.
+
+Layout (all integers and floats little endian):
+
+	magic    [8]byte  "WORDVEC\x00"
+	version  uint32   NATIVE_FORMAT_VERSION
+	reserved uint32
+	sections, each:
+		tag      [4]byte
+		reserved uint32
+		length   uint64   payload length in bytes
+		payload  [length]byte, zero padded to a multiple of 8 bytes
+	checksum uint32   CRC-32 (IEEE) of every byte before it
+
+The sections are META (JSON metadata, see NativeMetadata), VOCB (the vocabulary), SYN0, SYN1 and SNEG (the weight matrices as float64), SUBW (the character n-gram vectors of a subword model as float64), DTAG and SDOC (the document tags and vectors of a ParagraphVectors model), EMBF (the word vectors as float32, including their n-grams, for MmapEmbeddings) and END, which closes the list. Every payload starts 8 byte aligned so the matrices can be used straight from a mapped file.
+
+The version only changes for incompatible layouts. Readers skip sections with tags they don't know and ignore unknown metadata fields, so newer writers can add both without breaking older readers.
+*/
+const (
+	NATIVE_FORMAT_MAGIC   string = "WORDVEC\x00"
+	NATIVE_FORMAT_VERSION uint32 = 1
+	NATIVE_FORMAT_NAME    string = "native" // the format name of native models in LoadEmbeddingsFile
+)
+
+const (
+	sectionMeta    string = "META"
+	sectionVocab   string = "VOCB"
+	sectionSyn0    string = "SYN0"
+	sectionSyn1    string = "SYN1"
+	sectionSyn1neg string = "SNEG"
+	sectionEmbed   string = "EMBF"
+	sectionSubword string = "SUBW"
+	sectionDocTags string = "DTAG"
+	sectionSynDoc  string = "SDOC"
+	sectionEnd     string = "END\x00"
+)
+
+// NativeMetadata is the JSON metadata block of the native format.
+type NativeMetadata struct {
+	Params          map[string]string `json:"params"` // every config field, see (*VectorModel).Config
+	VocabSize       int               `json:"vocab_size"`
+	TrainWords      int64             `json:"train_words"`
+	FileSize        int64             `json:"file_size"`
+	WordCountActual int64             `json:"word_count_actual"`
+	TrainingTime    time.Duration     `json:"training_time_ns"`
+	SavedAt         time.Time         `json:"saved_at"`
+}
+
+// nativeWriter writes sections while keeping the running checksum.
+type nativeWriter struct {
+	w   io.Writer
+	crc hash.Hash32
+	err error
+}
+
+func (nw *nativeWriter) write(p []byte) {
+	if nw.err != nil {
+		return
+	}
+	_, nw.err = nw.w.Write(p)
+}
+
+func (nw *nativeWriter) section(tag string, payload []byte) {
+	var header [16]byte
+	copy(header[:4], tag)
+	binary.LittleEndian.PutUint64(header[8:], uint64(len(payload)))
+	nw.write(header[:])
+	nw.write(payload)
+	if pad := len(payload) % 8; pad != 0 {
+		nw.write(make([]byte, 8-pad))
+	}
+}
+
+func float64Payload(values []float64) []byte {
+	payload := make([]byte, 8*len(values))
+	for i, f := range values {
+		binary.LittleEndian.PutUint64(payload[8*i:], math.Float64bits(f))
+	}
+	return payload
+}
+
+// stringsPayload encodes strings as a uint32 count followed by a uint32 length and the bytes of each.
+func stringsPayload(values []string) []byte {
+	var buf bytes.Buffer
+	var scratch [4]byte
+	binary.LittleEndian.PutUint32(scratch[:], uint32(len(values)))
+	buf.Write(scratch[:])
+	for _, s := range values {
+		binary.LittleEndian.PutUint32(scratch[:], uint32(len(s)))
+		buf.Write(scratch[:])
+		buf.WriteString(s)
+	}
+	return buf.Bytes()
+}
+
+func readStringsPayload(payload []byte) ([]string, error) {
+	short := errors.New("Native model string section is truncated")
+	if len(payload) < 4 {
+		return nil, short
+	}
+	n := int(binary.LittleEndian.Uint32(payload))
+	p := payload[4:]
+	if n > len(p)/4 {
+		return nil, short
+	}
+	values := make([]string, n)
+	for i := range values {
+		if len(p) < 4 {
+			return nil, short
+		}
+		size := int(binary.LittleEndian.Uint32(p))
+		if len(p)-4 < size {
+			return nil, short
+		}
+		values[i] = string(p[4 : 4+size])
+		p = p[4+size:]
+	}
+	return values, nil
+}
+
+func float32Payload(values []float64) []byte {
+	payload := make([]byte, 4*len(values))
+	for i, f := range values {
+		binary.LittleEndian.PutUint32(payload[4*i:], math.Float32bits(float32(f)))
+	}
+	return payload
+}
+
+func (v *VectorModel) vocabPayload() []byte {
+	var buf bytes.Buffer
+	var scratch [8]byte
+	for a := 0; a < v.VocabSize; a++ {
+		vw := v.Vocab[a]
+		binary.LittleEndian.PutUint32(scratch[:4], uint32(len(vw.Word)))
+		buf.Write(scratch[:4])
+		buf.WriteString(vw.Word)
+		binary.LittleEndian.PutUint64(scratch[:], uint64(vw.Count))
+		buf.Write(scratch[:])
+		buf.WriteByte(vw.Codelen)
+		buf.Write(vw.Code[:vw.Codelen])
+		for d := 0; d < int(vw.Codelen); d++ {
+			binary.LittleEndian.PutUint32(scratch[:4], uint32(vw.Point[d]))
+			buf.Write(scratch[:4])
+		}
+	}
+	return buf.Bytes()
+}
+
+// Save writes the model in the native format.
+func (v *VectorModel) Save(w io.Writer) error {
+	meta := NativeMetadata{
+		Params:          v.configValues(),
+		VocabSize:       v.VocabSize,
+		TrainWords:      v.TrainWords,
+		FileSize:        v.FileSize,
+		WordCountActual: v.WordCountActual,
+		TrainingTime:    v.TrainingTime,
+		SavedAt:         time.Now().UTC(),
+	}
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	nw := &nativeWriter{crc: crc32.NewIEEE()}
+	nw.w = io.MultiWriter(bw, nw.crc)
+	var header [16]byte
+	copy(header[:8], NATIVE_FORMAT_MAGIC)
+	binary.LittleEndian.PutUint32(header[8:], NATIVE_FORMAT_VERSION)
+	nw.write(header[:])
+	nw.section(sectionMeta, metaJSON)
+	if v.VocabSize > 0 {
+		nw.section(sectionVocab, v.vocabPayload())
+	}
+	for _, m := range []struct {
+		tag    string
+		values []float64
+	}{{sectionSyn0, v.Syn0}, {sectionSyn1, v.Syn1}, {sectionSyn1neg, v.Syn1neg}, {sectionSubword, v.SynSubword}} {
+		if len(m.values) > 0 {
+			nw.section(m.tag, float64Payload(m.values))
+		}
+	}
+	if len(v.DocTags) > 0 && len(v.SynDoc) > 0 {
+		nw.section(sectionDocTags, stringsPayload(v.DocTags))
+		nw.section(sectionSynDoc, float64Payload(v.SynDoc))
+	}
+	if len(v.Syn0) > 0 {
+		nw.section(sectionEmbed, float32Payload(v.wordVectors()))
+	}
+	nw.section(sectionEnd, nil)
+	if nw.err != nil {
+		return nw.err
+	}
+	var checksum [4]byte
+	binary.LittleEndian.PutUint32(checksum[:], nw.crc.Sum32())
+	if _, err = bw.Write(checksum[:]); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// SaveFile writes the model in the native format to the named file.
+func (v *VectorModel) SaveFile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err = v.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readNativeSections reads a native format stream, checks its header and checksum and returns the payload of every section by tag.
+func readNativeSections(r io.Reader) (map[string][]byte, error) {
+	crc := crc32.NewIEEE()
+	tr := io.TeeReader(bufio.NewReader(r), crc)
+
+	var header [16]byte
+	if _, err := io.ReadFull(tr, header[:]); err != nil {
+		return nil, fmt.Errorf("reading native model header: %v", err)
+	}
+	if string(header[:8]) != NATIVE_FORMAT_MAGIC {
+		return nil, errors.New("Not a native wordvec model file")
+	}
+	if version := binary.LittleEndian.Uint32(header[8:]); version > NATIVE_FORMAT_VERSION {
+		return nil, fmt.Errorf("Native model format version %d is newer than the supported version %d", version, NATIVE_FORMAT_VERSION)
+	}
+
+	sections := make(map[string][]byte)
+	for {
+		if _, err := io.ReadFull(tr, header[:]); err != nil {
+			return nil, fmt.Errorf("reading native model section: %v", err)
+		}
+		tag := string(header[:4])
+		length := binary.LittleEndian.Uint64(header[8:])
+		padded := length
+		if pad := length % 8; pad != 0 {
+			padded += 8 - pad
+		}
+		if padded > math.MaxInt32*8 {
+			return nil, fmt.Errorf("native model section %q is too large: %d bytes", tag, length)
+		}
+		// The buffer grows with the data actually read, a corrupt length fails at the end of the file instead of allocating it up front
+		var buf bytes.Buffer
+		if n, err := io.CopyN(&buf, tr, int64(padded)); err != nil {
+			if err == io.EOF {
+				err = fmt.Errorf("unexpected EOF after %d of %d bytes", n, padded)
+			}
+			return nil, fmt.Errorf("reading native model section %q: %v", tag, err)
+		}
+		if tag == sectionEnd {
+			break
+		}
+		sections[tag] = buf.Bytes()[:length]
+	}
+
+	sum := crc.Sum32()
+	var checksum [4]byte
+	if _, err := io.ReadFull(tr, checksum[:]); err != nil {
+		return nil, fmt.Errorf("reading native model checksum: %v", err)
+	}
+	if binary.LittleEndian.Uint32(checksum[:]) != sum {
+		return nil, errors.New("Native model checksum mismatch, the file is corrupt")
+	}
+	return sections, nil
+}
+
+func float64Section(payload []byte, want int, tag string) ([]float64, error) {
+	if len(payload) != 8*want {
+		return nil, fmt.Errorf("native model section %q has %d bytes, expected %d", tag, len(payload), 8*want)
+	}
+	values := make([]float64, want)
+	for i := range values {
+		values[i] = math.Float64frombits(binary.LittleEndian.Uint64(payload[8*i:]))
+	}
+	return values, nil
+}
+
+// readVocabPayload fills the vocabulary from a VOCB payload and rebuilds the vocab hash.
+func (v *VectorModel) readVocabPayload(payload []byte, size int) error {
+	short := errors.New("Native model vocabulary section is truncated")
+	// Every entry holds at least its 4 byte word length, 8 byte count and code length byte
+	if size < 0 || size > len(payload)/13 {
+		return short
+	}
+	v.Vocab = make(VocabSlice, size+1)
+	v.VocabMaxSize = len(v.Vocab)
+	v.VocabSize = size
+	v.resetVocabHashIndices()
+	p := payload
+	for a := 0; a < size; a++ {
+		if len(p) < 4 {
+			return short
+		}
+		n := int(binary.LittleEndian.Uint32(p))
+		if len(p) < 4+n+9 {
+			return short
+		}
+		vw := &v.Vocab[a]
+		vw.Word = string(p[4 : 4+n])
+		vw.Count = int(binary.LittleEndian.Uint64(p[4+n:]))
+		vw.Codelen = p[4+n+8]
+		p = p[4+n+9:]
+		codelen := int(vw.Codelen)
+		if codelen > v.MaxCodeLen {
+			return fmt.Errorf("Native model word %q has a Huffman code of %d bits, longer than MaxCodeLen %d", vw.Word, codelen, v.MaxCodeLen)
+		}
+		if len(p) < codelen*5 {
+			return short
+		}
+		vw.Code = make([]byte, v.MaxCodeLen)
+		vw.Point = make([]int, v.MaxCodeLen)
+		copy(vw.Code, p[:codelen])
+		p = p[codelen:]
+		for d := 0; d < codelen; d++ {
+			vw.Point[d] = int(int32(binary.LittleEndian.Uint32(p[4*d:])))
+		}
+		p = p[4*codelen:]
+		hash := v.recomputeVocabHash(v.GetWordHash(vw.Word))
+		v.VocabHash[hash] = a
+	}
+	return nil
+}
+
+// vocabWords returns the words of a VOCB payload, skipping their counts and codes.
+func vocabWords(payload []byte, size int) ([]string, error) {
+	if size < 0 || size > len(payload)/13 {
+		return nil, errors.New("Native model vocabulary section is truncated")
+	}
+	words := make([]string, size)
+	p := payload
+	for a := 0; a < size; a++ {
+		if len(p) < 4 {
+			return nil, errors.New("Native model vocabulary section is truncated")
+		}
+		n := int(binary.LittleEndian.Uint32(p))
+		if len(p) < 4+n+9 {
+			return nil, errors.New("Native model vocabulary section is truncated")
+		}
+		words[a] = string(p[4 : 4+n])
+		codelen := int(p[4+n+8])
+		p = p[4+n+9:]
+		if len(p) < codelen*5 {
+			return nil, errors.New("Native model vocabulary section is truncated")
+		}
+		p = p[codelen*5:]
+	}
+	return words, nil
+}
+
+/*
+Load reads a model written by Save. The model is rebuilt with NewWord2VecModel from the saved hyperparameters, so it can be queried, saved in another format or trained further.
+
+Unknown sections and metadata fields are skipped; a file of a newer format version, a bad checksum or sections of the wrong size are errors.
+*/
+func Load(r io.Reader) (*VectorModel, error) {
+	sections, err := readNativeSections(r)
+	if err != nil {
+		return nil, err
+	}
+	var meta NativeMetadata
+	metaJSON, ok := sections[sectionMeta]
+	if !ok {
+		return nil, errors.New("Native model has no metadata section")
+	}
+	if err = json.Unmarshal(metaJSON, &meta); err != nil {
+		return nil, fmt.Errorf("reading native model metadata: %v", err)
+	}
+	params, err := configParams(meta.Params)
+	if err != nil {
+		return nil, fmt.Errorf("reading native model metadata: %v", err)
+	}
+	v, err := NewWord2VecModel("", "", params...)
+	if err != nil {
+		return nil, err
+	}
+	v.TrainWords = meta.TrainWords
+	v.FileSize = meta.FileSize
+	v.WordCountActual = meta.WordCountActual
+	v.TrainingTime = meta.TrainingTime
+
+	if meta.VocabSize > 0 {
+		if float64(meta.VocabSize) > float64(v.VocabHashSize)*0.7 {
+			return nil, fmt.Errorf("Native model vocabulary of %d words does not fit a hash of size %d", meta.VocabSize, v.VocabHashSize)
+		}
+		if err = v.readVocabPayload(sections[sectionVocab], meta.VocabSize); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range []struct {
+		tag    string
+		values *[]float64
+		size   int
+	}{{sectionSyn0, &v.Syn0, v.Layer1VecSize}, {sectionSyn1, &v.Syn1, v.outputSize()}, {sectionSyn1neg, &v.Syn1neg, v.outputSize()}} {
+		payload, ok := sections[m.tag]
+		if !ok {
+			continue
+		}
+		if *m.values, err = float64Section(payload, meta.VocabSize*m.size, m.tag); err != nil {
+			return nil, err
+		}
+	}
+	if payload, ok := sections[sectionSubword]; ok {
+		if v.SynSubword, err = float64Section(payload, v.Buckets*v.Layer1VecSize, sectionSubword); err != nil {
+			return nil, err
+		}
+		v.cacheSubwords()
+	}
+	if payload, ok := sections[sectionDocTags]; ok {
+		tags, err := readStringsPayload(payload)
+		if err != nil {
+			return nil, err
+		}
+		if v.SynDoc, err = float64Section(sections[sectionSynDoc], len(tags)*v.Layer1VecSize, sectionSynDoc); err != nil {
+			return nil, err
+		}
+		v.setDocTags(tags)
+	}
+	return v, nil
+}
+
+// LoadFile reads a model written by SaveFile.
+func LoadFile(name string) (*VectorModel, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
@@ -0,0 +1,151 @@
+package wordvec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func trainSmallModel(t *testing.T, modelParams ...ModelParams) *VectorModel {
+	mv := newSmallTrainingModel(t, filepath.Join(t.TempDir(), "vectors.txt"), modelParams...)
+	if _, err := mv.TrainModel(); err != nil {
+		t.Fatal(err)
+	}
+	return mv
+}
+
+func TestNativeFormatRoundTrip(t *testing.T) {
+	mv := trainSmallModel(t, SoftMaxOptionTrue, IterOption(1))
+	name := filepath.Join(t.TempDir(), "model.wv")
+	if err := mv.SaveFile(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Config() != mv.Config() {
+		t.Errorf("loaded params should match, got\n%s\nwant\n%s", loaded.Config(), mv.Config())
+	}
+	if loaded.VocabSize != mv.VocabSize || loaded.TrainWords != mv.TrainWords || loaded.TrainingTime != mv.TrainingTime {
+		t.Errorf("loaded corpus stats should match, got %d/%d/%v", loaded.VocabSize, loaded.TrainWords, loaded.TrainingTime)
+	}
+	for a := 0; a < mv.VocabSize; a++ {
+		want, got := mv.Vocab[a], loaded.Vocab[a]
+		if got.Word != want.Word || got.Count != want.Count || got.Codelen != want.Codelen {
+			t.Fatalf("vocab word %d should be %+v, got %+v", a, want, got)
+		}
+		for d := 0; d < int(want.Codelen); d++ {
+			if got.Code[d] != want.Code[d] || got.Point[d] != want.Point[d] {
+				t.Fatalf("Huffman code of %s differs at %d", want.Word, d)
+			}
+		}
+		if loaded.SearchVocab(want.Word) != a {
+			t.Errorf("loaded vocab hash should find %s at %d", want.Word, a)
+		}
+	}
+	for _, m := range []struct {
+		name      string
+		got, want []float64
+	}{{"Syn0", loaded.Syn0, mv.Syn0}, {"Syn1", loaded.Syn1, mv.Syn1}, {"Syn1neg", loaded.Syn1neg, mv.Syn1neg}} {
+		if len(m.got) != len(m.want) {
+			t.Fatalf("%s should have %d values, got %d", m.name, len(m.want), len(m.got))
+		}
+		for i := range m.want {
+			if m.got[i] != m.want[i] {
+				t.Fatalf("%s differs at %d", m.name, i)
+			}
+		}
+	}
+}
+
+func TestNativeFormatChecksum(t *testing.T) {
+	mv := trainSmallModel(t, IterOption(1))
+	var buf bytes.Buffer
+	if err := mv.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	data[len(data)/2] ^= 0xFF
+	if _, err := Load(bytes.NewReader(data)); err == nil {
+		t.Error("loading a corrupted model should fail")
+	}
+	if _, err := Load(strings.NewReader("not a model at all")); err == nil {
+		t.Error("loading a file without the native header should fail")
+	}
+}
+
+func TestNativeFormatCorruptLengths(t *testing.T) {
+	// A section claiming 8 GiB in a file of a few bytes must fail at the end of the file, not allocate the claimed length
+	var data bytes.Buffer
+	data.WriteString(NATIVE_FORMAT_MAGIC)
+	binary.Write(&data, binary.LittleEndian, uint32(NATIVE_FORMAT_VERSION))
+	var header [16]byte
+	copy(header[:4], sectionMeta)
+	binary.LittleEndian.PutUint64(header[8:], 1<<33)
+	data.Write(header[:])
+	data.WriteString("{}")
+	if _, err := Load(&data); err == nil || !strings.Contains(err.Error(), "reading native model section") {
+		t.Error("a truncated section should fail to read, got", err)
+	}
+
+	mv := trainSmallModel(t, SoftMaxOptionTrue, IterOption(1))
+	meta := map[string]interface{}{"params": mv.configValues(), "vocab_size": mv.VocabSize}
+	meta["params"].(map[string]string)["MaxCodeLen"] = "1"
+	metaJSON, _ := json.Marshal(meta)
+	short := writeNativeSections(NATIVE_FORMAT_VERSION, []string{sectionMeta, sectionVocab}, [][]byte{metaJSON, mv.vocabPayload()})
+	if _, err := Load(bytes.NewReader(short)); err == nil || !strings.Contains(err.Error(), "MaxCodeLen") {
+		t.Error("codes longer than MaxCodeLen should be an error naming MaxCodeLen, got", err)
+	}
+}
+
+// writeNativeSections writes a native format stream the way a future version of Save might.
+func writeNativeSections(version uint32, sections []string, payloads [][]byte) []byte {
+	var buf bytes.Buffer
+	nw := &nativeWriter{crc: crc32.NewIEEE()}
+	nw.w = &buf
+	var header [16]byte
+	copy(header[:8], NATIVE_FORMAT_MAGIC)
+	binary.LittleEndian.PutUint32(header[8:], version)
+	nw.write(header[:])
+	for i, tag := range sections {
+		nw.section(tag, payloads[i])
+	}
+	nw.section(sectionEnd, nil)
+	var checksum [4]byte
+	binary.LittleEndian.PutUint32(checksum[:], crc32.ChecksumIEEE(buf.Bytes()))
+	buf.Write(checksum[:])
+	return buf.Bytes()
+}
+
+func TestNativeFormatForwardCompatible(t *testing.T) {
+	mv := trainSmallModel(t, IterOption(1))
+	meta := map[string]interface{}{
+		"params":          mv.configValues(),
+		"vocab_size":      mv.VocabSize,
+		"train_words":     mv.TrainWords,
+		"some_new_metric": 0.5,
+	}
+	meta["params"].(map[string]string)["SomeNewParam"] = "7"
+	metaJSON, _ := json.Marshal(meta)
+	data := writeNativeSections(NATIVE_FORMAT_VERSION,
+		[]string{sectionMeta, "XTRA", sectionVocab, sectionSyn0},
+		[][]byte{metaJSON, []byte("a section added by a newer writer"), mv.vocabPayload(), float64Payload(mv.Syn0)},
+	)
+	loaded, err := Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal("unknown sections and fields should be skipped, got", err)
+	}
+	if loaded.VocabSize != mv.VocabSize || len(loaded.Syn0) != len(mv.Syn0) || len(loaded.Syn1neg) != 0 {
+		t.Errorf("expected the vocab and Syn0 only, got %d words, %d/%d weights", loaded.VocabSize, len(loaded.Syn0), len(loaded.Syn1neg))
+	}
+
+	newer := writeNativeSections(NATIVE_FORMAT_VERSION+1, []string{sectionMeta}, [][]byte{metaJSON})
+	if _, err := Load(bytes.NewReader(newer)); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Error("a newer format version should be rejected, got", err)
+	}
+}
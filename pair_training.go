@@ -0,0 +1,393 @@
+package wordvec
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+PairModel trains word vectors from arbitrary (word, context) pairs instead of window neighbours, like word2vecf (Levy and Goldberg, https://aclanthology.org/P14-2050): the contexts can be labelled syntactic relations, e.g. "scientist nsubj-1_discovers", or anything else that describes a word. The training file holds one "word context" pair per line:
+
+	australian amod_scientist
+	scientist amod-1_australian
+	scientist nsubj-1_discovers
+
+Words and contexts get their own vocabulary, each pruned by its own minimum count, and skip-gram with negative sampling predicts the context of every pair from its word with the sampler and updates of the window based trainer. Negative contexts are drawn from the unigram table of the context vocabulary.
+
+Words holds the training options, the word vocabulary and the word vectors (Syn0, with n-grams when Buckets > 0); Contexts holds the context vocabulary and the context vectors (its Syn1neg). Word vectors are written to Words.OutputFile and context vectors to Contexts.OutputFile.
+*/
+type PairModel struct {
+	Words    *VectorModel
+	Contexts *VectorModel
+}
+
+/*
+NewPairModel creates a PairModel for training on the pairs in trainFile. The word vectors are written to outFile and the context vectors to outFile with a ".contexts" suffix. Words occurring less than MinCount times are discarded like in NewWord2VecModel, contexts occurring less than contextMinCount times are discarded too; a pair is skipped when either is discarded.
+
+The model params are those of NewWord2VecModel. Pair training is skip-gram with negative sampling only, so NegSampling must be positive while SoftMax and ParagraphVectors must be off and the Architecture must not be positional; Alpha defaults to the skip-gram learning rate and WindowSkipLen is not used.
+*/
+func NewPairModel(trainFile, outFile string, contextMinCount int, modelParams ...ModelParams) (*PairModel, error) {
+	words, err := NewWord2VecModel(trainFile, outFile, modelParams...)
+	if err != nil {
+		return &PairModel{}, err
+	}
+	if !words.alphaSet {
+		words.Alpha = ALPHA_SKIP_GRAM
+	}
+	var errs []error
+	if contextMinCount < 0 {
+		errs = append(errs, fmt.Errorf("Context MinCount must not be negative, got %d", contextMinCount))
+	}
+	if words.NegSampling == 0 {
+		errs = append(errs, errors.New("Pair training needs NegSampling greater than 0"))
+	}
+	if words.SoftMax {
+		errs = append(errs, errors.New("Pair training does not support SoftMax"))
+	}
+	if words.ParagraphVectors {
+		errs = append(errs, errors.New("Pair training does not support ParagraphVectors"))
+	}
+	if words.Architecture.positional() {
+		errs = append(errs, fmt.Errorf("Pair training does not support the positional architecture %v", words.Architecture))
+	}
+	if err = errors.Join(errs...); err != nil {
+		return &PairModel{}, err
+	}
+
+	contexts := &VectorModel{
+		DebugMode:     words.DebugMode,
+		ExpTable:      words.ExpTable,
+		Layer1VecSize: words.Layer1VecSize,
+		Binaryf:       words.Binaryf,
+		MaxCodeLen:    words.MaxCodeLen,
+		MaxStringLen:  words.MaxStringLen,
+		MinCount:      contextMinCount,
+		MinReduce:     words.MinReduce,
+		NegSampling:   words.NegSampling,
+		NextRandom:    words.NextRandom,
+		OutputFile:    outFile + ".contexts",
+		TableSize:     words.TableSize,
+		TrainFile:     trainFile,
+		VocabHashSize: words.VocabHashSize,
+		VocabMaxSize:  words.VocabMaxSize,
+	}
+	contexts.allocateVocab()
+	return &PairModel{Words: words, Contexts: contexts}, nil
+}
+
+// readPair reads the next non-empty line of a pairs file and splits it into word and context. ok is false for a line that is not a pair.
+func readPair(r *bufio.Reader) (word, context string, ok bool, err error) {
+	for {
+		line, rerr := r.ReadString('\n')
+		if rerr != nil && (rerr != io.EOF || line == "") {
+			return "", "", false, rerr
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			if rerr == io.EOF {
+				return "", "", false, io.EOF
+			}
+			continue
+		}
+		if len(fields) != 2 {
+			return "", "", false, nil
+		}
+		return fields[0], fields[1], true, nil
+	}
+}
+
+// countToken adds one occurrence of token to the vocabulary of v, reducing the vocabulary when it outgrows the hash table.
+func (v *VectorModel) countToken(token string) {
+	i := v.SearchVocab(token)
+	if i == -1 {
+		a := v.addWordToVocab(token)
+		v.Vocab[a].Count = 1
+	} else {
+		v.Vocab[i].Count++
+	}
+	if float64(v.VocabSize) > (float64(v.VocabHashSize) * 0.7) {
+		v.reduceVocab()
+	}
+}
+
+/*
+LearnVocab builds the word and the context vocabulary from the pairs in the training file. Like LearnVocabFromTrainFile both start with "</s>", which never occurs in a pair, and are sorted by count after the rare entries are discarded. Words.TrainWords becomes the number of pairs with a kept word. A line that is not a "word context" pair is an error.
+*/
+func (p *PairModel) LearnVocab() error {
+	w, c := p.Words, p.Contexts
+	fmt.Fprintf(os.Stdout, "Learning word and context vocabularies from pairs file: %s\n", w.TrainFile)
+	f, err := os.Open(w.TrainFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fin := bufio.NewReader(f)
+
+	for _, v := range []*VectorModel{w, c} {
+		v.resetVocabHashIndices()
+		v.VocabSize = 0
+		v.TrainWords = 0
+		v.addWordToVocab("</s>")
+	}
+	var pairs int64
+	for {
+		word, context, ok, rerr := readPair(fin)
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return rerr
+		}
+		pairs++
+		if !ok {
+			return fmt.Errorf("Pair %d of %s is not a \"word context\" line", pairs, w.TrainFile)
+		}
+		if (w.DebugMode > 1) && (pairs%100000 == 0) {
+			fmt.Fprintf(os.Stdout, "%dK%c", pairs/1000, 13)
+		}
+		w.countToken(word)
+		c.countToken(context)
+	}
+	w.sortVocab()
+	c.sortVocab()
+	if w.DebugMode > 0 {
+		fmt.Fprintf(os.Stdout, "Pairs in training file: %d\n", pairs)
+		fmt.Fprintf(os.Stdout, "Word vocab size: %d\n", w.VocabSize)
+		fmt.Fprintf(os.Stdout, "Context vocab size: %d\n", c.VocabSize)
+	}
+	fileStat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	w.FileSize = fileStat.Size()
+	c.FileSize = w.FileSize
+	return nil
+}
+
+// InitNet seeds the word vectors like VectorModel.InitNet, zeroes the context vectors and builds the unigram table of the contexts, of Words.TableSize entries, that negative samples are drawn from.
+func (p *PairModel) InitNet() {
+	fmt.Fprintf(os.Stdout, "Init Net\n")
+	p.Words.initInputVectors()
+	p.Contexts.TableSize = p.Words.TableSize
+	p.Contexts.Syn1neg = make([]float64, p.Contexts.VocabSize*p.Contexts.Layer1VecSize)
+	p.Contexts.InitUnigramTable()
+}
+
+// trainPairsThread runs one pass of training over the id-th of NumThreads chunks of the pairs file, like trainModelThread.
+func (p *PairModel) trainPairsThread(id int, nextRandom *uint64) (stats threadStats, err error) {
+	w, c := p.Words, p.Contexts
+	var pairCount, lastPairCount int64
+	neu1e := make([]float64, w.Layer1VecSize)
+	hidden := make([]float64, w.Layer1VecSize) // input of a subword model
+	alpha := w.currentAlpha(atomic.LoadInt64(&w.WordCountActual))
+	layer1Size := w.Layer1VecSize
+
+	fi, ferr := os.Open(w.TrainFile)
+	if ferr != nil {
+		return stats, ferr
+	}
+	defer fi.Close()
+	if _, serr := fi.Seek(w.FileSize/int64(w.NumThreads)*int64(id), SEEK_SET); serr != nil {
+		return stats, serr
+	}
+	fin := bufio.NewReader(fi)
+	if id > 0 {
+		// skip the pair the chunk starts in the middle of, the previous thread trains it
+		if _, rerr := fin.ReadString('\n'); rerr != nil && rerr != io.EOF {
+			return stats, rerr
+		}
+	}
+
+	for {
+		if pairCount-lastPairCount > 10000 {
+			wordCountActual := atomic.AddInt64(&w.WordCountActual, pairCount-lastPairCount)
+			stats.Words += pairCount - lastPairCount
+			lastPairCount = pairCount
+			if w.DebugMode > 1 {
+				elapsed := time.Since(w.Start).Seconds()
+				fmt.Fprintf(os.Stdout, "%cAlpha: %f  Progress: %.2f%%  Pairs/thread/sec: %.2fk  ", 13, alpha,
+					float64(wordCountActual)/float64(int64(w.Iter)*w.TrainWords+1)*100,
+					float64(wordCountActual)/(elapsed*float64(w.NumThreads)+1)/1000)
+			}
+			alpha = w.currentAlpha(wordCountActual)
+		}
+		word, context, ok, rerr := readPair(fin)
+		if rerr != nil && rerr != io.EOF {
+			return stats, rerr
+		}
+		if rerr == io.EOF || pairCount > w.TrainWords/int64(w.NumThreads) {
+			atomic.AddInt64(&w.WordCountActual, pairCount-lastPairCount)
+			stats.Words += pairCount - lastPairCount
+			return stats, nil
+		}
+		if !ok {
+			continue
+		}
+		a := w.SearchVocab(word)
+		if a <= 0 {
+			continue
+		}
+		pairCount++
+		ctx := c.SearchVocab(context)
+		if ctx <= 0 {
+			continue
+		}
+		// The subsampling randomly discards frequent words while keeping the ranking same
+		if w.Sample > 0 {
+			cn := float64(w.Vocab[a].Count)
+			ran := (math.Sqrt(cn/(w.Sample*float64(w.TrainWords))) + 1) * (w.Sample * float64(w.TrainWords)) / cn
+			*nextRandom = *nextRandom*25214903917 + 11
+			if ran < float64(*nextRandom&0xFFFF)/65536 {
+				continue
+			}
+		}
+		in := w.Syn0[a*layer1Size : (a+1)*layer1Size]
+		if len(w.SynSubword) > 0 {
+			for d := range hidden {
+				hidden[d] = 0
+			}
+			w.addInput(a, hidden)
+			in = hidden
+		}
+		for d := range neu1e {
+			neu1e[d] = 0
+		}
+		c.trainTarget(in, ctx, 0, neu1e, alpha, true, nextRandom, &stats)
+		w.updateInput(a, neu1e)
+	}
+}
+
+/*
+Train learns both vocabularies, trains for Iter epochs across NumThreads goroutines and writes the word and the context vectors, see SaveOutput. The returned TrainingReport counts pairs as words; it is written next to the word vectors when WriteReport is set.
+*/
+func (p *PairModel) Train() (*TrainingReport, error) {
+	w := p.Words
+	if w.TrainFile == "" {
+		return nil, errors.New("No training file specified")
+	}
+	if w.OutputFile == "" || p.Contexts.OutputFile == "" {
+		return nil, errors.New("No output file specified")
+	}
+	fmt.Fprintf(os.Stdout, "Starting pair training using file %s\n", w.TrainFile)
+	if w.StartingAlpha == 0 {
+		w.StartingAlpha = w.Alpha
+	}
+	if err := p.LearnVocab(); err != nil {
+		return nil, err
+	}
+	if w.VocabSize < 2 || p.Contexts.VocabSize < 2 {
+		return nil, fmt.Errorf("Vocabularies of %d words and %d contexts are too small to train; lower the minimum counts or use a bigger training file", w.VocabSize, p.Contexts.VocabSize)
+	}
+	p.InitNet()
+
+	report := &TrainingReport{
+		StartingAlpha: w.StartingAlpha,
+		TrainWords:    w.TrainWords,
+		VocabSize:     w.VocabSize,
+	}
+	w.Start = time.Now()
+	w.WordCountActual = 0
+	nextRandoms := make([]uint64, w.NumThreads)
+	for id := range nextRandoms {
+		nextRandoms[id] = w.NextRandom + uint64(id)
+	}
+
+	for epoch := 0; epoch < w.Iter; epoch++ {
+		epochStart := time.Now()
+		stats := make([]threadStats, w.NumThreads)
+		errs := make([]error, w.NumThreads)
+		var wg sync.WaitGroup
+		for id := 0; id < w.NumThreads; id++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				defer recoverThread(&errs[id])
+				stats[id], errs[id] = p.trainPairsThread(id, &nextRandoms[id])
+			}(id)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			if err != nil {
+				return nil, err
+			}
+		}
+		report.addEpoch(epoch+1, stats, w.currentAlpha(w.WordCountActual), time.Since(epochStart))
+	}
+	report.finish(time.Since(w.Start))
+	w.TrainingTime = report.Elapsed
+	if w.DebugMode > 1 {
+		fmt.Fprintf(os.Stdout, "\n")
+	}
+	if w.DebugMode > 0 {
+		fmt.Fprintf(os.Stdout, "Trained %d pairs in %v, final loss %f\n", report.WordsProcessed, report.Elapsed, report.FinalLoss())
+	}
+
+	if err := p.SaveOutput(); err != nil {
+		return report, err
+	}
+	if w.WriteReport {
+		if err := report.WriteJSONFile(w.ReportFile()); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// SaveOutput writes the word vectors (or classes) to Words.OutputFile like VectorModel.SaveOutput and the context vectors to Contexts.OutputFile, in the same format.
+func (p *PairModel) SaveOutput() error {
+	if err := p.Words.SaveOutput(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "Save context vectors to file: %s\n", p.Contexts.OutputFile)
+	f, err := os.Create(p.Contexts.OutputFile)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(f)
+	err = p.WriteContextVectors(writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// WriteContextVectors writes the context vectors in the format of WriteVectors, text or binary as set by Binaryf.
+func (p *PairModel) WriteContextVectors(w io.Writer) error {
+	contexts, err := p.ContextEmbeddings()
+	if err != nil {
+		return err
+	}
+	format := FormatText
+	if p.Words.Binaryf {
+		format = FormatBinary
+	}
+	return WriteEmbeddings(w, contexts, format)
+}
+
+// ContextEmbeddings copies the trained context vectors into an Embeddings, e.g. to find the contexts that best describe a word vector.
+func (p *PairModel) ContextEmbeddings() (*Embeddings, error) {
+	c := p.Contexts
+	if c.VocabSize == 0 || len(c.Syn1neg) < c.VocabSize*c.Layer1VecSize {
+		return nil, errors.New("Model has no trained context vectors")
+	}
+	contexts := make([]string, c.VocabSize)
+	for a := range contexts {
+		contexts[a] = c.Vocab[a].Word
+	}
+	vectors := make([]float32, c.VocabSize*c.Layer1VecSize)
+	for i := range vectors {
+		vectors[i] = float32(c.Syn1neg[i])
+	}
+	return NewEmbeddings(contexts, c.Layer1VecSize, vectors)
+}
@@ -0,0 +1,137 @@
+package wordvec
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testFileForPairs string = "testdata/pairs_small.txt"
+
+func newSmallPairModel(t *testing.T, outFile string, contextMinCount int, modelParams ...ModelParams) *PairModel {
+	params := append([]ModelParams{
+		VocabHashSizeOption(5000),
+		MinCountOption(2),
+		Layer1VecSizeOption(20),
+		DebugModeOption(0),
+		IterOption(20),
+		NumThreadsOption(2),
+		SampleOption(0),
+	}, modelParams...)
+	p, err := NewPairModel(testFileForPairs, outFile, contextMinCount, params...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Words.TableSize = 1e5
+	return p
+}
+
+func readTextEmbeddings(t *testing.T, name string) *Embeddings {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	e, err := ReadEmbeddings(f, FormatText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestNewPairModelErrors(t *testing.T) {
+	for _, params := range [][]ModelParams{
+		{NegSamplingOption(0), SoftMaxOptionTrue},
+		{ParagraphVectorsTrue},
+		{ArchitectureOption(ArchStructuredSkipGram)},
+		{ArchitectureOption(ArchCWindow)},
+	} {
+		if _, err := NewPairModel(testFileForPairs, "out.txt", 1, append(params, VocabHashSizeOption(10))...); err == nil {
+			t.Error("expected an error for an unsupported objective")
+		}
+	}
+	if _, err := NewPairModel(testFileForPairs, "out.txt", -1, VocabHashSizeOption(10)); err == nil {
+		t.Error("expected an error for a negative context MinCount")
+	}
+	p, err := NewPairModel(testFileForPairs, "out.txt", 1, VocabHashSizeOption(10), VocabMaxSizeOption(50))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Contexts.VocabMaxSize != 50 || len(p.Contexts.Vocab) != 50 {
+		t.Errorf("the context vocabulary should take VocabMaxSize 50, got %d/%d", p.Contexts.VocabMaxSize, len(p.Contexts.Vocab))
+	}
+	if p.Words.Alpha != ALPHA_SKIP_GRAM {
+		t.Errorf("expected the skip-gram learning rate, got %f", p.Words.Alpha)
+	}
+	if p.Contexts.OutputFile != "out.txt.contexts" {
+		t.Errorf("expected context vectors next to the word vectors, got %q", p.Contexts.OutputFile)
+	}
+}
+
+func TestPairModelLearnVocab(t *testing.T) {
+	p := newSmallPairModel(t, "out.txt", 3)
+	if err := p.LearnVocab(); err != nil {
+		t.Fatal(err)
+	}
+	// zebra occurs once and is below MinCount 2, amod_striped occurs twice and is below the context MinCount 3
+	if p.Words.SearchVocab("zebra") != -1 || p.Words.SearchVocab("horse") <= 0 {
+		t.Error("expected zebra to be discarded and horse to be kept")
+	}
+	if p.Contexts.SearchVocab("amod_striped") != -1 || p.Contexts.SearchVocab("amod_furry") <= 0 {
+		t.Error("expected amod_striped to be discarded and amod_furry to be kept")
+	}
+	if p.Words.VocabSize != 11 || p.Contexts.VocabSize != 11 {
+		t.Errorf("expected 10 words and 10 contexts besides </s>, got %d and %d", p.Words.VocabSize, p.Contexts.VocabSize)
+	}
+	if p.Words.TrainWords != 601 {
+		t.Errorf("expected 601 pairs with a kept word, got %d", p.Words.TrainWords)
+	}
+	if p.Words.SearchVocab("amod_furry") != -1 || p.Contexts.SearchVocab("horse") != -1 {
+		t.Error("words and contexts should have separate vocabularies")
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.txt")
+	if err := os.WriteFile(bad, []byte("horse amod_furry\nhorse\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p.Words.TrainFile = bad
+	if err := p.LearnVocab(); err == nil || !strings.Contains(err.Error(), "Pair 2") {
+		t.Errorf("expected an error for the second line, got %v", err)
+	}
+}
+
+func TestPairModelTrain(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "vectors.txt")
+	p := newSmallPairModel(t, out, 1)
+	report, err := p.Train()
+	if err != nil {
+		t.Fatal(err)
+	}
+	losses := report.EpochLosses()
+	if losses[len(losses)-1] >= losses[0] {
+		t.Errorf("loss should go down during training, got %v", losses)
+	}
+
+	words := readTextEmbeddings(t, out)
+	contexts := readTextEmbeddings(t, p.Contexts.OutputFile)
+	if words.Len() != p.Words.VocabSize || contexts.Len() != p.Contexts.VocabSize {
+		t.Fatalf("expected %d words and %d contexts, got %d and %d", p.Words.VocabSize, p.Contexts.VocabSize, words.Len(), contexts.Len())
+	}
+	if _, ok := contexts.Index("amod_striped"); !ok {
+		t.Error("expected amod_striped to be kept with context MinCount 1")
+	}
+
+	// words sharing contexts end up close, the contexts of a word score highest against it
+	same, _ := words.Similarity("horse", "dog")
+	other, _ := words.Similarity("horse", "bread")
+	if same <= other {
+		t.Errorf("horse should be closer to dog than to bread, got %f and %f", same, other)
+	}
+	horse, _ := words.Vector("horse")
+	furry, _ := contexts.Vector("amod_furry")
+	fresh, _ := contexts.Vector("amod_fresh")
+	if dot32(horse, furry) <= dot32(horse, fresh) {
+		t.Error("horse should score amod_furry above amod_fresh")
+	}
+}
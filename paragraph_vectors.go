@@ -0,0 +1,209 @@
+package wordvec
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+/*
+Paragraph vectors (Le and Mikolov, https://arxiv.org/abs/1405.4053) learn a vector per document alongside the word vectors. With ParagraphVectors set, a token starting with DOC_TAG_PREFIX tags the rest of its line, normally it is the first token:
+
+	_*review_17 the horse finds apple in the field
+
+Tags are kept out of the word vocabulary. The architecture is CBOW or skip-gram: PV-DM adds the document vector to the averaged CBOW context, PV-DBOW predicts every word of the document from its vector (with negative sampling or hierarchical softmax) while skip-gram trains the words. Lines without a tag only train words.
+*/
+
+// isDocTag reports whether a token is a document tag.
+func isDocTag(token string) bool {
+	return len(token) > len(DOC_TAG_PREFIX) && strings.HasPrefix(token, DOC_TAG_PREFIX)
+}
+
+// docTagIndex returns the row of a document tag in SynDoc, or -1.
+func (v *VectorModel) docTagIndex(tag string) int {
+	if i, ok := v.docIndex[tag]; ok {
+		return i
+	}
+	return -1
+}
+
+// LearnDocTags collects the document tags of TrainFile in order of first appearance into DocTags.
+func (v *VectorModel) LearnDocTags() error {
+	f, err := os.Open(v.TrainFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fin := bufio.NewReader(f)
+	v.DocTags = nil
+	v.docIndex = make(map[string]int)
+	for {
+		word, rerr := v.ReadWord(fin)
+		if rerr == io.EOF {
+			break
+		}
+		if isDocTag(word) {
+			if _, ok := v.docIndex[word]; !ok {
+				v.docIndex[word] = len(v.DocTags)
+				v.DocTags = append(v.DocTags, word)
+			}
+		}
+	}
+	if v.DebugMode > 0 {
+		fmt.Fprintf(os.Stdout, "Document tags: %d\n", len(v.DocTags))
+	}
+	return nil
+}
+
+// setDocTags sets DocTags and rebuilds the tag index, e.g. after loading a model.
+func (v *VectorModel) setDocTags(tags []string) {
+	v.DocTags = tags
+	v.docIndex = make(map[string]int, len(tags))
+	for i, tag := range tags {
+		v.docIndex[tag] = i
+	}
+}
+
+// initDocVectors seeds SynDoc with small random values like Syn0.
+func (v *VectorModel) initDocVectors(nextRandom uint64) {
+	if !v.ParagraphVectors {
+		v.SynDoc = nil
+		return
+	}
+	v.SynDoc = make([]float64, len(v.DocTags)*v.Layer1VecSize)
+	for i := range v.SynDoc {
+		nextRandom = nextRandom*25214903917 + 11
+		v.SynDoc[i] = ((float64(nextRandom&0xFFFF) / 65536) - 0.5) / float64(v.Layer1VecSize)
+	}
+}
+
+// DocVector returns a copy of the trained vector of a document tag, or false if the tag is unknown.
+func (v *VectorModel) DocVector(tag string) ([]float32, bool) {
+	i := v.docTagIndex(tag)
+	if i < 0 || len(v.SynDoc) < (i+1)*v.Layer1VecSize {
+		return nil, false
+	}
+	vec := make([]float32, v.Layer1VecSize)
+	for d, x := range v.SynDoc[i*v.Layer1VecSize : (i+1)*v.Layer1VecSize] {
+		vec[d] = float32(x)
+	}
+	return vec, true
+}
+
+// DocEmbeddings copies the document vectors into an Embeddings keyed by tag, to find similar documents.
+func (v *VectorModel) DocEmbeddings() (*Embeddings, error) {
+	if len(v.DocTags) == 0 || len(v.SynDoc) < len(v.DocTags)*v.Layer1VecSize {
+		return nil, errors.New("Model has no trained document vectors")
+	}
+	vectors := make([]float32, len(v.DocTags)*v.Layer1VecSize)
+	for i := range vectors {
+		vectors[i] = float32(v.SynDoc[i])
+	}
+	return NewEmbeddings(v.DocTags, v.Layer1VecSize, vectors)
+}
+
+// DocVectorsFile is the path the document vectors are written to by SaveOutput: OutputFile with a ".docvecs" suffix.
+func (v *VectorModel) DocVectorsFile() string {
+	return v.OutputFile + ".docvecs"
+}
+
+// WriteDocVectors writes the document vectors in the format of WriteVectors, one tag per line.
+func (v *VectorModel) WriteDocVectors(w io.Writer) error {
+	vectors, err := v.DocEmbeddings()
+	if err != nil {
+		return err
+	}
+	format := FormatText
+	if v.Binaryf {
+		format = FormatBinary
+	}
+	return WriteEmbeddings(w, vectors, format)
+}
+
+/*
+InferVector learns a vector for an unseen document from its tokens with the word vectors and output weights frozen: starting from a random vector, only the document vector is trained for Iter passes over the tokens, with the learning rate decaying linearly from StartingAlpha. Unknown tokens are skipped.
+
+The model must have been trained with ParagraphVectors and keep its output weights, as TrainModel and Load do. A model without a unigram table builds it on the first call, so make a first call before inferring from several goroutines.
+*/
+func (v *VectorModel) InferVector(tokens []string) ([]float32, error) {
+	size := v.Layer1VecSize
+	if !v.ParagraphVectors {
+		return nil, errors.New("Model was not trained with ParagraphVectors")
+	}
+	if len(v.Syn0) < v.VocabSize*size || (v.SoftMax && len(v.Syn1) < v.VocabSize*size) || (v.NegSampling > 0 && len(v.Syn1neg) < v.VocabSize*size) {
+		return nil, errors.New("Model has no trained weights to infer from")
+	}
+	var words []int
+	for _, token := range tokens {
+		if a := v.SearchVocab(token); a > 0 {
+			words = append(words, a)
+		}
+	}
+	if len(words) == 0 {
+		return nil, errors.New("None of the tokens is in the vocabulary")
+	}
+	if v.NegSampling > 0 && len(v.Table) == 0 {
+		v.InitUnigramTable()
+	}
+
+	nextRandom := v.NextRandom
+	doc := make([]float64, size)
+	for d := range doc {
+		nextRandom = nextRandom*25214903917 + 11
+		doc[d] = ((float64(nextRandom&0xFFFF) / 65536) - 0.5) / float64(size)
+	}
+	neu1 := make([]float64, size)
+	neu1e := make([]float64, size)
+	var stats threadStats
+	startingAlpha := v.StartingAlpha
+	if startingAlpha == 0 {
+		startingAlpha = v.Alpha
+	}
+	steps := float64(v.Iter * len(words))
+	step := 0
+	for iter := 0; iter < v.Iter; iter++ {
+		for pos, word := range words {
+			alpha := startingAlpha * (1 - float64(step)/steps)
+			if alpha < startingAlpha*0.0001 {
+				alpha = startingAlpha * 0.0001
+			}
+			step++
+			for d := range neu1e {
+				neu1e[d] = 0
+			}
+			if v.Architecture == ArchSkipGram {
+				v.trainTarget(doc, word, 0, neu1e, alpha, false, &nextRandom, &stats)
+			} else {
+				for d := range neu1 {
+					neu1[d] = doc[d]
+				}
+				cw := 1
+				nextRandom = nextRandom*25214903917 + 11
+				b := int(nextRandom % uint64(v.WindowSkipLen))
+				for a := b; a < v.WindowSkipLen*2+1-b; a++ {
+					c := pos - v.WindowSkipLen + a
+					if a == v.WindowSkipLen || c < 0 || c >= len(words) {
+						continue
+					}
+					v.addInput(words[c], neu1)
+					cw++
+				}
+				for d := range neu1 {
+					neu1[d] /= float64(cw)
+				}
+				v.trainTarget(neu1, word, 0, neu1e, alpha, false, &nextRandom, &stats)
+			}
+			for d := range doc {
+				doc[d] += neu1e[d]
+			}
+		}
+	}
+	vec := make([]float32, size)
+	for d, x := range doc {
+		vec[d] = float32(x)
+	}
+	return vec, nil
+}
@@ -0,0 +1,143 @@
+package wordvec
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testFileForDocs string = "testdata/docs_small.txt"
+
+// trainDocModel trains paragraph vectors on the small document corpus: doc0 to doc9 are about animals, doc10 to doc19 about cooking.
+func trainDocModel(t *testing.T, modelParams ...ModelParams) *VectorModel {
+	params := append([]ModelParams{ParagraphVectorsTrue, TrainFileOption(testFileForDocs), IterOption(40), SampleOption(0)}, modelParams...)
+	mv := newSmallTrainingModel(t, filepath.Join(t.TempDir(), "vectors.txt"), params...)
+	if _, err := mv.TrainModel(); err != nil {
+		t.Fatal(err)
+	}
+	return mv
+}
+
+func isAnimalDoc(tag string) bool {
+	return len(tag) == len("_*doc0")
+}
+
+func TestIsDocTag(t *testing.T) {
+	for token, want := range map[string]bool{"_*doc1": true, "_*": false, "_doc": false, "doc": false, "*_doc": false} {
+		if isDocTag(token) != want {
+			t.Errorf("isDocTag(%q) should be %v", token, want)
+		}
+	}
+}
+
+func TestParagraphVectors(t *testing.T) {
+	for _, arch := range []struct {
+		name   string
+		params []ModelParams
+	}{{"PV-DM", nil}, {"PV-DBOW", []ModelParams{BagOfWordsFalse}}} {
+		mv := trainDocModel(t, arch.params...)
+		if len(mv.DocTags) != 20 {
+			t.Fatalf("%s: expected 20 document tags, got %d", arch.name, len(mv.DocTags))
+		}
+		if mv.SearchVocab("_*doc3") != -1 {
+			t.Errorf("%s: document tags should not be in the word vocabulary", arch.name)
+		}
+		docs, err := mv.DocEmbeddings()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the nearest documents should be on the same topic
+		wrong := 0
+		for _, tag := range mv.DocTags {
+			nn, err := docs.MostSimilar(tag, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, n := range nn {
+				if isAnimalDoc(n.Word) != isAnimalDoc(tag) {
+					wrong++
+				}
+			}
+		}
+		if wrong > 6 {
+			t.Errorf("%s: %d of 60 nearest documents are on the other topic", arch.name, wrong)
+		}
+
+		vec, err := mv.InferVector(strings.Fields("the goat walks in the meadow with the horse unicorn"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var animal, cooking float64
+		for _, tag := range mv.DocTags {
+			dv, _ := mv.DocVector(tag)
+			sim := cosine32(vec, dv)
+			if isAnimalDoc(tag) {
+				animal += sim
+			} else {
+				cooking += sim
+			}
+		}
+		if animal <= cooking {
+			t.Errorf("%s: an inferred animal document should be closer to the animal documents, got %f and %f", arch.name, animal/10, cooking/10)
+		}
+		if _, err := mv.InferVector([]string{"unicorn"}); err == nil {
+			t.Errorf("%s: expected an error without known tokens", arch.name)
+		}
+	}
+}
+
+func TestParagraphVectorsOutput(t *testing.T) {
+	mv := trainDocModel(t, IterOption(2))
+	f, err := os.Open(mv.DocVectorsFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	docs, err := ReadTextEmbeddings(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if docs.Len() != 20 || docs.Dim() != mv.Layer1VecSize {
+		t.Errorf("expected 20 document vectors of size %d, got %d of size %d", mv.Layer1VecSize, docs.Len(), docs.Dim())
+	}
+
+	name := filepath.Join(t.TempDir(), "model.wv")
+	if err := mv.SaveFile(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.ParagraphVectors || len(loaded.DocTags) != 20 {
+		t.Fatalf("document tags not restored, got %d", len(loaded.DocTags))
+	}
+	want, _ := mv.DocVector("_*doc7")
+	got, ok := loaded.DocVector("_*doc7")
+	if !ok || cosine32(want, got) < 0.9999 {
+		t.Error("document vector changed after loading")
+	}
+	loaded.TableSize = 1e5
+	if _, err := loaded.InferVector([]string{"the", "cow"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInferVectorNeedsParagraphVectors(t *testing.T) {
+	mv := trainSmallModel(t)
+	if _, err := mv.InferVector([]string{"the", "horse"}); err == nil {
+		t.Error("expected an error for a model without paragraph vectors")
+	}
+}
+
+func cosine32(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,126 @@
+package wordvec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// PHRASE_PASSES is the number of word2phrase passes of a PhrasePipeline, as in the demo-phrases script of word2vec.
+	PHRASE_PASSES int = 2
+	// PHRASE_THRESHOLD_DECAY is the factor the threshold is multiplied by after every pass.
+	PHRASE_THRESHOLD_DECAY float64 = 0.5
+)
+
+/*
+PhrasePipeline runs word2phrase several times, every pass on the output of the one before, so phrases grow by a word or more per pass: new york becomes new_york in the first pass and new_york times becomes new_york_times in the second. The threshold starts at Model.Threshold and is multiplied by Decay after every pass, the schedule of the demo-phrases script (200, then 100); Thresholds overrides the schedule with one threshold per pass.
+
+Model holds the word2phrase options (MinCount, PhraseScorer, MaxStringLen, VocabHashSize) and is reused by every pass. The intermediate corpora are temporary files in TempDir (the system default when empty) that are removed afterwards; the last pass writes OutputFile. The phrase table of all passes is written to TableFile unless it is empty.
+*/
+type PhrasePipeline struct {
+	Model      *VectorModel
+	Passes     int       // word2phrase passes, default PHRASE_PASSES
+	Decay      float64   // threshold factor between passes, default PHRASE_THRESHOLD_DECAY
+	Thresholds []float64 // explicit threshold of every pass, overrides Model.Threshold and Decay
+	TempDir    string    // directory of the intermediate corpora
+	TableFile  string    // where Run writes the phrase table, default outFile + ".phrases"
+}
+
+// NewPhrasePipeline creates a pipeline of passes word2phrase passes over trainFile with the model params of NewWord2PhraseModel; the first pass uses threshold.
+func NewPhrasePipeline(trainFile, outFile string, passes int, threshold float64, modelParams ...ModelParams) (*PhrasePipeline, error) {
+	if passes < 1 {
+		return &PhrasePipeline{}, fmt.Errorf("A phrase pipeline needs at least one pass, got %d", passes)
+	}
+	model, err := NewWord2PhraseModel(trainFile, outFile, threshold, modelParams...)
+	if err != nil {
+		return &PhrasePipeline{}, err
+	}
+	return &PhrasePipeline{
+		Model:     model,
+		Passes:    passes,
+		Decay:     PHRASE_THRESHOLD_DECAY,
+		TableFile: outFile + ".phrases",
+	}, nil
+}
+
+// thresholds returns the threshold of every pass.
+func (p *PhrasePipeline) thresholds() ([]float64, error) {
+	if len(p.Thresholds) > 0 {
+		if len(p.Thresholds) != p.Passes {
+			return nil, fmt.Errorf("Expected a threshold for each of the %d passes, got %d", p.Passes, len(p.Thresholds))
+		}
+		return p.Thresholds, nil
+	}
+	if p.Decay <= 0 {
+		return nil, fmt.Errorf("Decay must be greater than 0, got %g", p.Decay)
+	}
+	schedule := make([]float64, p.Passes)
+	threshold := p.Model.Threshold
+	for i := range schedule {
+		schedule[i] = threshold
+		threshold *= p.Decay
+	}
+	return schedule, nil
+}
+
+/*
+Run runs the passes and returns the phrase table with a PhrasePass for each of them. Model.TrainFile, OutputFile, Threshold and MinReduce are restored afterwards.
+*/
+func (p *PhrasePipeline) Run() (*PhraseTable, error) {
+	v := p.Model
+	if p.Passes < 1 {
+		return nil, fmt.Errorf("A phrase pipeline needs at least one pass, got %d", p.Passes)
+	}
+	if v.TrainFile == "" {
+		return nil, errors.New("No training file specified")
+	}
+	if v.OutputFile == "" {
+		return nil, errors.New("No output file specified")
+	}
+	schedule, err := p.thresholds()
+	if err != nil {
+		return nil, err
+	}
+	trainFile, outputFile, threshold, minReduce := v.TrainFile, v.OutputFile, v.Threshold, v.MinReduce
+	defer func() {
+		v.TrainFile, v.OutputFile, v.Threshold, v.MinReduce = trainFile, outputFile, threshold, minReduce
+	}()
+
+	table := &PhraseTable{MaxStringLen: v.MaxStringLen}
+	var temps []string
+	defer func() {
+		for _, name := range temps {
+			os.Remove(name)
+		}
+	}()
+	for pass, t := range schedule {
+		out := outputFile
+		if pass < p.Passes-1 {
+			f, err := os.CreateTemp(p.TempDir, filepath.Base(outputFile)+".pass*")
+			if err != nil {
+				return nil, err
+			}
+			f.Close()
+			out = f.Name()
+			temps = append(temps, out)
+		}
+		v.OutputFile, v.Threshold, v.MinReduce = out, t, minReduce
+		if v.DebugMode > 0 {
+			fmt.Fprintf(os.Stdout, "Phrase pass %d of %d, threshold %g\n", pass+1, p.Passes, t)
+		}
+		passTable, err := v.TrainPhrases()
+		if err != nil {
+			return nil, err
+		}
+		table.Passes = append(table.Passes, passTable.Passes...)
+		v.TrainFile = out
+	}
+	if p.TableFile != "" {
+		if err := table.SaveFile(p.TableFile); err != nil {
+			return table, err
+		}
+	}
+	return table, nil
+}
@@ -0,0 +1,149 @@
+package wordvec
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+/*
+PhraserConfig holds the options of a Phraser.
+
+	Thresholds	Minimum score of a join for every pass of the table; a missing or zero entry keeps the threshold the pass was learned with. Only the bigrams stored in the table can be joined, so thresholds below the learned ones join nothing more.
+	DP			Picks the non-overlapping joins of a pass with the highest total score instead of joining greedily from the left like word2phrase; this can differ from the phrasing of the training corpus when candidates overlap, as in "a b c" with both a_b and b_c.
+*/
+type PhraserConfig struct {
+	Thresholds []float64
+	DP         bool
+}
+
+/*
+Phraser applies a learned PhraseTable to text, so queries are phrased like the corpus the vectors were trained on before their tokens are looked up: the passes are applied in order, each joining the bigrams of its pass. With the default greedy joins a Phraser reproduces the output of TrainPhrases and PhrasePipeline exactly, including the truncation of long tokens between passes.
+
+A Phraser is not modified after NewPhraser and is safe for concurrent use.
+*/
+type Phraser struct {
+	maxStringLen int
+	dp           bool
+	passes       []map[[2]string]float64 // the joinable bigrams of every pass with their scores
+}
+
+// NewPhraser returns a Phraser for the table with the thresholds and join strategy of cfg.
+func NewPhraser(table *PhraseTable, cfg PhraserConfig) (*Phraser, error) {
+	if len(cfg.Thresholds) > len(table.Passes) {
+		return nil, fmt.Errorf("Got %d thresholds for a phrase table of %d passes", len(cfg.Thresholds), len(table.Passes))
+	}
+	p := &Phraser{maxStringLen: table.MaxStringLen, dp: cfg.DP}
+	for i, pass := range table.Passes {
+		threshold := pass.Threshold
+		if i < len(cfg.Thresholds) && cfg.Thresholds[i] != 0 {
+			threshold = cfg.Thresholds[i]
+		}
+		bigrams := make(map[[2]string]float64, len(pass.Bigrams))
+		for bigram, score := range pass.Bigrams {
+			if score > threshold {
+				bigrams[bigram] = score
+			}
+		}
+		p.passes = append(p.passes, bigrams)
+	}
+	return p, nil
+}
+
+// LoadPhraser reads a phrase table written by PhraseTable.SaveFile, such as the TableFile of a PhrasePipeline, and returns its Phraser.
+func LoadPhraser(name string, cfg PhraserConfig) (*Phraser, error) {
+	table, err := LoadPhraseTable(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewPhraser(table, cfg)
+}
+
+// truncate cuts a token to maxStringLen bytes, as ReadWord does when the next pass reads it.
+func (p *Phraser) truncate(token string) string {
+	if p.maxStringLen > 0 && len(token) >= p.maxStringLen {
+		return token[:p.maxStringLen]
+	}
+	return token
+}
+
+// join applies one pass to the tokens of a sentence.
+func (p *Phraser) join(bigrams map[[2]string]float64, tokens []string, last bool) []string {
+	joined := make([]string, 0, len(tokens))
+	phrase := func(a, b string) string {
+		if last {
+			return a + PHRASE_SEPARATOR + b
+		}
+		return p.truncate(a + PHRASE_SEPARATOR + b)
+	}
+	if !p.dp {
+		for i := 0; i < len(tokens); i++ {
+			if i+1 < len(tokens) {
+				if _, ok := bigrams[[2]string{tokens[i], tokens[i+1]}]; ok {
+					joined = append(joined, phrase(tokens[i], tokens[i+1]))
+					i++
+					continue
+				}
+			}
+			joined = append(joined, tokens[i])
+		}
+		return joined
+	}
+	// best[i] is the highest total score of joins among the first i tokens
+	best := make([]float64, len(tokens)+1)
+	pair := make([]bool, len(tokens)+1)
+	for i := 2; i <= len(tokens); i++ {
+		best[i] = best[i-1]
+		if score, ok := bigrams[[2]string{tokens[i-2], tokens[i-1]}]; ok && best[i-2]+score > best[i] {
+			best[i] = best[i-2] + score
+			pair[i] = true
+		}
+	}
+	for i := len(tokens); i > 0; {
+		if pair[i] {
+			joined = append(joined, phrase(tokens[i-2], tokens[i-1]))
+			i -= 2
+		} else {
+			joined = append(joined, tokens[i-1])
+			i--
+		}
+	}
+	for l, r := 0, len(joined)-1; l < r; l, r = l+1, r-1 {
+		joined[l], joined[r] = joined[r], joined[l]
+	}
+	return joined
+}
+
+// Phrase returns the tokens of one sentence with the phrases of every pass joined. Tokens are not truncated or split; use PhraseText for raw text.
+func (p *Phraser) Phrase(tokens []string) []string {
+	for i, bigrams := range p.passes {
+		tokens = p.join(bigrams, tokens, i == len(p.passes)-1)
+	}
+	return tokens
+}
+
+/*
+PhraseText splits text into tokens with ReadWord, the tokenizer of training, and returns them phrased. A line break ends a sentence, no phrase is formed across it and no "</s>" token is returned.
+*/
+func (p *Phraser) PhraseText(text string) []string {
+	tokenizer := &VectorModel{MaxStringLen: p.maxStringLen}
+	if p.maxStringLen <= 0 {
+		tokenizer.MaxStringLen = MAX_STRING_PHRASE
+	}
+	r := bufio.NewReader(strings.NewReader(text))
+	var phrased, sentence []string
+	for {
+		word, err := tokenizer.ReadWord(r)
+		if word == "</s>" {
+			phrased = append(phrased, p.Phrase(sentence)...)
+			sentence = sentence[:0]
+		} else if word != "" {
+			sentence = append(sentence, word)
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return append(phrased, p.Phrase(sentence)...)
+}
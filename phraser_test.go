@@ -0,0 +1,89 @@
+package wordvec
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPhraserReproducesPipeline(t *testing.T) {
+	dir := t.TempDir()
+	corpus := writePhraseCorpus(t)
+	out := filepath.Join(dir, "phrases.txt")
+	p, err := NewPhrasePipeline(corpus, out, 2, 5, VocabHashSizeOption(5000), MinCountOption(10), DebugModeOption(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.TempDir = dir
+	if _, err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	phraser, err := LoadPhraser(p.TableFile, PhraserConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(corpus)
+	phrased, _ := os.ReadFile(out)
+	want := strings.Split(strings.TrimSuffix(string(phrased), "\n"), "\n")
+	for i, line := range strings.Split(strings.TrimSuffix(string(raw), "\n"), "\n") {
+		if got := strings.Join(phraser.PhraseText(line), " "); got != want[i] {
+			t.Fatalf("line %d: expected %q like the pipeline, got %q", i, want[i], got)
+		}
+	}
+
+	got := phraser.PhraseText("who reads the  new york\ttimes\nin new\nyork")
+	expected := []string{"who", "reads", "the", "new_york_times", "in", "new", "york"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, no phrase across lines, got %v", expected, got)
+	}
+}
+
+func TestPhraserJoins(t *testing.T) {
+	table := &PhraseTable{MaxStringLen: 5, Passes: []PhrasePass{
+		{Threshold: 1, Bigrams: map[[2]string]float64{{"a", "b"}: 2, {"b", "c"}: 5, {"ccc", "dd"}: 3}},
+		{Threshold: 1, Bigrams: map[[2]string]float64{{"a_b", "c"}: 2, {"ccc_d", "e"}: 2}},
+	}}
+	tests := []struct {
+		cfg    PhraserConfig
+		tokens string
+		want   string
+	}{
+		{PhraserConfig{}, "a b c", "a_b_c"},
+		{PhraserConfig{DP: true}, "a b c", "a b_c"},
+		{PhraserConfig{Thresholds: []float64{3}}, "a b c", "a b_c"},
+		{PhraserConfig{DP: true}, "x a b y", "x a_b y"},
+		// ccc_dd is read back as ccc_d by the second pass
+		{PhraserConfig{}, "ccc dd e", "ccc_d_e"},
+	}
+	for _, test := range tests {
+		phraser, err := NewPhraser(table, test.cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(phraser.Phrase(strings.Fields(test.tokens)), " "); got != test.want {
+			t.Errorf("%+v: expected %q for %q, got %q", test.cfg, test.want, test.tokens, got)
+		}
+	}
+	if _, err := NewPhraser(table, PhraserConfig{Thresholds: []float64{1, 2, 3}}); err == nil {
+		t.Error("expected an error for more thresholds than passes")
+	}
+
+	phraser, _ := NewPhraser(table, PhraserConfig{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if got := phraser.PhraseText("a b c\nccc dd e"); len(got) != 2 {
+					t.Errorf("unexpected phrasing %v", got)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
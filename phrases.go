@@ -0,0 +1,339 @@
+package wordvec
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PHRASE_SEPARATOR joins the words of a phrase into one token, as word2phrase does.
+const PHRASE_SEPARATOR string = "_"
+
+/*
+PhraseScorer selects how word2phrase scores a bigram "a b" from the counts of a, b and the bigram in the training data, see the PhraseScorer field of VectorModel. A bigram is joined into the token a_b when its score is above Threshold, so every scorer needs a threshold on its own scale.
+*/
+type PhraseScorer int
+
+const (
+	// PhraseScoreWord2Phrase is the score of the original word2phrase, (count(a b) - MinCount) / count(a) / count(b) * TrainWords. Its scale depends on the corpus size; word2phrase uses thresholds of 100 to 200.
+	PhraseScoreWord2Phrase PhraseScorer = iota
+	// PhraseScoreNPMI is the normalized pointwise mutual information of a and b (Bouma, 2009), between -1 and 1 whatever the corpus size; thresholds around 0.5 work well.
+	PhraseScoreNPMI
+	// PhraseScoreChiSquare is Pearson's chi-square statistic of the 2x2 contingency table of a and b; 10.83 is the 0.1% significance level of one degree of freedom.
+	PhraseScoreChiSquare
+)
+
+// String returns the name of the scorer as used in model configs and phrase tables.
+func (s PhraseScorer) String() string {
+	switch s {
+	case PhraseScoreWord2Phrase:
+		return "word2phrase"
+	case PhraseScoreNPMI:
+		return "npmi"
+	case PhraseScoreChiSquare:
+		return "chi-square"
+	}
+	return fmt.Sprintf("PhraseScorer(%d)", int(s))
+}
+
+// ParsePhraseScorer returns the scorer with the given name: "word2phrase", "npmi" or "chi-square".
+func ParsePhraseScorer(name string) (PhraseScorer, error) {
+	switch strings.ToLower(name) {
+	case "word2phrase", "default":
+		return PhraseScoreWord2Phrase, nil
+	case "npmi":
+		return PhraseScoreNPMI, nil
+	case "chi-square", "chisquare", "chi2":
+		return PhraseScoreChiSquare, nil
+	}
+	return 0, fmt.Errorf("Unknown phrase scorer %q", name)
+}
+
+/*
+scorePhrase scores the bigram "a b" from the count pa of a, pb of b and pab of the bigram with the PhraseScorer of the model. Words seen less than MinCount times are never joined and score 0, as in word2phrase.
+*/
+func (v *VectorModel) scorePhrase(pa, pb, pab int) float64 {
+	minCount := float64(v.MinCount)
+	if pa < v.MinCount || pb < v.MinCount || pab == 0 {
+		return 0
+	}
+	n := float64(v.TrainWords)
+	a, b, ab := float64(pa), float64(pb), float64(pab)
+	switch v.PhraseScorer {
+	case PhraseScoreNPMI:
+		if pab < v.MinCount {
+			return 0
+		}
+		if ab >= n {
+			return 1
+		}
+		return math.Log(ab*n/(a*b)) / -math.Log(ab/n)
+	case PhraseScoreChiSquare:
+		if pab < v.MinCount {
+			return 0
+		}
+		o12, o21 := a-ab, b-ab
+		o22 := n - a - b + ab
+		den := a * b * (n - a) * (n - b)
+		if den <= 0 {
+			return 0
+		}
+		d := ab*o22 - o12*o21
+		return n * d * d / den
+	}
+	return (ab - minCount) / a / b * n
+}
+
+/*
+learnPhraseVocab counts the words of TrainFile and the bigrams of consecutive words within a line into the vocabulary, the bigram "a b" as the token a_b, like the vocabulary of word2phrase. Nothing is discarded by MinCount here, the scores check it.
+*/
+func (v *VectorModel) learnPhraseVocab() error {
+	fmt.Fprintf(os.Stdout, "Learning Vocab from Training File: %s\n", v.TrainFile)
+	f, err := os.Open(v.TrainFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fin := bufio.NewReader(f)
+	v.resetVocabHashIndices()
+	v.VocabSize = 0
+	v.TrainWords = 0
+	lastWord := ""
+	for {
+		word, rerr := v.ReadWord(fin)
+		if rerr == io.EOF {
+			break
+		}
+		if word == "</s>" {
+			lastWord = ""
+			continue
+		}
+		v.TrainWords++
+		if (v.DebugMode > 1) && (v.TrainWords%100000 == 0) {
+			fmt.Fprintf(os.Stdout, "%dK%c", v.TrainWords/1000, 13)
+		}
+		v.countToken(word)
+		if lastWord != "" {
+			v.countToken(lastWord + PHRASE_SEPARATOR + word)
+		}
+		lastWord = word
+	}
+	if v.DebugMode > 0 {
+		fmt.Fprintf(os.Stdout, "Vocab size (unigrams + bigrams): %d\n", v.VocabSize)
+		fmt.Fprintf(os.Stdout, "Words in train file: %d\n", v.TrainWords)
+	}
+	return nil
+}
+
+// vocabCount returns the count of a token in the vocabulary, 0 when it is not in it.
+func (v *VectorModel) vocabCount(token string) int {
+	if i := v.SearchVocab(token); i != -1 {
+		return v.Vocab[i].Count
+	}
+	return 0
+}
+
+/*
+TrainPhrases is word2phrase: it learns the word and bigram counts of TrainFile and writes it to OutputFile with every bigram scoring above Threshold joined into one token, such as new_york. Bigrams are joined greedily from the left and a joined word does not start the next bigram, so one pass only forms two-word phrases; see PhrasePipeline for longer ones. Lines are kept and words are separated by single spaces.
+
+The returned PhraseTable has the joined bigrams with their scores, to phrase other text the same way.
+*/
+func (v *VectorModel) TrainPhrases() (*PhraseTable, error) {
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+	if v.TrainFile == "" {
+		return nil, errors.New("No training file specified")
+	}
+	if v.OutputFile == "" {
+		return nil, errors.New("No output file specified")
+	}
+	fmt.Fprintf(os.Stdout, "Starting phrase detection using file %s\n", v.TrainFile)
+	if err := v.learnPhraseVocab(); err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Open(v.TrainFile)
+	if err != nil {
+		return nil, err
+	}
+	defer fi.Close()
+	fo, err := os.Create(v.OutputFile)
+	if err != nil {
+		return nil, err
+	}
+	defer fo.Close()
+	fin := bufio.NewReader(fi)
+	fout := bufio.NewWriter(fo)
+
+	pass := PhrasePass{Threshold: v.Threshold, Scorer: v.PhraseScorer, Bigrams: make(map[[2]string]float64)}
+	lastWord := ""
+	pa := 0
+	lineStart := true
+	for {
+		word, rerr := v.ReadWord(fin)
+		if rerr == io.EOF {
+			break
+		}
+		if word == "</s>" {
+			fout.WriteByte('\n')
+			lastWord, pa, lineStart = "", 0, true
+			continue
+		}
+		pb := v.vocabCount(word)
+		score := 0.0
+		if lastWord != "" {
+			score = v.scorePhrase(pa, pb, v.vocabCount(lastWord+PHRASE_SEPARATOR+word))
+		}
+		if score > v.Threshold {
+			fout.WriteString(PHRASE_SEPARATOR)
+			pass.Bigrams[[2]string{lastWord, word}] = score
+			// a joined word does not start the next bigram
+			pb = 0
+		} else if !lineStart {
+			fout.WriteByte(' ')
+		}
+		fout.WriteString(word)
+		lastWord, pa, lineStart = word, pb, false
+	}
+	if err := fout.Flush(); err != nil {
+		return nil, err
+	}
+	if v.DebugMode > 0 {
+		fmt.Fprintf(os.Stdout, "Phrases joined: %d\n", len(pass.Bigrams))
+	}
+	return &PhraseTable{MaxStringLen: v.MaxStringLen, Passes: []PhrasePass{pass}}, nil
+}
+
+// PhrasePass is the outcome of one word2phrase pass: the bigrams that scored above Threshold with Scorer and were joined.
+type PhrasePass struct {
+	Threshold float64
+	Scorer    PhraseScorer
+	Bigrams   map[[2]string]float64 // the two tokens of a joined bigram -> its score
+}
+
+/*
+PhraseTable is the phrasing learned by TrainPhrases or a PhrasePipeline, one PhrasePass per pass in the order they ran; a token of a later pass can be a phrase joined by an earlier one. MaxStringLen is the word length the corpus was read with, longer words (and phrases) were truncated.
+*/
+type PhraseTable struct {
+	MaxStringLen int
+	Passes       []PhrasePass
+}
+
+/*
+WriteTo writes the table as text: a "phrases <passes> <MaxStringLen>" line, then for every pass a "pass <threshold> <scorer> <bigrams>" line followed by that many "<a> <b> <score>" lines, sorted by score.
+*/
+func (t *PhraseTable) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var n int64
+	write := func(format string, args ...interface{}) {
+		m, _ := fmt.Fprintf(bw, format, args...)
+		n += int64(m)
+	}
+	write("phrases %d %d\n", len(t.Passes), t.MaxStringLen)
+	for _, pass := range t.Passes {
+		write("pass %s %s %d\n", strconv.FormatFloat(pass.Threshold, 'g', -1, 64), pass.Scorer, len(pass.Bigrams))
+		bigrams := make([][2]string, 0, len(pass.Bigrams))
+		for bigram := range pass.Bigrams {
+			bigrams = append(bigrams, bigram)
+		}
+		sort.Slice(bigrams, func(i, j int) bool {
+			si, sj := pass.Bigrams[bigrams[i]], pass.Bigrams[bigrams[j]]
+			if si != sj {
+				return si > sj
+			}
+			return bigrams[i][0]+" "+bigrams[i][1] < bigrams[j][0]+" "+bigrams[j][1]
+		})
+		for _, bigram := range bigrams {
+			write("%s %s %s\n", bigram[0], bigram[1], strconv.FormatFloat(pass.Bigrams[bigram], 'g', -1, 64))
+		}
+	}
+	return n, bw.Flush()
+}
+
+// SaveFile writes the table to a file, see WriteTo.
+func (t *PhraseTable) SaveFile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err = t.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadPhraseTable reads a table written by WriteTo.
+func ReadPhraseTable(r io.Reader) (*PhraseTable, error) {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	next := func() []string {
+		if !scanner.Scan() {
+			return nil
+		}
+		line++
+		return strings.Fields(scanner.Text())
+	}
+	fields := next()
+	if len(fields) != 3 || fields[0] != "phrases" {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("Not a phrase table, expected a \"phrases <passes> <max string length>\" line")
+	}
+	passes, err := strconv.Atoi(fields[1])
+	if err != nil || passes < 0 {
+		return nil, fmt.Errorf("Bad number of passes %q in phrase table", fields[1])
+	}
+	t := &PhraseTable{}
+	if t.MaxStringLen, err = strconv.Atoi(fields[2]); err != nil {
+		return nil, fmt.Errorf("Bad max string length %q in phrase table", fields[2])
+	}
+	for p := 0; p < passes; p++ {
+		fields = next()
+		if len(fields) != 4 || fields[0] != "pass" {
+			return nil, fmt.Errorf("Line %d of phrase table: expected \"pass <threshold> <scorer> <bigrams>\"", line)
+		}
+		pass := PhrasePass{Bigrams: make(map[[2]string]float64)}
+		if pass.Threshold, err = strconv.ParseFloat(fields[1], 64); err != nil {
+			return nil, fmt.Errorf("Line %d of phrase table: %v", line, err)
+		}
+		if pass.Scorer, err = ParsePhraseScorer(fields[2]); err != nil {
+			return nil, fmt.Errorf("Line %d of phrase table: %v", line, err)
+		}
+		count, err := strconv.Atoi(fields[3])
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("Line %d of phrase table: bad number of bigrams %q", line, fields[3])
+		}
+		for i := 0; i < count; i++ {
+			fields = next()
+			if len(fields) != 3 {
+				return nil, fmt.Errorf("Line %d of phrase table: expected \"<a> <b> <score>\"", line)
+			}
+			score, err := strconv.ParseFloat(fields[2], 64)
+			if err != nil {
+				return nil, fmt.Errorf("Line %d of phrase table: %v", line, err)
+			}
+			pass.Bigrams[[2]string{fields[0], fields[1]}] = score
+		}
+		t.Passes = append(t.Passes, pass)
+	}
+	return t, scanner.Err()
+}
+
+// LoadPhraseTable reads a table from a file, see ReadPhraseTable.
+func LoadPhraseTable(name string) (*PhraseTable, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadPhraseTable(f)
+}
@@ -0,0 +1,142 @@
+package wordvec
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writePhraseCorpus writes lines with "new york times" and "new york" between rare random filler words.
+func writePhraseCorpus(t *testing.T) string {
+	rnd := rand.New(rand.NewSource(2))
+	var sb strings.Builder
+	for i := 0; i < 400; i++ {
+		filler := func() string { return fmt.Sprint("w", rnd.Intn(200)) }
+		switch i % 4 {
+		case 0:
+			fmt.Fprintf(&sb, "%s new york times %s\n", filler(), filler())
+		case 1:
+			fmt.Fprintf(&sb, "%s new york %s\n", filler(), filler())
+		default:
+			fmt.Fprintf(&sb, "%s %s %s %s\n", filler(), filler(), filler(), filler())
+		}
+	}
+	name := filepath.Join(t.TempDir(), "corpus.txt")
+	if err := os.WriteFile(name, []byte(sb.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestScorePhrase(t *testing.T) {
+	v := &VectorModel{MinCount: 5, TrainWords: 1000}
+	if got := v.scorePhrase(20, 10, 8); math.Abs(got-(8-5)/20.0/10.0*1000) > 1e-12 {
+		t.Errorf("unexpected word2phrase score %f", got)
+	}
+	if got := v.scorePhrase(4, 10, 4); got != 0 {
+		t.Errorf("words below MinCount should score 0, got %f", got)
+	}
+	v.PhraseScorer = PhraseScoreNPMI
+	if got := v.scorePhrase(10, 10, 10); math.Abs(got-1) > 1e-12 {
+		t.Errorf("words that only occur together should have an NPMI of 1, got %f", got)
+	}
+	if got := v.scorePhrase(100, 100, 10); math.Abs(got) > 1e-12 {
+		t.Errorf("independent words should have an NPMI of 0, got %f", got)
+	}
+	v.PhraseScorer = PhraseScoreChiSquare
+	// observed 10 5 / 5 980 for a and b
+	a, b, ab, n := 15.0, 15.0, 10.0, 1000.0
+	want := n * math.Pow(ab*(n-a-b+ab)-(a-ab)*(b-ab), 2) / (a * b * (n - a) * (n - b))
+	if got := v.scorePhrase(15, 15, 10); math.Abs(got-want) > 1e-9 || got < 10.83 {
+		t.Errorf("expected a significant chi-square of %f, got %f", want, got)
+	}
+	for _, s := range []PhraseScorer{PhraseScoreWord2Phrase, PhraseScoreNPMI, PhraseScoreChiSquare} {
+		if got, err := ParsePhraseScorer(s.String()); err != nil || got != s {
+			t.Errorf("expected %v back from %q, got %v, %v", s, s.String(), got, err)
+		}
+	}
+}
+
+func TestTrainPhrases(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "phrases.txt")
+	mv, err := NewWord2PhraseModel(writePhraseCorpus(t), out, 5, VocabHashSizeOption(5000), MinCountOption(10), DebugModeOption(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := mv.TrainPhrases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(text), "\n"), "\n")
+	if len(lines) != 400 {
+		t.Fatalf("expected the 400 lines to be kept, got %d", len(lines))
+	}
+	if !strings.Contains(lines[0], " new_york times ") || !strings.Contains(lines[1], " new_york ") {
+		t.Errorf("expected new_york to be joined, got %q and %q", lines[0], lines[1])
+	}
+	if strings.Contains(string(text), "new_york_times") {
+		t.Error("a single pass should only join bigrams")
+	}
+	if _, ok := table.Passes[0].Bigrams[[2]string{"new", "york"}]; !ok || table.Passes[0].Threshold != 5 || len(table.Passes[0].Bigrams) != 1 {
+		t.Errorf("expected new york in the phrase table, got %v", table.Passes[0])
+	}
+}
+
+func TestPhrasePipeline(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "phrases.txt")
+	p, err := NewPhrasePipeline(writePhraseCorpus(t), out, 2, 5, VocabHashSizeOption(5000), MinCountOption(10), DebugModeOption(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.TempDir = dir
+	table, err := p.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := os.ReadFile(out)
+	if !strings.Contains(string(text), " new_york_times ") || !strings.Contains(string(text), " new_york w") {
+		t.Errorf("expected new_york_times after two passes, got %q", strings.SplitN(string(text), "\n", 2)[0])
+	}
+	if len(table.Passes) != 2 || table.Passes[1].Threshold != 2.5 {
+		t.Fatalf("expected two passes with a decayed threshold, got %+v", table.Passes)
+	}
+	if _, ok := table.Passes[1].Bigrams[[2]string{"new_york", "times"}]; !ok {
+		t.Errorf("expected new_york times in the second pass, got %v", table.Passes[1].Bigrams)
+	}
+	if p.Model.TrainFile == out || p.Model.Threshold != 5 {
+		t.Error("the model should be restored after the passes")
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 2 {
+		t.Errorf("expected the output and the table only, got %d files", len(files))
+	}
+
+	// the table written next to the output reads back the same
+	loaded, err := LoadPhraseTable(p.TableFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, table) {
+		t.Errorf("expected the table back, got %+v", loaded)
+	}
+	var buf bytes.Buffer
+	table.WriteTo(&buf)
+	if _, err := ReadPhraseTable(strings.NewReader(strings.Replace(buf.String(), "pass 2.5", "pass x", 1))); err == nil {
+		t.Error("expected an error for a bad threshold")
+	}
+
+	p.Thresholds = []float64{1}
+	if _, err := p.Run(); err == nil {
+		t.Error("expected an error for a schedule that does not match the passes")
+	}
+}
@@ -0,0 +1,161 @@
+package wordvec
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+)
+
+const (
+	// PPMI_SMOOTHING is the context distribution smoothing exponent, the power InitUnigramTable raises counts to.
+	PPMI_SMOOTHING float64 = 0.75
+	// SVD_OVERSAMPLE is the number of random directions sampled beyond Layer1VecSize by the randomized SVD.
+	SVD_OVERSAMPLE int = 10
+	// SVD_POWER_ITER is the number of power iterations of the randomized SVD.
+	SVD_POWER_ITER int = 2
+	// SVD_EIGENVALUE_WEIGHT is the exponent p of the singular values in the word vectors U * S^p.
+	SVD_EIGENVALUE_WEIGHT float64 = 0.5
+)
+
+/*
+PPMIModel is the count-based baseline of Levy, Goldberg and Dagan (https://aclanthology.org/Q15-1016): the words of the vocabulary are described by their shifted positive pointwise mutual information with their contexts, and the PPMI matrix is reduced to Layer1VecSize dimensions with a truncated SVD. Like GloVeModel it shares the corpus and vocabulary code of word2vec: Model holds the options, builds the vocabulary like TrainModel and writes the vectors with SaveOutput.
+
+Co-occurrences are counted within WindowSkipLen words. The context counts are raised to Smoothing before normalizing, as the unigram table of negative sampling does, which keeps rare contexts from dominating:
+
+	PMI(w, c) = log(#(w,c) * sum_c' #(c')^Smoothing / (#(w) * #(c)^Smoothing))
+	SPPMI(w, c) = max(PMI(w, c) - log(Shift), 0)
+
+Shift plays the role of the number of negative samples of SGNS. The word vectors are U * S^EigenvalueWeight of the SVD U S Vt of the SPPMI matrix, computed with a randomized SVD in pure Go over NumThreads goroutines.
+*/
+type PPMIModel struct {
+	Model            *VectorModel
+	Smoothing        float64            // context distribution smoothing, default PPMI_SMOOTHING
+	Shift            float64            // k of the shifted PPMI, default 1 (no shift)
+	Oversample       int                // extra random directions of the SVD, default SVD_OVERSAMPLE
+	PowerIterations  int                // power iterations of the SVD, default SVD_POWER_ITER
+	EigenvalueWeight float64            // exponent of the singular values in the vectors, default SVD_EIGENVALUE_WEIGHT
+	Cooccurrence     CooccurrenceConfig // counting, Window defaults to WindowSkipLen
+	SingularValues   []float64          // the Layer1VecSize largest singular values of the SPPMI matrix, set by Train
+}
+
+// NewPPMIModel creates a PPMIModel with the model params of NewWord2VecModel. Subwords and paragraph vectors have no counterpart and are errors.
+func NewPPMIModel(trainFile, outFile string, modelParams ...ModelParams) (*PPMIModel, error) {
+	model, err := NewWord2VecModel(trainFile, outFile, modelParams...)
+	if err != nil {
+		return &PPMIModel{}, err
+	}
+	if model.Buckets > 0 || model.ParagraphVectors {
+		return &PPMIModel{}, errors.New("PPMI does not support subwords or paragraph vectors")
+	}
+	return &PPMIModel{
+		Model:            model,
+		Smoothing:        PPMI_SMOOTHING,
+		Shift:            1,
+		Oversample:       SVD_OVERSAMPLE,
+		PowerIterations:  SVD_POWER_ITER,
+		EigenvalueWeight: SVD_EIGENVALUE_WEIGHT,
+	}, nil
+}
+
+/*
+ppmiMatrix reads the co-occurrence counts, sorted by word as CountCooccurrences writes them, and returns the positive entries of the shifted PPMI matrix with words as rows and contexts as columns.
+*/
+func (p *PPMIModel) ppmiMatrix(counts *CooccurrenceFile) (*sparseMatrix, error) {
+	n := p.Model.VocabSize
+	wordCounts := make([]float64, n)
+	contextCounts := make([]float64, n)
+	err := counts.Each(func(r CooccurrenceRecord) error {
+		wordCounts[r.Word] += r.Count
+		contextCounts[r.Context] += r.Count
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var smoothedTotal float64
+	for c, count := range contextCounts {
+		contextCounts[c] = math.Pow(count, p.Smoothing)
+		smoothedTotal += contextCounts[c]
+	}
+	if smoothedTotal == 0 {
+		return nil, errors.New("Training file has no co-occurrences")
+	}
+	logShift := math.Log(p.Shift)
+	m := &sparseMatrix{rows: n, cols: n, indptr: make([]int, n+1)}
+	row := 0
+	err = counts.Each(func(r CooccurrenceRecord) error {
+		for ; row < int(r.Word); row++ {
+			m.indptr[row+1] = len(m.indices)
+		}
+		pmi := math.Log(r.Count*smoothedTotal/(wordCounts[r.Word]*contextCounts[r.Context])) - logShift
+		if pmi > 0 {
+			m.indices = append(m.indices, r.Context)
+			m.values = append(m.values, pmi)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for ; row < n; row++ {
+		m.indptr[row+1] = len(m.indices)
+	}
+	return m, nil
+}
+
+/*
+Train builds (or reads) the vocabulary, counts the co-occurrences, computes the SPPMI matrix and its truncated SVD and writes the vectors to OutputFile with SaveOutput, text, binary or k-means classes as for word2vec. Words without positive PMI with any context, such as "</s>", get zero vectors.
+*/
+func (p *PPMIModel) Train() error {
+	v := p.Model
+	if err := v.Validate(); err != nil {
+		return err
+	}
+	if p.Smoothing <= 0 || p.Smoothing > 1 || p.Shift < 1 || p.Oversample < 0 || p.PowerIterations < 0 {
+		return fmt.Errorf("PPMI needs 0 < Smoothing <= 1, Shift >= 1 and no negative Oversample or PowerIterations, got %g, %g, %d and %d", p.Smoothing, p.Shift, p.Oversample, p.PowerIterations)
+	}
+	if v.TrainFile == "" {
+		return errors.New("No training file specified")
+	}
+	if v.OutputFile == "" {
+		return errors.New("No output file specified")
+	}
+	fmt.Fprintf(os.Stdout, "Starting PPMI-SVD training using file %s\n", v.TrainFile)
+	start := time.Now()
+	if err := v.prepareVocab(); err != nil {
+		return err
+	}
+	if v.Layer1VecSize > v.VocabSize {
+		return fmt.Errorf("Layer1VecSize %d is larger than the vocabulary of %d words", v.Layer1VecSize, v.VocabSize)
+	}
+	counts, err := v.CountCooccurrences(p.Cooccurrence)
+	if err != nil {
+		return err
+	}
+	m, err := p.ppmiMatrix(counts)
+	counts.Remove()
+	if err != nil {
+		return err
+	}
+	if v.DebugMode > 0 {
+		fmt.Fprintf(os.Stdout, "SPPMI matrix: %d x %d, %d nonzeros\n", m.rows, m.cols, len(m.values))
+	}
+
+	sigma, u := randomizedSVD(m, v.Layer1VecSize, p.Oversample, p.PowerIterations, rand.New(rand.NewSource(int64(v.NextRandom))), v.NumThreads)
+	p.SingularValues = sigma
+	v.Syn0 = make([]float64, v.VocabSize*v.Layer1VecSize)
+	for c, s := range sigma {
+		scale := math.Pow(s, p.EigenvalueWeight)
+		for a := 0; a < v.VocabSize; a++ {
+			v.Syn0[a*v.Layer1VecSize+c] = u.data[a*u.cols+c] * scale
+		}
+	}
+	v.TrainingTime = time.Since(start)
+	if v.DebugMode > 0 {
+		fmt.Fprintf(os.Stdout, "Factorized in %v, largest singular value %f\n", v.TrainingTime, sigma[0])
+	}
+	return v.SaveOutput()
+}
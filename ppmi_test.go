@@ -0,0 +1,73 @@
+package wordvec
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestPPMIMatrix(t *testing.T) {
+	mv := newCooccurrenceModel(t, "a b\na c\nb c\n")
+	p := &PPMIModel{Model: mv, Smoothing: 1, Shift: 1}
+	counts, err := mv.CountCooccurrences(CooccurrenceConfig{Window: 1, TempDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer counts.Remove()
+	m, err := p.ppmiMatrix(counts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every word co-occurs once with each of the two others: PMI = log(1 * 6 / (2 * 2))
+	want := math.Log(1.5)
+	if len(m.values) != 6 {
+		t.Fatalf("expected 6 positive entries, got %d", len(m.values))
+	}
+	for _, x := range m.values {
+		if math.Abs(x-want) > 1e-12 {
+			t.Errorf("expected PMI %v, got %v", want, x)
+		}
+	}
+
+	// a shift of 2 removes every entry
+	p.Shift = 2
+	if m, _ = p.ppmiMatrix(counts); len(m.values) != 0 {
+		t.Errorf("expected the shift to remove PMI below log 2, got %v", m.values)
+	}
+}
+
+func TestPPMITrain(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "vectors.txt")
+	p, err := NewPPMIModel(testFileForTraining, out,
+		VocabHashSizeOption(5000),
+		MinCountOption(1),
+		Layer1VecSizeOption(10),
+		DebugModeOption(0),
+		NumThreadsOption(2),
+		WindowSkipLenOption(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Cooccurrence.TempDir = t.TempDir()
+	if err = p.Train(); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.SingularValues) != 10 || p.SingularValues[0] < p.SingularValues[9] || p.SingularValues[9] <= 0 {
+		t.Errorf("expected 10 descending positive singular values, got %v", p.SingularValues)
+	}
+	e := readTextEmbeddings(t, out)
+	if e.Len() != p.Model.VocabSize || e.Dim() != 10 {
+		t.Fatalf("expected %d vectors of size 10, got %d of size %d", p.Model.VocabSize, e.Len(), e.Dim())
+	}
+	same, _ := e.Similarity("horse", "dog")
+	other, _ := e.Similarity("horse", "bread")
+	if same <= other {
+		t.Errorf("horse should be closer to dog than to bread, got %f and %f", same, other)
+	}
+
+	p.Shift = 0
+	if err = p.Train(); err == nil {
+		t.Error("expected an error for a shift below 1")
+	}
+}
@@ -0,0 +1,491 @@
+package wordvec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"sync"
+)
+
+const (
+	// PQ_CENTROIDS is the number of centroids per sub-space, so that a code fits in a byte.
+	PQ_CENTROIDS int = 256
+	// PQ_TRAIN_SAMPLE is the most rows the codebooks are trained on; larger vocabularies are sampled.
+	PQ_TRAIN_SAMPLE int = 65536
+	// PQ_FORMAT_MAGIC starts every compressed model file written by (*QuantizedEmbeddings).Save.
+	PQ_FORMAT_MAGIC string = "WORDVPQ\x00"
+	// PQ_FORMAT_VERSION is the version of the compressed model file format.
+	PQ_FORMAT_VERSION uint32 = 1
+)
+
+/*
+QuantizedEmbeddings is a set of word vectors compressed with product quantization (Jégou et al., https://hal.inria.fr/inria-00514462). Each normalized vector is split into M sub-vectors of Dim/M values and every sub-vector is replaced by the index of its nearest centroid in a codebook of up to PQ_CENTROIDS centroids trained with k-means, so a word takes M bytes. A 300-dim float32 vector of 1200 bytes compressed with M = 20 or M = 40 is 60 or 30 times smaller.
+
+Similarity queries use asymmetric distance computation: the query is not quantized, its dot products with all centroids are computed once per query and the score of a word is the sum of M table lookups. Vector returns the reconstruction from the centroids.
+*/
+type QuantizedEmbeddings struct {
+	words     []string
+	index     map[string]int
+	dim       int
+	m         int
+	subDim    int
+	ks        int
+	centroids []float32 // M codebooks of ks rows of subDim values
+	codes     []byte    // M codes per word
+	normsOnce sync.Once
+	norms     []float32
+}
+
+/*
+QuantizationReport describes how well a QuantizedEmbeddings reconstructs the vectors it was trained on. Errors are measured on the normalized vectors, so MeanSquaredError is also the relative error: 0 is perfect, 1 is as bad as replacing every vector by zero.
+*/
+type QuantizationReport struct {
+	Words            int     `json:"words"`
+	Dim              int     `json:"dim"`
+	SubQuantizers    int     `json:"sub_quantizers"`
+	Centroids        int     `json:"centroids"`
+	BytesPerVector   int     `json:"bytes_per_vector"`
+	CompressionRatio float64 `json:"compression_ratio"` // against float32 vectors, codebooks included
+	MeanSquaredError float64 `json:"mean_squared_error"`
+	MaxSquaredError  float64 `json:"max_squared_error"`
+	MeanCosine       float64 `json:"mean_cosine"` // between each vector and its reconstruction
+}
+
+// Quantize compresses the trained word vectors (Syn0) into m bytes per word, see QuantizeEmbeddings.
+func (v *VectorModel) Quantize(m, iter int) (*QuantizedEmbeddings, error) {
+	e, err := v.Embeddings()
+	if err != nil {
+		return nil, err
+	}
+	return QuantizeEmbeddings(e, m, iter)
+}
+
+/*
+QuantizeEmbeddings trains M codebooks with iter rounds of k-means on the normalized vectors of e (at most PQ_TRAIN_SAMPLE of them) and encodes every word. Dim must be a multiple of m. The sub-spaces are trained in parallel.
+*/
+func QuantizeEmbeddings(e *Embeddings, m, iter int) (*QuantizedEmbeddings, error) {
+	if m < 1 || e.Dim()%m != 0 {
+		return nil, fmt.Errorf("Number of sub-quantizers must divide the dimension %d, got %d", e.Dim(), m)
+	}
+	if iter < 1 {
+		return nil, fmt.Errorf("K-means iterations must be at least 1, got %d", iter)
+	}
+	if e.Len() == 0 {
+		return nil, errors.New("No word vectors to quantize")
+	}
+	matrix := make([]float32, len(e.vectors))
+	copy(matrix, e.vectors)
+	NormalizeRows(matrix, e.dim)
+
+	q := &QuantizedEmbeddings{
+		words:  e.words,
+		index:  e.index,
+		dim:    e.dim,
+		m:      m,
+		subDim: e.dim / m,
+		ks:     PQ_CENTROIDS,
+	}
+	if q.ks > e.Len() {
+		q.ks = e.Len()
+	}
+	rnd := rand.New(rand.NewSource(1))
+	sample := rnd.Perm(e.Len())
+	if len(sample) > PQ_TRAIN_SAMPLE {
+		sample = sample[:PQ_TRAIN_SAMPLE]
+	}
+	q.centroids = make([]float32, m*q.ks*q.subDim)
+	var wg sync.WaitGroup
+	work := make(chan int)
+	for t := 0; t < runtime.GOMAXPROCS(0); t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sub := range work {
+				q.trainCodebook(matrix, sample, sub, iter, int64(sub)+1)
+			}
+		}()
+	}
+	for sub := 0; sub < m; sub++ {
+		work <- sub
+	}
+	close(work)
+	wg.Wait()
+
+	q.codes = make([]byte, e.Len()*m)
+	for i := 0; i < e.Len(); i++ {
+		q.encode(matrix[i*q.dim:(i+1)*q.dim], q.codes[i*m:(i+1)*m])
+	}
+	return q, nil
+}
+
+// codebook returns the centroids of sub-space sub.
+func (q *QuantizedEmbeddings) codebook(sub int) []float32 {
+	size := q.ks * q.subDim
+	return q.centroids[sub*size : (sub+1)*size]
+}
+
+// nearestCentroid returns the centroid of a codebook closest to x in squared euclidean distance.
+func nearestCentroid(codebook, x []float32) (int, float32) {
+	best, bestDist := 0, float32(math.MaxFloat32)
+	d := len(x)
+	for c := 0; c*d < len(codebook); c++ {
+		var dist float32
+		for j, xv := range x {
+			diff := xv - codebook[c*d+j]
+			dist += diff * diff
+		}
+		if dist < bestDist {
+			best, bestDist = c, dist
+		}
+	}
+	return best, bestDist
+}
+
+// trainCodebook runs k-means on the sub-vectors of the sample rows for one sub-space. Empty clusters are restarted on a random sample row.
+func (q *QuantizedEmbeddings) trainCodebook(matrix []float32, sample []int, sub, iter int, seed int64) {
+	rnd := rand.New(rand.NewSource(seed))
+	d := q.subDim
+	cb := q.codebook(sub)
+	subVec := func(row int) []float32 {
+		start := row*q.dim + sub*d
+		return matrix[start : start+d]
+	}
+	for c, r := range rnd.Perm(len(sample))[:q.ks] {
+		copy(cb[c*d:(c+1)*d], subVec(sample[r]))
+	}
+	sums := make([]float64, q.ks*d)
+	counts := make([]int, q.ks)
+	for it := 0; it < iter; it++ {
+		for i := range sums {
+			sums[i] = 0
+		}
+		for i := range counts {
+			counts[i] = 0
+		}
+		for _, row := range sample {
+			x := subVec(row)
+			c, _ := nearestCentroid(cb, x)
+			counts[c]++
+			for j, xv := range x {
+				sums[c*d+j] += float64(xv)
+			}
+		}
+		for c := 0; c < q.ks; c++ {
+			if counts[c] == 0 {
+				copy(cb[c*d:(c+1)*d], subVec(sample[rnd.Intn(len(sample))]))
+				continue
+			}
+			for j := 0; j < d; j++ {
+				cb[c*d+j] = float32(sums[c*d+j] / float64(counts[c]))
+			}
+		}
+	}
+}
+
+// encode writes the codes of a normalized vector.
+func (q *QuantizedEmbeddings) encode(vec []float32, codes []byte) {
+	for sub := range codes {
+		c, _ := nearestCentroid(q.codebook(sub), vec[sub*q.subDim:(sub+1)*q.subDim])
+		codes[sub] = byte(c)
+	}
+}
+
+// Len returns the number of words.
+func (q *QuantizedEmbeddings) Len() int { return len(q.words) }
+
+// Dim returns the size of the vectors.
+func (q *QuantizedEmbeddings) Dim() int { return q.dim }
+
+// SubQuantizers returns M, the number of sub-spaces and of code bytes per word.
+func (q *QuantizedEmbeddings) SubQuantizers() int { return q.m }
+
+// Word returns the i-th word.
+func (q *QuantizedEmbeddings) Word(i int) string { return q.words[i] }
+
+// Words returns all words in row order. The slice must not be modified.
+func (q *QuantizedEmbeddings) Words() []string { return q.words }
+
+// Index returns the row of word.
+func (q *QuantizedEmbeddings) Index(word string) (int, bool) {
+	i, ok := q.index[word]
+	return i, ok
+}
+
+// Codes returns the M code bytes of word i. They must not be modified.
+func (q *QuantizedEmbeddings) Codes(i int) []byte { return q.codes[i*q.m : (i+1)*q.m] }
+
+// row reconstructs the vector of word i into buf.
+func (q *QuantizedEmbeddings) row(i int, buf []float32) []float32 {
+	buf = buf[:q.dim]
+	for sub, c := range q.Codes(i) {
+		cb := q.codebook(sub)
+		copy(buf[sub*q.subDim:(sub+1)*q.subDim], cb[int(c)*q.subDim:(int(c)+1)*q.subDim])
+	}
+	return buf
+}
+
+func (q *QuantizedEmbeddings) norm(i int) float32 {
+	q.normsOnce.Do(func() { q.norms = rowNorms(q) })
+	return q.norms[i]
+}
+
+// Vector returns the reconstructed vector of word, or false if the word is unknown.
+func (q *QuantizedEmbeddings) Vector(word string) ([]float32, bool) {
+	return vectorOf(q, word)
+}
+
+// Similarity returns the cosine similarity of the reconstructed vectors of two words.
+func (q *QuantizedEmbeddings) Similarity(a, b string) (float64, error) {
+	return similarity(q, a, b)
+}
+
+// MostSimilar returns the n words most similar to word, the word itself excluded. The query is the reconstructed vector of word.
+func (q *QuantizedEmbeddings) MostSimilar(word string, n int) ([]Neighbor, error) {
+	i, ok := q.Index(word)
+	if !ok {
+		return nil, fmt.Errorf("Out of dictionary word: %s", word)
+	}
+	return q.MostSimilarVector(q.row(i, make([]float32, q.dim)), n, word), nil
+}
+
+/*
+MostSimilarVector returns the n words most similar to vec by asymmetric distance computation, skipping the words in exclude. Similarities are dot products of the normalized query with the reconstructed vectors, which approximate cosine similarities.
+*/
+func (q *QuantizedEmbeddings) MostSimilarVector(vec []float32, n int, exclude ...string) []Neighbor {
+	if n <= 0 || len(vec) != q.dim {
+		return nil
+	}
+	query := make([]float32, q.dim)
+	copy(query, vec)
+	NormalizeRows(query, q.dim)
+	table := q.distanceTable(query)
+	skip := make(map[int]bool, len(exclude))
+	for _, w := range exclude {
+		if i, ok := q.Index(w); ok {
+			skip[i] = true
+		}
+	}
+	heap := make(hitHeap, 0, n)
+	for i := 0; i < q.Len(); i++ {
+		if skip[i] {
+			continue
+		}
+		var score float32
+		for sub, c := range q.codes[i*q.m : (i+1)*q.m] {
+			score += table[sub*q.ks+int(c)]
+		}
+		heap.offer(Hit{Index: i, Score: score}, n)
+	}
+	hits := heap.sorted()
+	nn := make([]Neighbor, len(hits))
+	for i, h := range hits {
+		nn[i] = Neighbor{Word: q.words[h.Index], Similarity: float64(h.Score)}
+	}
+	return nn
+}
+
+// distanceTable holds the dot product of each sub-vector of the query with every centroid of its codebook.
+func (q *QuantizedEmbeddings) distanceTable(query []float32) []float32 {
+	table := make([]float32, q.m*q.ks)
+	for sub := 0; sub < q.m; sub++ {
+		x := query[sub*q.subDim : (sub+1)*q.subDim]
+		cb := q.codebook(sub)
+		for c := 0; c < q.ks; c++ {
+			table[sub*q.ks+c] = dot32(x, cb[c*q.subDim:(c+1)*q.subDim])
+		}
+	}
+	return table
+}
+
+// ReconstructionReport compares the quantized vectors with the original vectors e, matched by word.
+func (q *QuantizedEmbeddings) ReconstructionReport(e *Embeddings) (*QuantizationReport, error) {
+	if e.Dim() != q.dim {
+		return nil, fmt.Errorf("Expected vectors of dimension %d, got %d", q.dim, e.Dim())
+	}
+	report := &QuantizationReport{
+		Dim:            q.dim,
+		SubQuantizers:  q.m,
+		Centroids:      q.ks,
+		BytesPerVector: q.m,
+	}
+	orig := make([]float32, q.dim)
+	rec := make([]float32, q.dim)
+	for i, word := range q.words {
+		j, ok := e.Index(word)
+		if !ok {
+			continue
+		}
+		copy(orig, e.row(j, nil))
+		NormalizeRows(orig, q.dim)
+		q.row(i, rec)
+		var sq, dot, norm float64
+		for d := range orig {
+			diff := float64(orig[d] - rec[d])
+			sq += diff * diff
+			dot += float64(orig[d]) * float64(rec[d])
+			norm += float64(rec[d]) * float64(rec[d])
+		}
+		report.Words++
+		report.MeanSquaredError += sq
+		if sq > report.MaxSquaredError {
+			report.MaxSquaredError = sq
+		}
+		if norm > 0 {
+			report.MeanCosine += dot / math.Sqrt(norm)
+		}
+	}
+	if report.Words == 0 {
+		return nil, errors.New("No words in common with the quantized vectors")
+	}
+	report.MeanSquaredError /= float64(report.Words)
+	report.MeanCosine /= float64(report.Words)
+	original := float64(4 * q.Len() * q.dim)
+	report.CompressionRatio = original / float64(len(q.codes)+4*len(q.centroids))
+	return report, nil
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *QuantizationReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+/*
+Save writes the compressed model in little endian, followed by a CRC-32 (IEEE) of every byte before it:
+
+	magic     [8]byte  "WORDVPQ\x00"
+	version   uint32
+	words     uint32
+	dim       uint32
+	m         uint32
+	centroids uint32   per codebook
+	codebooks m*centroids*(dim/m) float32
+	vocab     per word a uint32 length and the word bytes
+	codes     words*m bytes
+	checksum  uint32
+*/
+func (q *QuantizedEmbeddings) Save(w io.Writer) error {
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+	var b [4]byte
+	put := func(x uint32) {
+		binary.LittleEndian.PutUint32(b[:], x)
+		bw.Write(b[:])
+	}
+	bw.WriteString(PQ_FORMAT_MAGIC)
+	put(PQ_FORMAT_VERSION)
+	put(uint32(q.Len()))
+	put(uint32(q.dim))
+	put(uint32(q.m))
+	put(uint32(q.ks))
+	for _, f := range q.centroids {
+		put(math.Float32bits(f))
+	}
+	for _, word := range q.words {
+		put(uint32(len(word)))
+		bw.WriteString(word)
+	}
+	bw.Write(q.codes)
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(b[:], crc.Sum32())
+	_, err := w.Write(b[:])
+	return err
+}
+
+// SaveFile writes the compressed model to a file, see Save.
+func (q *QuantizedEmbeddings) SaveFile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err = q.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadQuantizedEmbeddings reads a compressed model written by Save and verifies its checksum.
+func LoadQuantizedEmbeddings(r io.Reader) (*QuantizedEmbeddings, error) {
+	crc := crc32.NewIEEE()
+	br := bufio.NewReader(r)
+	tr := io.TeeReader(br, crc)
+	magic := make([]byte, len(PQ_FORMAT_MAGIC))
+	if _, err := io.ReadFull(tr, magic); err != nil || string(magic) != PQ_FORMAT_MAGIC {
+		return nil, errors.New("Not a quantized embeddings file")
+	}
+	header := make([]uint32, 5)
+	if err := binary.Read(tr, binary.LittleEndian, header); err != nil {
+		return nil, fmt.Errorf("reading quantized embeddings header: %v", err)
+	}
+	if header[0] > PQ_FORMAT_VERSION {
+		return nil, fmt.Errorf("Quantized embeddings format version %d is newer than the supported version %d", header[0], PQ_FORMAT_VERSION)
+	}
+	n, dim, m, ks := int(header[1]), int(header[2]), int(header[3]), int(header[4])
+	if dim < 1 || m < 1 || dim%m != 0 || ks < 1 || ks > PQ_CENTROIDS {
+		return nil, fmt.Errorf("bad quantized embeddings header: dim %d, m %d, centroids %d", dim, m, ks)
+	}
+	q := &QuantizedEmbeddings{dim: dim, m: m, subDim: dim / m, ks: ks}
+	q.centroids = make([]float32, m*ks*q.subDim)
+	if err := binary.Read(tr, binary.LittleEndian, q.centroids); err != nil {
+		return nil, fmt.Errorf("reading quantized embeddings codebooks: %v", err)
+	}
+	q.words = make([]string, 0, n)
+	q.index = make(map[string]int, n)
+	var size uint32
+	for i := 0; i < n; i++ {
+		if err := binary.Read(tr, binary.LittleEndian, &size); err != nil {
+			return nil, fmt.Errorf("quantized embeddings file is truncated after %d words", i)
+		}
+		if size > 1<<20 {
+			return nil, fmt.Errorf("bad word length %d in quantized embeddings file", size)
+		}
+		word := make([]byte, size)
+		if _, err := io.ReadFull(tr, word); err != nil {
+			return nil, fmt.Errorf("quantized embeddings file is truncated after %d words", i)
+		}
+		q.words = append(q.words, string(word))
+		if _, ok := q.index[q.words[i]]; !ok {
+			q.index[q.words[i]] = i
+		}
+	}
+	q.codes = make([]byte, n*m)
+	if _, err := io.ReadFull(tr, q.codes); err != nil {
+		return nil, errors.New("quantized embeddings file is truncated in the codes")
+	}
+	for _, c := range q.codes {
+		if int(c) >= ks {
+			return nil, fmt.Errorf("bad code %d in quantized embeddings file with %d centroids", c, ks)
+		}
+	}
+	want := crc.Sum32()
+	var sum uint32
+	if err := binary.Read(br, binary.LittleEndian, &sum); err != nil {
+		return nil, errors.New("quantized embeddings file is missing its checksum")
+	}
+	if sum != want {
+		return nil, errors.New("Quantized embeddings checksum mismatch")
+	}
+	return q, nil
+}
+
+// LoadQuantizedEmbeddingsFile reads a compressed model from a file, see LoadQuantizedEmbeddings.
+func LoadQuantizedEmbeddingsFile(name string) (*QuantizedEmbeddings, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadQuantizedEmbeddings(f)
+}
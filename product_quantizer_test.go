@@ -0,0 +1,155 @@
+package wordvec
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// clusteredEmbeddings returns n words around a few random centres, which product quantization reconstructs well.
+func clusteredEmbeddings(t *testing.T, n, dim, clusters int) *Embeddings {
+	rnd := rand.New(rand.NewSource(7))
+	centres := make([]float32, clusters*dim)
+	for i := range centres {
+		centres[i] = float32(rnd.NormFloat64())
+	}
+	words := make([]string, n)
+	vectors := make([]float32, n*dim)
+	for i := range words {
+		words[i] = fmt.Sprintf("w%d", i)
+		c := i % clusters
+		for d := 0; d < dim; d++ {
+			vectors[i*dim+d] = centres[c*dim+d] + 0.05*float32(rnd.NormFloat64())
+		}
+	}
+	e, err := NewEmbeddings(words, dim, vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestQuantizeEmbeddings(t *testing.T) {
+	e := clusteredEmbeddings(t, 2000, 60, 40)
+	q, err := QuantizeEmbeddings(e, 10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Len() != 2000 || q.Dim() != 60 || q.SubQuantizers() != 10 || len(q.Codes(0)) != 10 {
+		t.Fatalf("unexpected shape: %d words, dim %d, m %d", q.Len(), q.Dim(), q.SubQuantizers())
+	}
+	report, err := q.ReconstructionReport(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Words != 2000 || report.BytesPerVector != 10 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if report.MeanSquaredError > 0.05 || report.MeanCosine < 0.95 {
+		t.Errorf("reconstruction is too poor: %+v", report)
+	}
+	// 240 bytes per vector become 10, but the codebooks are not negligible for 2000 words
+	if want := float64(2000*240) / float64(2000*10+10*256*6*4); report.CompressionRatio != want {
+		t.Errorf("expected a compression ratio of %.2f, got %.2f", want, report.CompressionRatio)
+	}
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil || !bytes.Contains(buf.Bytes(), []byte(`"mean_squared_error"`)) {
+		t.Errorf("unexpected JSON report %q (%v)", buf.String(), err)
+	}
+
+	// the nearest neighbours by ADC should mostly be in the same cluster
+	nn, err := q.MostSimilar("w0", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	same := 0
+	for _, n := range nn {
+		if n.Word == "w0" {
+			t.Fatal("MostSimilar returned the word itself")
+		}
+		var i int
+		fmt.Sscanf(n.Word, "w%d", &i)
+		if i%40 == 0 {
+			same++
+		}
+	}
+	if same < 9 {
+		t.Errorf("expected neighbours of w0 from its cluster, got %+v", nn)
+	}
+	if _, err := q.MostSimilar("unicorn", 3); err == nil {
+		t.Error("an unknown word should be an error")
+	}
+	if sim, err := q.Similarity("w0", "w40"); err != nil || sim < 0.9 {
+		t.Errorf("expected a high similarity within a cluster, got %v (%v)", sim, err)
+	}
+}
+
+func TestQuantizeErrors(t *testing.T) {
+	e := clusteredEmbeddings(t, 10, 6, 2)
+	if _, err := QuantizeEmbeddings(e, 4, 5); err == nil {
+		t.Error("expected an error when m does not divide the dimension")
+	}
+	if _, err := QuantizeEmbeddings(e, 3, 0); err == nil {
+		t.Error("expected an error for zero iterations")
+	}
+	// fewer words than centroids
+	q, err := QuantizeEmbeddings(e, 3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report, _ := q.ReconstructionReport(e); report.MeanSquaredError > 1e-6 {
+		t.Errorf("with a centroid per word the reconstruction should be exact, got %+v", report)
+	}
+}
+
+func TestQuantizedEmbeddingsSaveLoad(t *testing.T) {
+	e := clusteredEmbeddings(t, 500, 20, 10)
+	q, err := QuantizeEmbeddings(e, 5, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(t.TempDir(), "model.pq")
+	if err := q.SaveFile(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadQuantizedEmbeddingsFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := q.MostSimilar("w3", 5)
+	got, err := loaded.MostSimilar("w3", 5)
+	if err != nil || fmt.Sprint(want) != fmt.Sprint(got) {
+		t.Errorf("expected %v after loading, got %v (%v)", want, got, err)
+	}
+	wv, _ := q.Vector("w7")
+	gv, _ := loaded.Vector("w7")
+	if fmt.Sprint(wv) != fmt.Sprint(gv) {
+		t.Errorf("reconstruction changed after loading")
+	}
+
+	var buf bytes.Buffer
+	if err := q.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	data[len(data)-10] ^= 1
+	if _, err := LoadQuantizedEmbeddings(bytes.NewReader(data)); err == nil {
+		t.Error("expected a checksum error")
+	}
+	if _, err := LoadQuantizedEmbeddings(bytes.NewReader(data[:100])); err == nil {
+		t.Error("expected an error for a truncated file")
+	}
+}
+
+func TestVectorModelQuantize(t *testing.T) {
+	mv := trainSmallModel(t)
+	q, err := mv.Quantize(5, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Len() != mv.VocabSize || q.Dim() != mv.Layer1VecSize {
+		t.Errorf("expected %d words of size %d, got %d of size %d", mv.VocabSize, mv.Layer1VecSize, q.Len(), q.Dim())
+	}
+}
@@ -0,0 +1,103 @@
+package wordvec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// LearnVocabFromSentences builds the vocabulary from in-memory sentences like LearnVocabFromTrainFile does from TrainFile: every sentence ends with a "</s>" and words that occur less than MinCount times are discarded.
+func (v *VectorModel) LearnVocabFromSentences(sentences [][]string) error {
+	fmt.Fprintf(os.Stdout, "Learning Vocab from %d sentences\n", len(sentences))
+	if err := v.loadCatalog(); err != nil {
+		return err
+	}
+	v.resetVocabHashIndices()
+	v.VocabSize = 0
+	v.TrainWords = 0
+	v.addWordToVocab("</s>")
+
+	for _, sentence := range sentences {
+		for _, word := range sentence {
+			if word == "" {
+				continue
+			}
+			v.TrainWords++
+			i := v.SearchVocab(word)
+			if i == -1 {
+				a := v.addWordToVocab(word)
+				v.Vocab[a].Count = 1
+			} else {
+				v.Vocab[i].Count++
+			}
+			if float64(v.VocabSize) > (float64(v.VocabHashSize) * 0.7) {
+				v.reduceVocab()
+			}
+		}
+		v.Vocab[0].Count++
+		v.TrainWords++
+	}
+	v.sortVocab()
+	if v.DebugMode > 0 {
+		fmt.Fprintf(os.Stdout, "Vocab size: %d\n", v.VocabSize)
+		fmt.Fprintf(os.Stdout, "Words in sentences: %d\n", v.TrainWords)
+	}
+	v.FileSize = 0
+	return nil
+}
+
+/*
+TrainSentences trains the model like TrainModel on an in-memory corpus instead of TrainFile, for corpora that are generated rather than read, such as the random walks of the graph package. The vocabulary is always learned from the sentences (VocabInFile is not used) and saved to VocabOutFile when set. The sentences are split between the NumThreads goroutines; sentences longer than MaxSentenceLen are cut into pieces as in a training file.
+
+The vectors are written to OutputFile (and the report to ReportFile() when WriteReport is set) only when OutputFile is set; otherwise they stay in Syn0, see Embeddings. Paragraph vectors need the document tags of a training file and are an error.
+*/
+func (v *VectorModel) TrainSentences(sentences [][]string) (*TrainingReport, error) {
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+	if v.ParagraphVectors {
+		return nil, errors.New("Paragraph vectors need a training file with document tags")
+	}
+	fmt.Fprintf(os.Stdout, "Starting training on %d sentences\n", len(sentences))
+	if v.StartingAlpha == 0 {
+		v.StartingAlpha = v.Alpha
+	}
+	if err := v.LearnVocabFromSentences(sentences); err != nil {
+		return nil, err
+	}
+	if v.VocabOutFile != "" {
+		if err := v.SaveVocab(); err != nil {
+			return nil, err
+		}
+	}
+	if v.VocabSize < 2 {
+		return nil, fmt.Errorf("Vocabulary of %d words is too small to train; lower MinCount or use more sentences", v.VocabSize)
+	}
+
+	v.sentences = make([][]int, len(sentences))
+	for i, sentence := range sentences {
+		v.sentences[i] = make([]int, len(sentence))
+		for j, word := range sentence {
+			v.sentences[i][j] = v.SearchVocab(word)
+		}
+	}
+	defer func() { v.sentences = nil }()
+	report, err := v.trainEpochs(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	if v.OutputFile == "" {
+		return report, nil
+	}
+	if err := v.SaveOutput(); err != nil {
+		return report, err
+	}
+	if v.WriteReport {
+		if err := report.WriteJSONFile(v.ReportFile()); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
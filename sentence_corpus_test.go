@@ -0,0 +1,84 @@
+package wordvec
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readSentences(t *testing.T, name string) [][]string {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var sentences [][]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		sentences = append(sentences, strings.Fields(scanner.Text()))
+	}
+	return sentences
+}
+
+func TestLearnVocabFromSentences(t *testing.T) {
+	mv, err := NewWord2VecModel("", "", VocabHashSizeOption(1000), MinCountOption(2), DebugModeOption(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mv.LearnVocabFromSentences([][]string{{"a", "b", "a"}, {"a", "c", "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if mv.VocabSize != 3 || mv.Vocab[0].Word != "</s>" || mv.Vocab[0].Count != 2 || mv.Vocab[1].Word != "a" || mv.SearchVocab("c") != -1 {
+		t.Errorf("expected </s>, a and b, got %d words: %v", mv.VocabSize, mv.Vocab[:mv.VocabSize])
+	}
+	if mv.TrainWords != 7 {
+		t.Errorf("expected 7 training words with the sentence ends, got %d", mv.TrainWords)
+	}
+}
+
+func TestTrainSentences(t *testing.T) {
+	sentences := readSentences(t, testFileForTraining)
+	mv := newSmallTrainingModel(t, "", BagOfWordsFalse, IterOption(5), MaxSentenceLenOption(4))
+	report, err := mv.TrainSentences(sentences)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromFile := newSmallTrainingModel(t, "")
+	if err := fromFile.LearnVocabFromTrainFile(); err != nil {
+		t.Fatal(err)
+	}
+	if mv.VocabSize != fromFile.VocabSize || mv.TrainWords != fromFile.TrainWords {
+		t.Errorf("expected the vocabulary of the training file, got %d words and %d training words instead of %d and %d",
+			mv.VocabSize, mv.TrainWords, fromFile.VocabSize, fromFile.TrainWords)
+	}
+	losses := report.EpochLosses()
+	if losses[len(losses)-1] >= losses[0] {
+		t.Errorf("loss should go down during training, got %v", losses)
+	}
+	if report.WordsProcessed < 5*mv.TrainWords*9/10 {
+		t.Errorf("every epoch should go over all sentences, processed %d words of %d", report.WordsProcessed, 5*mv.TrainWords)
+	}
+	e, err := mv.Embeddings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	same, _ := e.Similarity("horse", "dog")
+	other, _ := e.Similarity("horse", "bread")
+	if same <= other {
+		t.Errorf("horse should be closer to dog than to bread, got %f and %f", same, other)
+	}
+
+	out := filepath.Join(t.TempDir(), "vectors.txt")
+	mv = newSmallTrainingModel(t, out, IterOption(1))
+	if _, err := mv.TrainSentences(sentences); err != nil {
+		t.Fatal(err)
+	}
+	if e := readTextEmbeddings(t, out); e.Len() != mv.VocabSize {
+		t.Errorf("expected %d written vectors, got %d", mv.VocabSize, e.Len())
+	}
+	if _, err := newSmallTrainingModel(t, "", ParagraphVectorsTrue).TrainSentences(sentences); err == nil {
+		t.Error("expected an error for paragraph vectors")
+	}
+}
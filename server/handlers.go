@@ -0,0 +1,296 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jbowles/wordvec"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type vocabResponse struct {
+	Words    int       `json:"words"`
+	Dim      int       `json:"dim"`
+	File     string    `json:"file"`
+	Format   string    `json:"format"`
+	Job      string    `json:"job,omitempty"`
+	LoadedAt time.Time `json:"loaded_at"`
+	Word     string    `json:"word,omitempty"`
+	Known    *bool     `json:"known,omitempty"`
+	Index    *int      `json:"index,omitempty"`
+}
+
+type vectorRequest struct {
+	Word string `json:"word"`
+}
+
+type vectorResponse struct {
+	Word   string    `json:"word"`
+	Vector []float32 `json:"vector"`
+}
+
+type mostSimilarRequest struct {
+	Word    string   `json:"word"`
+	Words   []string `json:"words"`
+	Exclude []string `json:"exclude"`
+	N       int      `json:"n"`
+}
+
+type analogyRequest struct {
+	A string `json:"a"`
+	B string `json:"b"`
+	C string `json:"c"`
+	N int    `json:"n"`
+}
+
+type neighborsResponse struct {
+	Neighbors []wordvec.Neighbor `json:"neighbors"`
+}
+
+type similarityRequest struct {
+	A string `json:"a"`
+	B string `json:"b"`
+}
+
+type similarityResponse struct {
+	Similarity float64 `json:"similarity"`
+}
+
+type oddOneOutRequest struct {
+	Words []string `json:"words"`
+}
+
+type oddOneOutResponse struct {
+	Word string `json:"word"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, errorResponse{Error: fmt.Sprintf(format, args...)})
+}
+
+// allowMethod answers 405 unless the request uses method.
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, "Method %s not allowed, use %s", r.Method, method)
+	return false
+}
+
+// decode reads the JSON body of a POST request into req, answering 405, 413 or 400 when it can't.
+func (s *Server) decode(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if !allowMethod(w, r, http.MethodPost) {
+		return false
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.cfg.MaxBodyBytes))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(req)
+	if err == nil && dec.Decode(&struct{}{}) != io.EOF {
+		err = errors.New("Request body must be a single JSON object")
+	}
+	if err == nil {
+		return true
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, "Request body larger than %d bytes", s.cfg.MaxBodyBytes)
+	} else {
+		writeError(w, http.StatusBadRequest, "Bad request body: %v", err)
+	}
+	return false
+}
+
+// results returns the number of neighbors a query asked for, DEFAULT_RESULTS up to MaxResults when it gives none, answering 400 when it is out of range.
+func (s *Server) results(w http.ResponseWriter, n int) (int, bool) {
+	if n == 0 {
+		n = DEFAULT_RESULTS
+		if n > s.cfg.MaxResults {
+			n = s.cfg.MaxResults
+		}
+	}
+	if n < 1 || n > s.cfg.MaxResults {
+		writeError(w, http.StatusBadRequest, "n must be between 1 and %d, got %d", s.cfg.MaxResults, n)
+		return 0, false
+	}
+	return n, true
+}
+
+// known answers 503 without vectors, 400 for a missing word and 404 for the first word that is not in the vocabulary.
+func known(w http.ResponseWriter, e *wordvec.Embeddings, words ...string) bool {
+	if e == nil {
+		writeError(w, http.StatusServiceUnavailable, "No model loaded yet")
+		return false
+	}
+	for _, word := range words {
+		if word == "" {
+			writeError(w, http.StatusBadRequest, "Missing word")
+			return false
+		}
+		if _, ok := e.Index(word); !ok {
+			writeError(w, http.StatusNotFound, "Out of dictionary word: %s", word)
+			return false
+		}
+	}
+	return true
+}
+
+func (m *model) vocab() vocabResponse {
+	return vocabResponse{
+		Words:    m.embeddings.Len(),
+		Dim:      m.embeddings.Dim(),
+		File:     m.file,
+		Format:   m.format,
+		Job:      m.job,
+		LoadedAt: m.loadedAt,
+	}
+}
+
+func (s *Server) handleVocab(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	m := s.model()
+	if m == nil {
+		writeError(w, http.StatusServiceUnavailable, "No model loaded yet")
+		return
+	}
+	resp := m.vocab()
+	if word := r.URL.Query().Get("word"); word != "" {
+		i, ok := m.embeddings.Index(word)
+		resp.Word, resp.Known = word, &ok
+		if ok {
+			resp.Index = &i
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleVector(w http.ResponseWriter, r *http.Request) {
+	var req vectorRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	e := s.Embeddings()
+	if !known(w, e, req.Word) {
+		return
+	}
+	vec, _ := e.Vector(req.Word)
+	writeJSON(w, http.StatusOK, vectorResponse{Word: req.Word, Vector: vec})
+}
+
+func (s *Server) handleMostSimilar(w http.ResponseWriter, r *http.Request) {
+	var req mostSimilarRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	words := req.Words
+	if req.Word != "" {
+		words = append([]string{req.Word}, words...)
+	}
+	if len(words) == 0 {
+		writeError(w, http.StatusBadRequest, "Missing word")
+		return
+	}
+	n, ok := s.results(w, req.N)
+	if !ok {
+		return
+	}
+	e := s.Embeddings()
+	if !known(w, e, words...) {
+		return
+	}
+	neighbors, err := e.RecommendSimilar(words, req.Exclude, n)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, neighborsResponse{Neighbors: nonNil(neighbors)})
+}
+
+func (s *Server) handleAnalogy(w http.ResponseWriter, r *http.Request) {
+	var req analogyRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	n, ok := s.results(w, req.N)
+	if !ok {
+		return
+	}
+	e := s.Embeddings()
+	if !known(w, e, req.A, req.B, req.C) {
+		return
+	}
+	neighbors, err := e.Analogy(req.A, req.B, req.C, n)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, neighborsResponse{Neighbors: nonNil(neighbors)})
+}
+
+func (s *Server) handleSimilarity(w http.ResponseWriter, r *http.Request) {
+	var req similarityRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	e := s.Embeddings()
+	if !known(w, e, req.A, req.B) {
+		return
+	}
+	sim, err := e.Similarity(req.A, req.B)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, similarityResponse{Similarity: sim})
+}
+
+func (s *Server) handleOddOneOut(w http.ResponseWriter, r *http.Request) {
+	var req oddOneOutRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	e := s.Embeddings()
+	if !known(w, e, req.Words...) {
+		return
+	}
+	odd, err := e.OddOneOut(req.Words)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, oddOneOutResponse{Word: odd})
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	if err := s.Reload(); err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.model().vocab())
+}
+
+// nonNil returns an empty list instead of nil so it encodes as [] rather than null.
+func nonNil(neighbors []wordvec.Neighbor) []wordvec.Neighbor {
+	if neighbors == nil {
+		return []wordvec.Neighbor{}
+	}
+	return neighbors
+}
@@ -0,0 +1,380 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jbowles/wordvec"
+)
+
+const (
+	// DEFAULT_WORKERS is the number of training jobs run at the same time.
+	DEFAULT_WORKERS int = 1
+	// DEFAULT_MAX_QUEUED_JOBS is the number of jobs that can wait for a worker.
+	DEFAULT_MAX_QUEUED_JOBS int = 16
+)
+
+// JobStatus is the state of a training job: queued until a worker is free, running, and then done, failed or canceled.
+type JobStatus string
+
+const (
+	JobQueued   JobStatus = "queued"
+	JobRunning  JobStatus = "running"
+	JobDone     JobStatus = "done"
+	JobFailed   JobStatus = "failed"
+	JobCanceled JobStatus = "canceled"
+)
+
+func (cfg Config) jobDefaults() (Config, error) {
+	if cfg.Workers == 0 {
+		cfg.Workers = DEFAULT_WORKERS
+	}
+	if cfg.MaxQueuedJobs == 0 {
+		cfg.MaxQueuedJobs = DEFAULT_MAX_QUEUED_JOBS
+	}
+	if cfg.JobThreads == 0 && cfg.Workers > 0 {
+		cfg.JobThreads = runtime.GOMAXPROCS(0) / cfg.Workers
+		if cfg.JobThreads < 1 {
+			cfg.JobThreads = 1
+		}
+	}
+	if cfg.Workers < 1 || cfg.MaxQueuedJobs < 1 || cfg.JobThreads < 1 {
+		return cfg, fmt.Errorf("Workers, MaxQueuedJobs and JobThreads must be at least 1, got %d, %d and %d", cfg.Workers, cfg.MaxQueuedJobs, cfg.JobThreads)
+	}
+	return cfg, nil
+}
+
+// job is a training job; its fields after params are guarded by the mutex of its jobQueue.
+type job struct {
+	id         string
+	trainFile  string
+	outputFile string
+	format     string
+	params     []wordvec.ModelParams
+	status     JobStatus
+	err        string
+	submitted  time.Time
+	started    time.Time
+	finished   time.Time
+	progress   *wordvec.TrainingProgress
+	report     *wordvec.TrainingReport
+	cancel     context.CancelFunc
+}
+
+type jobRequest struct {
+	TrainFile string                     `json:"train_file"`
+	Params    map[string]json.RawMessage `json:"params"`
+}
+
+type jobResponse struct {
+	ID         string                    `json:"id"`
+	Status     JobStatus                 `json:"status"`
+	TrainFile  string                    `json:"train_file"`
+	OutputFile string                    `json:"output_file"`
+	Error      string                    `json:"error,omitempty"`
+	Submitted  time.Time                 `json:"submitted"`
+	Started    *time.Time                `json:"started,omitempty"`
+	Finished   *time.Time                `json:"finished,omitempty"`
+	Progress   *wordvec.TrainingProgress `json:"progress,omitempty"`
+	Report     *wordvec.TrainingReport   `json:"report,omitempty"`
+}
+
+type jobsResponse struct {
+	Jobs []jobResponse `json:"jobs"`
+}
+
+// jobQueue runs the training jobs of a Server on Workers goroutines, queueing up to MaxQueuedJobs more.
+type jobQueue struct {
+	s      *Server
+	mu     sync.Mutex
+	jobs   map[string]*job
+	order  []*job
+	nextID int
+	closed bool
+	queue  chan *job
+	ctx    context.Context // cancelled by close, stops the workers and the running jobs
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newJobQueue(s *Server) *jobQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &jobQueue{
+		s:      s,
+		jobs:   make(map[string]*job),
+		queue:  make(chan *job, s.cfg.MaxQueuedJobs),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for i := 0; i < s.cfg.Workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+func (q *jobQueue) work() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case j := <-q.queue:
+			q.run(j)
+		}
+	}
+}
+
+// run trains a job and publishes its vectors, unless it was cancelled while queued.
+func (q *jobQueue) run(j *job) {
+	q.mu.Lock()
+	if j.status != JobQueued {
+		q.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(q.ctx)
+	defer cancel()
+	j.status, j.started, j.cancel = JobRunning, time.Now(), cancel
+	q.mu.Unlock()
+	fmt.Fprintf(os.Stdout, "Starting job %s on %s\n", j.id, j.trainFile)
+
+	e, report, err := q.train(ctx, j)
+	if err == nil {
+		q.s.publish(&model{embeddings: e, file: j.outputFile, format: j.format, job: j.id, loadedAt: time.Now()})
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j.finished = time.Now()
+	switch {
+	case err != nil && ctx.Err() != nil:
+		j.status = JobCanceled
+	case err != nil:
+		j.status, j.err = JobFailed, err.Error()
+		fmt.Fprintf(os.Stderr, "Job %s failed: %v\n", j.id, err)
+	default:
+		j.status, j.report = JobDone, report
+	}
+}
+
+// train trains the model of a job, recording its progress, and returns its vectors. A panic while setting up or training the model fails the job instead of the server.
+func (q *jobQueue) train(ctx context.Context, j *job) (e *wordvec.Embeddings, report *wordvec.TrainingReport, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, report, err = nil, nil, fmt.Errorf("Training panicked: %v", r)
+		}
+	}()
+	params := append(append([]wordvec.ModelParams{}, q.s.cfg.JobParams...), j.params...)
+	params = append(params, wordvec.ProgressOption(func(p wordvec.TrainingProgress) {
+		q.mu.Lock()
+		j.progress = &p
+		q.mu.Unlock()
+	}))
+	v, err := wordvec.NewWord2VecModel(j.trainFile, j.outputFile, params...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if v.NumThreads > q.s.cfg.JobThreads {
+		v.NumThreads = q.s.cfg.JobThreads
+	}
+	report, err = v.TrainModelContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	e, err = v.Embeddings()
+	if err != nil {
+		return nil, nil, err
+	}
+	return e, report, nil
+}
+
+// submit gives a job its ID and output file in JobDir and queues it.
+func (q *jobQueue) submit(j *job, binary bool) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return errors.New("Server is shutting down")
+	}
+	j.id = strconv.Itoa(q.nextID + 1)
+	ext := ".txt"
+	if binary {
+		ext = ".bin"
+	}
+	j.outputFile = filepath.Join(q.s.cfg.JobDir, "job-"+j.id+ext)
+	select {
+	case q.queue <- j:
+	default:
+		return errors.New("Too many queued jobs, try again later")
+	}
+	q.nextID++
+	q.jobs[j.id] = j
+	q.order = append(q.order, j)
+	return nil
+}
+
+// close cancels the queued and running jobs and waits for the workers to stop.
+func (q *jobQueue) close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	for _, j := range q.order {
+		if j.status == JobQueued {
+			j.status, j.finished = JobCanceled, time.Now()
+		}
+	}
+	q.mu.Unlock()
+	q.cancel()
+	q.wg.Wait()
+}
+
+// Close cancels the training jobs and waits for them to stop. The vectors being served stay; new jobs are refused.
+func (s *Server) Close() {
+	if s.jobs != nil {
+		s.jobs.close()
+	}
+}
+
+// response returns a snapshot of the job; the caller holds the mutex of the queue.
+func (j *job) response() jobResponse {
+	resp := jobResponse{
+		ID:         j.id,
+		Status:     j.status,
+		TrainFile:  j.trainFile,
+		OutputFile: j.outputFile,
+		Error:      j.err,
+		Submitted:  j.submitted,
+		Progress:   j.progress,
+		Report:     j.report,
+	}
+	if !j.started.IsZero() {
+		started := j.started
+		resp.Started = &started
+	}
+	if !j.finished.IsZero() {
+		finished := j.finished
+		resp.Finished = &finished
+	}
+	return resp
+}
+
+/*
+jobParams turns the params of a job request into ModelParams. The keys are config fields as in wordvec.LoadModelConfig, the values JSON strings, numbers or booleans. TrainFile and OutputFile are set by the job. It also reports whether Binaryf is set, which needs a .bin output file.
+*/
+func jobParams(raw map[string]json.RawMessage) ([]wordvec.ModelParams, bool, error) {
+	values := make(map[string]string, len(raw))
+	binary := false
+	for key, value := range raw {
+		if strings.EqualFold(key, "TrainFile") || strings.EqualFold(key, "OutputFile") {
+			return nil, false, fmt.Errorf("%s is set by the job", key)
+		}
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			s = strings.TrimSpace(string(value))
+		}
+		if strings.EqualFold(key, "Binaryf") {
+			binary, _ = strconv.ParseBool(s)
+		}
+		values[key] = s
+	}
+	params, err := wordvec.ModelConfigParams(values)
+	return params, binary, err
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		q := s.jobs
+		q.mu.Lock()
+		resp := jobsResponse{Jobs: make([]jobResponse, len(q.order))}
+		for i, j := range q.order {
+			resp.Jobs[i] = j.response()
+		}
+		q.mu.Unlock()
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	var req jobRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	if req.TrainFile == "" {
+		writeError(w, http.StatusBadRequest, "Missing train_file")
+		return
+	}
+	if _, err := os.Stat(req.TrainFile); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	params, binary, err := jobParams(req.Params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Bad params: %v", err)
+		return
+	}
+	j := &job{
+		trainFile: req.TrainFile,
+		format:    wordvec.FormatText.String(),
+		params:    params,
+		status:    JobQueued,
+		submitted: time.Now(),
+	}
+	if binary {
+		j.format = wordvec.FormatBinary.String()
+	}
+	if err := s.jobs.submit(j, binary); err != nil {
+		writeError(w, http.StatusServiceUnavailable, "%v", err)
+		return
+	}
+	s.jobs.mu.Lock()
+	resp := j.response()
+	s.jobs.mu.Unlock()
+	w.Header().Set("Location", "/jobs/"+j.id)
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		w.Header().Set("Allow", "GET, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "Method %s not allowed, use GET or DELETE", r.Method)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	// The response is written after unlocking, a slow client must not hold up the Progress of the running jobs
+	q := s.jobs
+	q.mu.Lock()
+	j, ok := q.jobs[id]
+	if !ok {
+		q.mu.Unlock()
+		writeError(w, http.StatusNotFound, "No job %q", id)
+		return
+	}
+	status := http.StatusOK
+	if r.Method == http.MethodDelete {
+		switch j.status {
+		case JobQueued:
+			j.status, j.finished = JobCanceled, time.Now()
+		case JobRunning:
+			j.cancel()
+			status = http.StatusAccepted
+		default:
+			jobStatus := j.status
+			q.mu.Unlock()
+			writeError(w, http.StatusConflict, "Job %s is already %s", id, jobStatus)
+			return
+		}
+	}
+	resp := j.response()
+	q.mu.Unlock()
+	writeJSON(w, status, resp)
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jbowles/wordvec"
+)
+
+const testCorpus = "../testdata/train_small.txt"
+
+func newJobServer(t *testing.T, cfg Config) (*Server, *httptest.Server) {
+	t.Helper()
+	cfg.JobDir = t.TempDir()
+	cfg.JobParams = append([]wordvec.ModelParams{
+		wordvec.VocabHashSizeOption(5000),
+		wordvec.MinCountOption(1),
+		wordvec.Layer1VecSizeOption(10),
+		wordvec.DebugModeOption(0),
+		func(v *wordvec.VectorModel) error { v.TableSize = 1e5; return nil },
+	}, cfg.JobParams...)
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(func() {
+		ts.Close()
+		s.Close()
+	})
+	return s, ts
+}
+
+func getJob(t *testing.T, ts *httptest.Server, id string) jobResponse {
+	t.Helper()
+	r, err := http.Get(ts.URL + "/jobs/" + id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	var resp jobResponse
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil || r.StatusCode != http.StatusOK {
+		t.Fatalf("GET /jobs/%s: status %d, %v", id, r.StatusCode, err)
+	}
+	return resp
+}
+
+// waitJob polls a job until done returns true for it.
+func waitJob(t *testing.T, ts *httptest.Server, id string, done func(jobResponse) bool) jobResponse {
+	t.Helper()
+	for deadline := time.Now().Add(30 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		j := getJob(t, ts, id)
+		if done(j) {
+			return j
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is still %s", id, j.Status)
+		}
+	}
+}
+
+func finished(j jobResponse) bool {
+	return j.Status == JobDone || j.Status == JobFailed || j.Status == JobCanceled
+}
+
+func deleteJob(t *testing.T, ts *httptest.Server, id string) int {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/jobs/"+id, nil)
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Body.Close()
+	return r.StatusCode
+}
+
+func TestJobPublishes(t *testing.T) {
+	s, ts := newJobServer(t, Config{})
+	var resp errorResponse
+	if status := post(t, ts, "/vector", `{"word": "the"}`, &resp); status != http.StatusServiceUnavailable {
+		t.Errorf("queries before the first job should be 503, got %d %+v", status, resp)
+	}
+
+	var j jobResponse
+	if status := post(t, ts, "/jobs", `{"train_file": "`+testCorpus+`", "params": {"Iter": 2, "Architecture": "skip-gram", "NumThreads": "2"}}`, &j); status != http.StatusCreated || j.ID != "1" || j.Status != JobQueued {
+		t.Fatalf("expected job 1 to be queued, got %d %+v", status, j)
+	}
+	j = waitJob(t, ts, j.ID, finished)
+	if j.Status != JobDone || j.Report == nil || len(j.Report.Epochs) != 2 || j.Progress == nil || j.Progress.Epoch != 2 || j.Progress.WordsPerSec <= 0 {
+		t.Fatalf("job should be done with a report and progress, got %+v", j)
+	}
+	if _, err := os.Stat(j.OutputFile); err != nil || filepath.Dir(j.OutputFile) != s.cfg.JobDir {
+		t.Errorf("the vectors should be written to the job directory: %v", err)
+	}
+
+	vocab := getVocab(t, ts, "?word=the")
+	if vocab.Job != "1" || vocab.Dim != 10 || vocab.Words != s.Embeddings().Len() || !*vocab.Known {
+		t.Errorf("the vectors of job 1 should be served, got %+v", vocab)
+	}
+	var nn neighborsResponse
+	if status := post(t, ts, "/most-similar", `{"word": "the", "n": 3}`, &nn); status != http.StatusOK || len(nn.Neighbors) != 3 {
+		t.Errorf("most similar on the published vectors: status %d, %+v", status, nn)
+	}
+
+	r, err := http.Get(ts.URL + "/jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	var list jobsResponse
+	if err := json.NewDecoder(r.Body).Decode(&list); err != nil || len(list.Jobs) != 1 || list.Jobs[0].ID != "1" {
+		t.Errorf("expected the list of one job, got %+v, %v", list, err)
+	}
+}
+
+func TestJobErrors(t *testing.T) {
+	_, ts := newJobServer(t, Config{})
+	for _, body := range []string{
+		`{"params": {"Iter": 1}}`,
+		`{"train_file": "../testdata/does_not_exist.txt"}`,
+		`{"train_file": "` + testCorpus + `", "params": {"Iter": "many"}}`,
+		`{"train_file": "` + testCorpus + `", "params": {"Layers": 2}}`,
+		`{"train_file": "` + testCorpus + `", "params": {"OutputFile": "/tmp/vectors.txt"}}`,
+	} {
+		var resp errorResponse
+		if status := post(t, ts, "/jobs", body, &resp); status != http.StatusBadRequest || resp.Error == "" {
+			t.Errorf("%s: expected 400 with an error, got %d %+v", body, status, resp)
+		}
+	}
+	if status := deleteJob(t, ts, "7"); status != http.StatusNotFound {
+		t.Errorf("an unknown job should be 404, got %d", status)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.txt")
+	os.WriteFile(empty, nil, 0644)
+	for _, body := range []string{
+		`{"train_file": "` + empty + `"}`,
+		`{"train_file": "` + testCorpus + `", "params": {"SoftMax": true, "MaxCodeLen": 2}}`,
+		`{"train_file": "` + testCorpus + `", "params": {"VocabHashSize": -1}}`,
+	} {
+		var j jobResponse
+		post(t, ts, "/jobs", body, &j)
+		if j = waitJob(t, ts, j.ID, finished); j.Status != JobFailed || j.Error == "" {
+			t.Errorf("%s: the job should fail with an error, got %+v", body, j)
+		}
+	}
+}
+
+func TestJobPanic(t *testing.T) {
+	panics := func(v *wordvec.VectorModel) error { panic("option exploded") }
+	_, ts := newJobServer(t, Config{JobParams: []wordvec.ModelParams{panics}})
+	var j jobResponse
+	post(t, ts, "/jobs", `{"train_file": "`+testCorpus+`"}`, &j)
+	if j = waitJob(t, ts, j.ID, finished); j.Status != JobFailed || !strings.Contains(j.Error, "option exploded") {
+		t.Errorf("a panicking job should fail with the panic, got %+v", j)
+	}
+	post(t, ts, "/jobs", `{"train_file": "`+testCorpus+`"}`, &j)
+	if j = waitJob(t, ts, j.ID, finished); j.Status != JobFailed {
+		t.Errorf("the worker should keep running jobs after a panic, got %+v", j)
+	}
+
+	// Without an ExpTable the training threads themselves panic
+	noExpTable := func(v *wordvec.VectorModel) error { v.ExpTable = nil; return nil }
+	_, ts = newJobServer(t, Config{JobParams: []wordvec.ModelParams{noExpTable}})
+	post(t, ts, "/jobs", `{"train_file": "`+testCorpus+`", "params": {"Iter": 1}}`, &j)
+	if j = waitJob(t, ts, j.ID, finished); j.Status != JobFailed || !strings.Contains(j.Error, "index out of range") {
+		t.Errorf("a job panicking in a training thread should fail with the panic, got %+v", j)
+	}
+}
+
+func TestJobCancel(t *testing.T) {
+	s, ts := newJobServer(t, Config{Workers: 1, MaxQueuedJobs: 1})
+	long := `{"train_file": "` + testCorpus + `", "params": {"Iter": 100000}}`
+	var running, queued jobResponse
+	post(t, ts, "/jobs", long, &running)
+	waitJob(t, ts, running.ID, func(j jobResponse) bool { return j.Status == JobRunning })
+	if status := post(t, ts, "/jobs", long, &queued); status != http.StatusCreated {
+		t.Fatalf("the second job should be queued, got %d", status)
+	}
+	var resp errorResponse
+	if status := post(t, ts, "/jobs", long, &resp); status != http.StatusServiceUnavailable {
+		t.Errorf("a full queue should refuse jobs with 503, got %d %+v", status, resp)
+	}
+
+	if status := deleteJob(t, ts, queued.ID); status != http.StatusOK {
+		t.Errorf("cancelling a queued job should be 200, got %d", status)
+	}
+	if j := getJob(t, ts, queued.ID); j.Status != JobCanceled || j.Started != nil {
+		t.Errorf("a job cancelled in the queue should never start, got %+v", j)
+	}
+	if status := deleteJob(t, ts, running.ID); status != http.StatusAccepted {
+		t.Errorf("cancelling a running job should be 202, got %d", status)
+	}
+	if j := waitJob(t, ts, running.ID, finished); j.Status != JobCanceled || j.Report != nil {
+		t.Errorf("the running job should be canceled, got %+v", j)
+	}
+	if status := deleteJob(t, ts, running.ID); status != http.StatusConflict {
+		t.Errorf("cancelling a finished job should be 409, got %d", status)
+	}
+	if s.Embeddings() != nil {
+		t.Error("a cancelled job should publish nothing")
+	}
+
+	post(t, ts, "/jobs", long, &running)
+	waitJob(t, ts, running.ID, func(j jobResponse) bool { return j.Status == JobRunning })
+	s.Close()
+	if j := getJob(t, ts, running.ID); j.Status != JobCanceled {
+		t.Errorf("Close should cancel the running job, got %+v", j)
+	}
+	if status := post(t, ts, "/jobs", long, &resp); status != http.StatusServiceUnavailable {
+		t.Errorf("a closed server should refuse jobs, got %d", status)
+	}
+}
@@ -0,0 +1,271 @@
+/*
+Package server serves trained word vectors over HTTP as JSON. Queries are POSTed as JSON bodies of at most MaxBodyBytes and answered with JSON; errors come back as {"error": "..."} with a 4xx status, 404 for words that are not in the vocabulary.
+
+	GET  /vocab                                              {"words": 71290, "dim": 300, "file": "...", "format": "binary", "loaded_at": "..."}
+	GET  /vocab?word=cat                                     the same plus {"word": "cat", "known": true, "index": 1034}
+	POST /vector        {"word": "cat"}                      {"word": "cat", "vector": [0.1, ...]}
+	POST /most-similar  {"words": ["cat"], "exclude": [], "n": 10}   {"neighbors": [{"word": "dog", "similarity": 0.8}, ...]}
+	POST /analogy       {"a": "man", "b": "king", "c": "woman", "n": 10}   {"neighbors": [...]}
+	POST /similarity    {"a": "cat", "b": "dog"}             {"similarity": 0.8}
+	POST /odd-one-out   {"words": ["cat", "dog", "car"]}     {"word": "car"}
+	POST /reload                                             reloads the model file and returns /vocab
+
+With a JobDir the server also trains models, see Config:
+
+	POST   /jobs       {"train_file": "corpus.txt", "params": {"Layer1VecSize": 200, "Architecture": "skip-gram"}}   201 with the job
+	GET    /jobs                                             every job, oldest first
+	GET    /jobs/{id}  {"id": "1", "status": "running", "progress": {"epoch": 1, "loss": 2.1, "words_per_sec": 350000, ...}, ...}
+	DELETE /jobs/{id}                                        cancels a queued or running job
+
+A job trains with wordvec.NewWord2VecModel and TrainModelContext; its params are config fields as read by wordvec.LoadModelConfig. Jobs read and write files with the permissions of the server, so the jobs endpoints should not be reachable by untrusted clients.
+
+Several words in /most-similar are combined like RecommendSimilar does, by the mean of their normalized vectors. The model file is loaded into memory; Reload, POST /reload or, with a ReloadInterval, a change of the file's modification time replace it without dropping requests, and a model that fails to load leaves the old one in place. A finished training job replaces it the same way.
+*/
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jbowles/wordvec"
+)
+
+const (
+	// DEFAULT_MAX_BODY_BYTES is the largest request body accepted.
+	DEFAULT_MAX_BODY_BYTES int64 = 1 << 20
+	// DEFAULT_RESULTS is the number of neighbors returned when a query gives no n.
+	DEFAULT_RESULTS int = 10
+	// DEFAULT_MAX_RESULTS is the largest n a query may ask for.
+	DEFAULT_MAX_RESULTS int = 1000
+	// DEFAULT_SHUTDOWN_TIMEOUT is how long ListenAndServe waits for running requests on shutdown.
+	DEFAULT_SHUTDOWN_TIMEOUT time.Duration = 10 * time.Second
+	// FORMAT_NATIVE names the native model format of wordvec.SaveFile in Config.Format.
+	FORMAT_NATIVE string = wordvec.NATIVE_FORMAT_NAME
+)
+
+/*
+Config holds the settings of a Server. Zero values take the defaults.
+
+	ModelFile		The vectors to serve.
+	Format			The format of ModelFile: "native" for wordvec.SaveFile models or a name accepted by wordvec.ParseEmbeddingFormat. Empty detects the format like wordvec.LoadEmbeddingsFile.
+	MaxBodyBytes	Largest request body; larger ones get 413.
+	MaxResults		Largest n of a query.
+	ReloadInterval	How often ListenAndServe checks the modification time of ModelFile and reloads it when changed; 0 disables the check.
+	ShutdownTimeout	How long ListenAndServe waits for running requests after its context is done.
+	JobDir			Directory the vectors of training jobs are written to; empty disables the jobs endpoints. Without a ModelFile the server has no vectors to query until the first job finishes.
+	Workers			Training jobs run at the same time; more are queued.
+	MaxQueuedJobs	Jobs waiting for a worker; more are refused with 503.
+	JobThreads		Most NumThreads of a job, GOMAXPROCS/Workers by default, so the workers together don't oversubscribe the host.
+	JobParams		ModelParams applied to every job before the params of its request, e.g. server wide defaults.
+*/
+type Config struct {
+	ModelFile       string
+	Format          string
+	MaxBodyBytes    int64
+	MaxResults      int
+	ReloadInterval  time.Duration
+	ShutdownTimeout time.Duration
+	JobDir          string
+	Workers         int
+	MaxQueuedJobs   int
+	JobThreads      int
+	JobParams       []wordvec.ModelParams
+}
+
+func (cfg Config) withDefaults() (Config, error) {
+	if cfg.ModelFile == "" && cfg.JobDir == "" {
+		return cfg, errors.New("No model file to serve and no job directory to train models in")
+	}
+	if cfg.Format != "" && cfg.Format != FORMAT_NATIVE {
+		if _, err := wordvec.ParseEmbeddingFormat(cfg.Format); err != nil {
+			return cfg, err
+		}
+	}
+	if cfg.MaxBodyBytes == 0 {
+		cfg.MaxBodyBytes = DEFAULT_MAX_BODY_BYTES
+	}
+	if cfg.MaxResults == 0 {
+		cfg.MaxResults = DEFAULT_MAX_RESULTS
+	}
+	if cfg.ShutdownTimeout == 0 {
+		cfg.ShutdownTimeout = DEFAULT_SHUTDOWN_TIMEOUT
+	}
+	if cfg.MaxBodyBytes < 1 || cfg.MaxResults < 1 || cfg.ReloadInterval < 0 || cfg.ShutdownTimeout < 0 {
+		return cfg, fmt.Errorf("MaxBodyBytes and MaxResults must be at least 1 and the durations positive, got %d, %d, %v and %v", cfg.MaxBodyBytes, cfg.MaxResults, cfg.ReloadInterval, cfg.ShutdownTimeout)
+	}
+	return cfg.jobDefaults()
+}
+
+// model is one loaded version of the vectors; it is replaced as a whole, never modified.
+type model struct {
+	embeddings *wordvec.Embeddings
+	file       string
+	format     string
+	job        string // the training job that made the vectors, if any
+	loadedAt   time.Time
+}
+
+// Server answers vector queries over HTTP, see the package documentation. It is safe for concurrent use.
+type Server struct {
+	cfg         Config
+	mu          sync.RWMutex
+	current     *model
+	fileModTime time.Time  // modification time of ModelFile when it was last loaded
+	reloadMu    sync.Mutex // one reload at a time
+	mux         *http.ServeMux
+	jobs        *jobQueue
+}
+
+// New loads the model file of cfg, starts the job workers when cfg has a JobDir and returns a Server for it. Close stops the workers.
+func New(cfg Config) (*Server, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{cfg: cfg, mux: http.NewServeMux()}
+	if cfg.ModelFile != "" {
+		if err := s.Reload(); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.JobDir != "" {
+		if err := os.MkdirAll(cfg.JobDir, 0755); err != nil {
+			return nil, err
+		}
+		s.jobs = newJobQueue(s)
+		s.mux.HandleFunc("/jobs", s.handleJobs)
+		s.mux.HandleFunc("/jobs/", s.handleJob)
+	}
+	s.mux.HandleFunc("/vocab", s.handleVocab)
+	s.mux.HandleFunc("/vector", s.handleVector)
+	s.mux.HandleFunc("/most-similar", s.handleMostSimilar)
+	s.mux.HandleFunc("/analogy", s.handleAnalogy)
+	s.mux.HandleFunc("/similarity", s.handleSimilarity)
+	s.mux.HandleFunc("/odd-one-out", s.handleOddOneOut)
+	s.mux.HandleFunc("/reload", s.handleReload)
+	return s, nil
+}
+
+// ServeHTTP serves the endpoints of the package documentation.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Embeddings returns the vectors being served, nil before the first training job of a server without a ModelFile has finished.
+func (s *Server) Embeddings() *wordvec.Embeddings {
+	if m := s.model(); m != nil {
+		return m.embeddings
+	}
+	return nil
+}
+
+func (s *Server) model() *model {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// publish serves m from then on; requests already running finish on the old vectors.
+func (s *Server) publish(m *model) {
+	s.mu.Lock()
+	s.current = m
+	s.mu.Unlock()
+	fmt.Fprintf(os.Stdout, "Serving %d words of dimension %d from %s\n", m.embeddings.Len(), m.embeddings.Dim(), m.file)
+}
+
+// Reload reads the model file again and serves it from then on, also after a training job published its vectors; requests already running finish on the old vectors. On error the old vectors stay.
+func (s *Server) Reload() error {
+	if s.cfg.ModelFile == "" {
+		return errors.New("No model file to reload")
+	}
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	info, err := os.Stat(s.cfg.ModelFile)
+	if err != nil {
+		return err
+	}
+	e, format, err := wordvec.LoadEmbeddingsFile(s.cfg.ModelFile, s.cfg.Format)
+	if err != nil {
+		return fmt.Errorf("Loading %s: %v", s.cfg.ModelFile, err)
+	}
+	s.publish(&model{
+		embeddings: e,
+		file:       s.cfg.ModelFile,
+		format:     format,
+		loadedAt:   time.Now(),
+	})
+	s.mu.Lock()
+	s.fileModTime = info.ModTime()
+	s.mu.Unlock()
+	return nil
+}
+
+// changed reports whether the model file was modified since it was loaded.
+func (s *Server) changed() bool {
+	info, err := os.Stat(s.cfg.ModelFile)
+	if err != nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return !info.ModTime().Equal(s.fileModTime)
+}
+
+// Watch reloads the model file whenever its modification time changes, checking every ReloadInterval until ctx is done. A file that fails to load, e.g. because it is still being written, is tried again on the next check.
+func (s *Server) Watch(ctx context.Context) {
+	if s.cfg.ReloadInterval <= 0 || s.cfg.ModelFile == "" {
+		return
+	}
+	ticker := time.NewTicker(s.cfg.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.changed() {
+				if err := s.Reload(); err != nil {
+					fmt.Fprintf(os.Stderr, "Reload failed: %v\n", err)
+				}
+			}
+		}
+	}
+}
+
+/*
+ListenAndServe serves on addr, watching the model file for changes, until ctx is done. It then stops accepting connections, waits up to ShutdownTimeout for running requests and cancels the training jobs with Close before returning; a clean shutdown returns nil.
+*/
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	go s.Watch(watchCtx)
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	fmt.Fprintf(os.Stdout, "Shutting down\n")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	defer s.Close()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
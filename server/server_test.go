@@ -0,0 +1,264 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jbowles/wordvec"
+)
+
+var testWords = []string{"man", "woman", "king", "queen", "apple"}
+
+var testVectors = []float32{
+	1, 0, 0,
+	0, 0, 1,
+	1, 1, 0,
+	0, 1, 1,
+	0.5, -1, 0.2,
+}
+
+// writeVectors writes words and vectors to name in the given format.
+func writeVectors(t *testing.T, name string, words []string, vectors []float32, format wordvec.EmbeddingFormat) {
+	t.Helper()
+	e, err := wordvec.NewEmbeddings(words, len(vectors)/len(words), vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := wordvec.WriteEmbeddings(f, e, format); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestServer(t *testing.T, cfg Config) (*Server, *httptest.Server) {
+	t.Helper()
+	if cfg.ModelFile == "" {
+		cfg.ModelFile = filepath.Join(t.TempDir(), "vectors.txt")
+		writeVectors(t, cfg.ModelFile, testWords, testVectors, wordvec.FormatText)
+	}
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+// post sends body to path and decodes the JSON answer into resp, returning the status.
+func post(t *testing.T, ts *httptest.Server, path, body string, resp interface{}) int {
+	t.Helper()
+	r, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(resp); err != nil {
+		t.Fatalf("%s: undecodable answer: %v", path, err)
+	}
+	return r.StatusCode
+}
+
+func getVocab(t *testing.T, ts *httptest.Server, query string) vocabResponse {
+	t.Helper()
+	r, err := http.Get(ts.URL + "/vocab" + query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	var resp vocabResponse
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil || r.StatusCode != http.StatusOK {
+		t.Fatalf("GET /vocab%s: status %d, %v", query, r.StatusCode, err)
+	}
+	return resp
+}
+
+func TestQueries(t *testing.T) {
+	_, ts := newTestServer(t, Config{})
+
+	vocab := getVocab(t, ts, "?word=king")
+	if vocab.Words != 5 || vocab.Dim != 3 || vocab.Format != "text" || vocab.Known == nil || !*vocab.Known || *vocab.Index != 2 {
+		t.Errorf("unexpected vocab %+v", vocab)
+	}
+	if vocab := getVocab(t, ts, "?word=prince"); vocab.Known == nil || *vocab.Known || vocab.Index != nil {
+		t.Errorf("prince should be unknown, got %+v", vocab)
+	}
+
+	var vec vectorResponse
+	if status := post(t, ts, "/vector", `{"word": "queen"}`, &vec); status != http.StatusOK || fmt.Sprint(vec.Vector) != "[0 1 1]" {
+		t.Errorf("vector of queen: status %d, %+v", status, vec)
+	}
+
+	var nn neighborsResponse
+	if status := post(t, ts, "/most-similar", `{"word": "king", "n": 2}`, &nn); status != http.StatusOK || len(nn.Neighbors) != 2 || nn.Neighbors[0].Word == "king" {
+		t.Errorf("most similar to king: status %d, %+v", status, nn)
+	}
+	if status := post(t, ts, "/most-similar", `{"words": ["man", "king"], "exclude": ["queen"], "n": 1}`, &nn); status != http.StatusOK || len(nn.Neighbors) != 1 || nn.Neighbors[0].Word != "apple" {
+		t.Errorf("most similar to man and king but queen should be apple: status %d, %+v", status, nn)
+	}
+
+	if status := post(t, ts, "/analogy", `{"a": "man", "b": "king", "c": "woman", "n": 1}`, &nn); status != http.StatusOK || len(nn.Neighbors) != 1 || nn.Neighbors[0].Word != "queen" {
+		t.Errorf("man is to king as woman is to queen: status %d, %+v", status, nn)
+	}
+
+	var sim similarityResponse
+	if status := post(t, ts, "/similarity", `{"a": "man", "b": "woman"}`, &sim); status != http.StatusOK || sim.Similarity != 0 {
+		t.Errorf("man and woman are orthogonal: status %d, %+v", status, sim)
+	}
+
+	var odd oddOneOutResponse
+	if status := post(t, ts, "/odd-one-out", `{"words": ["king", "queen", "woman", "apple"]}`, &odd); status != http.StatusOK || odd.Word != "apple" {
+		t.Errorf("apple is the odd one out: status %d, %+v", status, odd)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	_, ts := newTestServer(t, Config{MaxBodyBytes: 64, MaxResults: 3})
+	for _, c := range []struct {
+		path, body string
+		status     int
+	}{
+		{"/vector", `{"word": "prince"}`, http.StatusNotFound},
+		{"/vector", `{}`, http.StatusBadRequest},
+		{"/vector", `{"word": "king", "other": 1}`, http.StatusBadRequest},
+		{"/vector", `{"word": "king"} {"word": "queen"}`, http.StatusBadRequest},
+		{"/vector", `not json`, http.StatusBadRequest},
+		{"/vector", `{"word": "` + strings.Repeat("k", 100) + `"}`, http.StatusRequestEntityTooLarge},
+		{"/most-similar", `{"word": "king", "n": 4}`, http.StatusBadRequest},
+		{"/most-similar", `{"words": ["king", "prince"]}`, http.StatusNotFound},
+		{"/analogy", `{"a": "man", "b": "king"}`, http.StatusBadRequest},
+		{"/similarity", `{"a": "man", "b": "prince"}`, http.StatusNotFound},
+		{"/odd-one-out", `{"words": ["man", "king"]}`, http.StatusBadRequest},
+	} {
+		var resp errorResponse
+		if status := post(t, ts, c.path, c.body, &resp); status != c.status || resp.Error == "" {
+			t.Errorf("%s %s: expected status %d with an error, got %d %+v", c.path, c.body, c.status, status, resp)
+		}
+	}
+	r, err := http.Get(ts.URL + "/vector?word=king")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Body.Close()
+	if r.StatusCode != http.StatusMethodNotAllowed || r.Header.Get("Allow") != http.MethodPost {
+		t.Errorf("GET /vector should be 405 allowing POST, got %d %q", r.StatusCode, r.Header.Get("Allow"))
+	}
+}
+
+func TestReload(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "vectors.bin")
+	writeVectors(t, name, testWords, testVectors, wordvec.FormatBinary)
+	s, ts := newTestServer(t, Config{ModelFile: name})
+	if vocab := getVocab(t, ts, ""); vocab.Format != "binary" || vocab.Words != 5 {
+		t.Fatalf("a .bin file should be read as binary, got %+v", vocab)
+	}
+
+	writeVectors(t, name, []string{"a", "b"}, []float32{1, 0, 0, 1}, wordvec.FormatBinary)
+	var vocab vocabResponse
+	if status := post(t, ts, "/reload", ``, &vocab); status != http.StatusOK || vocab.Words != 2 || vocab.Dim != 2 {
+		t.Errorf("reload should serve the new vectors, got %d %+v", status, vocab)
+	}
+
+	for _, broken := range []string{"broken", "10000000000000 100\nab"} {
+		os.WriteFile(name, []byte(broken), 0644)
+		var resp errorResponse
+		if status := post(t, ts, "/reload", ``, &resp); status != http.StatusInternalServerError {
+			t.Errorf("%q should fail to reload, got %d %+v", broken, status, resp)
+		}
+		if s.Embeddings().Len() != 2 {
+			t.Errorf("a failed reload should keep the old vectors, got %d words", s.Embeddings().Len())
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "vectors.txt")
+	writeVectors(t, name, testWords, testVectors, wordvec.FormatText)
+	s, _ := newTestServer(t, Config{ModelFile: name, ReloadInterval: 5 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Watch(ctx)
+
+	writeVectors(t, name, []string{"a", "b"}, []float32{1, 0, 0, 1}, wordvec.FormatText)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(name, later, later)
+	for deadline := time.Now().Add(5 * time.Second); s.Embeddings().Len() != 2; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("a changed model file should be reloaded")
+		}
+	}
+}
+
+func TestNativeModel(t *testing.T) {
+	v, err := wordvec.NewWord2VecModel("", "",
+		wordvec.VocabHashSizeOption(1000),
+		wordvec.MinCountOption(1),
+		wordvec.Layer1VecSizeOption(4),
+		wordvec.DebugModeOption(0),
+		func(v *wordvec.VectorModel) error { v.TableSize = 1e5; return nil },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.TrainSentences([][]string{{"a", "b", "c"}, {"b", "c", "d"}}); err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(t.TempDir(), "model")
+	if err := v.SaveFile(name); err != nil {
+		t.Fatal(err)
+	}
+	_, ts := newTestServer(t, Config{ModelFile: name})
+	if vocab := getVocab(t, ts, "?word=c"); vocab.Format != FORMAT_NATIVE || vocab.Words != v.VocabSize || !*vocab.Known {
+		t.Errorf("a native model should be detected by its magic, got %+v", vocab)
+	}
+}
+
+func TestListenAndServe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	s, _ := newTestServer(t, Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.ListenAndServe(ctx, addr) }()
+
+	var r *http.Response
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if r, err = http.Post("http://"+addr+"/similarity", "application/json", bytes.NewBufferString(`{"a": "king", "b": "queen"}`)); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+	}
+	r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", r.StatusCode)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("a shutdown should return nil, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ListenAndServe did not return after its context was done")
+	}
+}
@@ -0,0 +1,156 @@
+package wordvec
+
+import (
+	"hash/fnv"
+)
+
+/*
+Subword vectors follow fastText (Bojanowski et al., https://arxiv.org/abs/1607.04606): a word is wrapped in "<" and ">" and every character n-gram of MinN to MaxN characters is hashed into one of Buckets rows of SynSubword. The input vector of a word is the mean of its own row of Syn0 and the rows of its n-grams, and training updates all of them, so any string, misspelled or unseen, gets a vector from the n-grams it shares with the vocabulary.
+
+Subwords are off while Buckets is 0.
+*/
+
+// charNgrams returns the character n-grams of "<word>" with minN to maxN characters (runes, not bytes).
+func charNgrams(word string, minN, maxN int) []string {
+	runes := []rune("<" + word + ">")
+	var ngrams []string
+	for n := minN; n <= maxN; n++ {
+		for i := 0; i+n <= len(runes); i++ {
+			ngrams = append(ngrams, string(runes[i:i+n]))
+		}
+	}
+	return ngrams
+}
+
+// SubwordBuckets returns the rows of SynSubword holding the character n-grams of word, or nil when subwords are off.
+func (v *VectorModel) SubwordBuckets(word string) []int {
+	if v.Buckets <= 0 {
+		return nil
+	}
+	ngrams := charNgrams(word, v.MinN, v.MaxN)
+	buckets := make([]int, len(ngrams))
+	h := fnv.New32a()
+	for i, ngram := range ngrams {
+		h.Reset()
+		h.Write([]byte(ngram))
+		buckets[i] = int(h.Sum32() % uint32(v.Buckets))
+	}
+	return buckets
+}
+
+// initSubwords seeds SynSubword like Syn0 and caches the n-gram buckets of every vocabulary word. The sentence end "</s>" has no n-grams.
+func (v *VectorModel) initSubwords(nextRandom uint64) {
+	v.subwords = nil
+	if v.Buckets <= 0 {
+		v.SynSubword = nil
+		return
+	}
+	v.SynSubword = make([]float64, v.Buckets*v.Layer1VecSize)
+	for i := range v.SynSubword {
+		nextRandom = nextRandom*25214903917 + 11
+		v.SynSubword[i] = ((float64(nextRandom&0xFFFF) / 65536) - 0.5) / float64(v.Layer1VecSize)
+	}
+	v.cacheSubwords()
+}
+
+// cacheSubwords computes the n-gram buckets of every vocabulary word, used by training to avoid hashing on every occurrence.
+func (v *VectorModel) cacheSubwords() {
+	v.subwords = make([][]int, v.VocabSize)
+	for a := 1; a < v.VocabSize; a++ {
+		v.subwords[a] = v.SubwordBuckets(v.Vocab[a].Word)
+	}
+}
+
+// wordSubwords returns the n-gram buckets of vocabulary word a, from the cache when there is one.
+func (v *VectorModel) wordSubwords(a int) []int {
+	if v.Buckets <= 0 || a == 0 || len(v.SynSubword) == 0 {
+		return nil
+	}
+	if len(v.subwords) == v.VocabSize {
+		return v.subwords[a]
+	}
+	return v.SubwordBuckets(v.Vocab[a].Word)
+}
+
+// addInput adds the input vector of vocabulary word a, the mean of its Syn0 row and its n-gram rows, to dst.
+func (v *VectorModel) addInput(a int, dst []float64) {
+	size := v.Layer1VecSize
+	row := v.Syn0[a*size : (a+1)*size]
+	buckets := v.wordSubwords(a)
+	if len(buckets) == 0 {
+		for d := range dst {
+			dst[d] += row[d]
+		}
+		return
+	}
+	scale := 1 / float64(len(buckets)+1)
+	for d := range dst {
+		dst[d] += row[d] * scale
+	}
+	for _, b := range buckets {
+		row = v.SynSubword[b*size : (b+1)*size]
+		for d := range dst {
+			dst[d] += row[d] * scale
+		}
+	}
+}
+
+// updateInput adds the gradient grad to the Syn0 row of vocabulary word a and to each of its n-gram rows, as fastText does.
+func (v *VectorModel) updateInput(a int, grad []float64) {
+	size := v.Layer1VecSize
+	row := v.Syn0[a*size : (a+1)*size]
+	for d := range grad {
+		row[d] += grad[d]
+	}
+	for _, b := range v.wordSubwords(a) {
+		row = v.SynSubword[b*size : (b+1)*size]
+		for d := range grad {
+			row[d] += grad[d]
+		}
+	}
+}
+
+// wordVectors returns the input vector of every vocabulary word, row-major: Syn0 itself without subwords, otherwise a new matrix of the means with the n-gram rows.
+func (v *VectorModel) wordVectors() []float64 {
+	if v.Buckets <= 0 || len(v.SynSubword) == 0 {
+		return v.Syn0
+	}
+	size := v.Layer1VecSize
+	vectors := make([]float64, v.VocabSize*size)
+	for a := 0; a < v.VocabSize; a++ {
+		v.addInput(a, vectors[a*size:(a+1)*size])
+	}
+	return vectors
+}
+
+/*
+WordVector returns the vector of any string. A vocabulary word gets its trained input vector (including its n-grams in a subword model); an unknown word in a subword model gets the mean of the rows of its character n-grams, which places misspellings next to the words they share n-grams with. It returns false for an unknown word when subwords are off.
+*/
+func (v *VectorModel) WordVector(word string) ([]float32, bool) {
+	size := v.Layer1VecSize
+	if len(v.Syn0) < v.VocabSize*size {
+		return nil, false
+	}
+	vec := make([]float64, size)
+	if a := v.SearchVocab(word); a >= 0 {
+		v.addInput(a, vec)
+	} else {
+		buckets := v.SubwordBuckets(word)
+		if len(buckets) == 0 || len(v.SynSubword) == 0 {
+			return nil, false
+		}
+		for _, b := range buckets {
+			for d, x := range v.SynSubword[b*size : (b+1)*size] {
+				vec[d] += x
+			}
+		}
+		for d := range vec {
+			vec[d] /= float64(len(buckets))
+		}
+	}
+	out := make([]float32, size)
+	for d, x := range vec {
+		out[d] = float32(x)
+	}
+	return out, true
+}
@@ -0,0 +1,136 @@
+package wordvec
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCharNgrams(t *testing.T) {
+	got := charNgrams("cat", 3, 4)
+	want := []string{"<ca", "cat", "at>", "<cat", "cat>"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	// n-grams are made of characters, not bytes
+	for _, ngram := range charNgrams("café", 3, 3) {
+		if !strings.Contains("<café>", ngram) || len([]rune(ngram)) != 3 {
+			t.Errorf("bad n-gram %q", ngram)
+		}
+	}
+	if got := charNgrams("a", 4, 6); len(got) != 0 {
+		t.Errorf("expected no n-grams longer than the word, got %v", got)
+	}
+}
+
+func TestSubwordBuckets(t *testing.T) {
+	mv := newSmallTrainingModel(t, "", BucketsOption(100), MinNOption(2), MaxNOption(3))
+	buckets := mv.SubwordBuckets("horse")
+	if len(buckets) != len(charNgrams("horse", 2, 3)) {
+		t.Fatalf("expected one bucket per n-gram, got %v", buckets)
+	}
+	for _, b := range buckets {
+		if b < 0 || b >= 100 {
+			t.Errorf("bucket %d out of range", b)
+		}
+	}
+	if !reflect.DeepEqual(buckets, mv.SubwordBuckets("horse")) {
+		t.Error("buckets should be deterministic")
+	}
+	off := newSmallTrainingModel(t, "")
+	if off.SubwordBuckets("horse") != nil {
+		t.Error("expected no buckets with subwords off")
+	}
+}
+
+func TestSubwordOptionsValidate(t *testing.T) {
+	for _, params := range [][]ModelParams{
+		{BucketsOption(-1)},
+		{MinNOption(0)},
+		{BucketsOption(10), MinNOption(5), MaxNOption(3)},
+	} {
+		if _, err := NewWord2VecModel("train.txt", "out.txt", append([]ModelParams{VocabHashSizeOption(10)}, params...)...); err == nil {
+			t.Errorf("expected an error for %d options", len(params))
+		}
+	}
+	// a bad n-gram range does not matter while subwords are off
+	if _, err := NewWord2VecModel("train.txt", "out.txt", VocabHashSizeOption(10), MinNOption(5), MaxNOption(3)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTrainSubwordModel(t *testing.T) {
+	animals := map[string]bool{"horse": true, "rabbit": true, "dog": true, "cow": true, "cat": true, "mouse": true, "goat": true, "sheep": true}
+	places := map[string]bool{"barn": true, "garden": true, "field": true, "farm": true, "kitchen": true, "house": true, "stable": true, "meadow": true}
+	for _, cbow := range []bool{false, true} {
+		params := []ModelParams{BucketsOption(5000), MinNOption(3), MaxNOption(5), IterOption(30), SampleOption(0)}
+		if !cbow {
+			params = append(params, BagOfWordsFalse)
+		}
+		out := filepath.Join(t.TempDir(), "vectors.txt")
+		mv := newSmallTrainingModel(t, out, params...)
+		report, err := mv.TrainModel()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if losses := report.EpochLosses(); losses[len(losses)-1] >= losses[0] {
+			t.Errorf("cbow %v: loss should go down during training, got %v", cbow, losses)
+		}
+		if len(mv.SynSubword) != 5000*mv.Layer1VecSize {
+			t.Fatalf("expected %d n-gram weights, got %d", 5000*mv.Layer1VecSize, len(mv.SynSubword))
+		}
+		e, err := mv.Embeddings()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// a misspelt word should land among the words of its kind
+		for misspelt, kind := range map[string]map[string]bool{"rabit": animals, "horsse": animals, "kitchenn": places, "gardn": places} {
+			if mv.SearchVocab(misspelt) != -1 {
+				t.Fatalf("%s should be out of vocabulary", misspelt)
+			}
+			vec, ok := mv.WordVector(misspelt)
+			if !ok {
+				t.Fatalf("expected a vector for %s", misspelt)
+			}
+			if nn := e.MostSimilarVector(vec, 1); len(nn) != 1 || !kind[nn[0].Word] {
+				t.Errorf("cbow %v: unexpected nearest word to %s: %v", cbow, misspelt, nn)
+			}
+		}
+		vec, ok := mv.WordVector("horse")
+		want, _ := e.Vector("horse")
+		if !ok || !reflect.DeepEqual(vec, want) {
+			t.Errorf("cbow %v: WordVector of a vocabulary word should match its embedding", cbow)
+		}
+	}
+}
+
+func TestSubwordModelNativeRoundTrip(t *testing.T) {
+	mv := trainSmallModel(t, BucketsOption(500), BagOfWordsFalse)
+	name := filepath.Join(t.TempDir(), "model.wv")
+	if err := mv.SaveFile(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Buckets != 500 || loaded.MinN != mv.MinN || loaded.MaxN != mv.MaxN {
+		t.Errorf("subword parameters not restored: %d %d %d", loaded.Buckets, loaded.MinN, loaded.MaxN)
+	}
+	want, _ := mv.WordVector("horsse")
+	got, ok := loaded.WordVector("horsse")
+	if !ok || !reflect.DeepEqual(want, got) {
+		t.Error("out of vocabulary vectors differ after loading")
+	}
+}
+
+func TestWordVectorWithoutSubwords(t *testing.T) {
+	mv := trainSmallModel(t)
+	if _, ok := mv.WordVector("horsse"); ok {
+		t.Error("expected no vector for an unknown word with subwords off")
+	}
+	if _, ok := mv.WordVector("horse"); !ok {
+		t.Error("expected a vector for a vocabulary word")
+	}
+}
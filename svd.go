@@ -0,0 +1,265 @@
+package wordvec
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// sparseMatrix is a matrix in compressed sparse row form: the entries of row i are indices[indptr[i]:indptr[i+1]] with their values.
+type sparseMatrix struct {
+	rows, cols int
+	indptr     []int
+	indices    []int32
+	values     []float64
+}
+
+// denseMatrix is a row-major matrix.
+type denseMatrix struct {
+	rows, cols int
+	data       []float64
+}
+
+func newDenseMatrix(rows, cols int) *denseMatrix {
+	return &denseMatrix{rows: rows, cols: cols, data: make([]float64, rows*cols)}
+}
+
+func (d *denseMatrix) row(i int) []float64 {
+	return d.data[i*d.cols : (i+1)*d.cols]
+}
+
+// parallelRows calls fn for blocks of the rows 0 to n on up to threads goroutines.
+func parallelRows(n, threads int, fn func(start, end int)) {
+	if threads < 1 {
+		threads = 1
+	}
+	chunk := (n + threads - 1) / threads
+	var wg sync.WaitGroup
+	for start := 0; start < n; start += chunk {
+		end := start + chunk
+		if end > n {
+			end = n
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			fn(start, end)
+		}(start, end)
+	}
+	wg.Wait()
+}
+
+// mul returns m times the dense matrix x.
+func (m *sparseMatrix) mul(x *denseMatrix, threads int) *denseMatrix {
+	out := newDenseMatrix(m.rows, x.cols)
+	parallelRows(m.rows, threads, func(start, end int) {
+		for i := start; i < end; i++ {
+			dst := out.row(i)
+			for k := m.indptr[i]; k < m.indptr[i+1]; k++ {
+				a := m.values[k]
+				for c, xv := range x.row(int(m.indices[k])) {
+					dst[c] += a * xv
+				}
+			}
+		}
+	})
+	return out
+}
+
+// transpose returns the transposed matrix, also in compressed sparse row form.
+func (m *sparseMatrix) transpose() *sparseMatrix {
+	t := &sparseMatrix{
+		rows:    m.cols,
+		cols:    m.rows,
+		indptr:  make([]int, m.cols+1),
+		indices: make([]int32, len(m.indices)),
+		values:  make([]float64, len(m.values)),
+	}
+	for _, j := range m.indices {
+		t.indptr[j+1]++
+	}
+	for j := 0; j < m.cols; j++ {
+		t.indptr[j+1] += t.indptr[j]
+	}
+	next := append([]int(nil), t.indptr[:m.cols]...)
+	for i := 0; i < m.rows; i++ {
+		for k := m.indptr[i]; k < m.indptr[i+1]; k++ {
+			j := m.indices[k]
+			t.indices[next[j]] = int32(i)
+			t.values[next[j]] = m.values[k]
+			next[j]++
+		}
+	}
+	return t
+}
+
+// orthonormalize replaces the columns of d by an orthonormal basis of their span with modified Gram-Schmidt. Columns that are (numerically) in the span of the previous ones become zero.
+func orthonormalize(d *denseMatrix) {
+	cols := make([][]float64, d.cols)
+	for c := range cols {
+		cols[c] = make([]float64, d.rows)
+		for i := 0; i < d.rows; i++ {
+			cols[c][i] = d.data[i*d.cols+c]
+		}
+	}
+	for c, col := range cols {
+		for _, prev := range cols[:c] {
+			var dot float64
+			for i, x := range col {
+				dot += x * prev[i]
+			}
+			for i := range col {
+				col[i] -= dot * prev[i]
+			}
+		}
+		var norm float64
+		for _, x := range col {
+			norm += x * x
+		}
+		norm = math.Sqrt(norm)
+		for i := range col {
+			if norm > 1e-10 {
+				col[i] /= norm
+			} else {
+				col[i] = 0
+			}
+		}
+	}
+	for c, col := range cols {
+		for i, x := range col {
+			d.data[i*d.cols+c] = x
+		}
+	}
+}
+
+/*
+symmetricEigen returns the eigenvalues of the symmetric n x n matrix a (row-major, overwritten) in descending order and the matching eigenvectors as the columns of a row-major n x n matrix, using cyclic Jacobi rotations.
+*/
+func symmetricEigen(a []float64, n int) ([]float64, []float64) {
+	v := make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		v[i*n+i] = 1
+	}
+	for sweep := 0; sweep < 100; sweep++ {
+		var off, total float64
+		for p := 0; p < n; p++ {
+			for q := 0; q < n; q++ {
+				total += a[p*n+q] * a[p*n+q]
+				if p != q {
+					off += a[p*n+q] * a[p*n+q]
+				}
+			}
+		}
+		if off <= 1e-24*total || off == 0 {
+			break
+		}
+		for p := 0; p < n-1; p++ {
+			for q := p + 1; q < n; q++ {
+				apq := a[p*n+q]
+				if math.Abs(apq) < 1e-300 {
+					continue
+				}
+				theta := (a[q*n+q] - a[p*n+p]) / (2 * apq)
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					akp, akq := a[k*n+p], a[k*n+q]
+					a[k*n+p] = c*akp - s*akq
+					a[k*n+q] = s*akp + c*akq
+				}
+				for k := 0; k < n; k++ {
+					apk, aqk := a[p*n+k], a[q*n+k]
+					a[p*n+k] = c*apk - s*aqk
+					a[q*n+k] = s*apk + c*aqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k*n+p], v[k*n+q]
+					v[k*n+p] = c*vkp - s*vkq
+					v[k*n+q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return a[order[i]*n+order[i]] > a[order[j]*n+order[j]] })
+	values := make([]float64, n)
+	vectors := make([]float64, n*n)
+	for c, i := range order {
+		values[c] = a[i*n+i]
+		for k := 0; k < n; k++ {
+			vectors[k*n+c] = v[k*n+i]
+		}
+	}
+	return values, vectors
+}
+
+/*
+randomizedSVD returns the k largest singular values of the sparse matrix a and its left singular vectors as the columns of a rows x k matrix, with the randomized range finder of Halko, Martinsson and Tropp (https://arxiv.org/abs/0909.4061): the range of a is sampled with k+oversample random directions, refined with powerIter power iterations, and the small projected matrix is decomposed exactly.
+*/
+func randomizedSVD(a *sparseMatrix, k, oversample, powerIter int, rnd *rand.Rand, threads int) ([]float64, *denseMatrix) {
+	l := k + oversample
+	if l > a.rows {
+		l = a.rows
+	}
+	if l > a.cols {
+		l = a.cols
+	}
+	omega := newDenseMatrix(a.cols, l)
+	for i := range omega.data {
+		omega.data[i] = rnd.NormFloat64()
+	}
+	at := a.transpose()
+	q := a.mul(omega, threads)
+	orthonormalize(q)
+	for i := 0; i < powerIter; i++ {
+		z := at.mul(q, threads)
+		orthonormalize(z)
+		q = a.mul(z, threads)
+		orthonormalize(q)
+	}
+	// B = Qt A is l x cols; its left singular vectors are the eigenvectors of B Bt
+	bt := at.mul(q, threads)
+	bbt := make([]float64, l*l)
+	for i := 0; i < bt.rows; i++ {
+		r := bt.row(i)
+		for p, x := range r {
+			if x == 0 {
+				continue
+			}
+			for s, y := range r {
+				bbt[p*l+s] += x * y
+			}
+		}
+	}
+	eigenvalues, eigenvectors := symmetricEigen(bbt, l)
+	if k > l {
+		k = l
+	}
+	sigma := make([]float64, k)
+	for c := range sigma {
+		sigma[c] = math.Sqrt(math.Max(eigenvalues[c], 0))
+	}
+	u := newDenseMatrix(a.rows, k)
+	parallelRows(a.rows, threads, func(start, end int) {
+		for i := start; i < end; i++ {
+			qi := q.row(i)
+			ui := u.row(i)
+			for c := range ui {
+				var sum float64
+				for p, x := range qi {
+					sum += x * eigenvectors[p*l+c]
+				}
+				ui[c] = sum
+			}
+		}
+	})
+	return sigma, u
+}
@@ -0,0 +1,96 @@
+package wordvec
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSymmetricEigen(t *testing.T) {
+	a := []float64{
+		4, 1, 2,
+		1, 3, 0,
+		2, 0, 5,
+	}
+	orig := append([]float64(nil), a...)
+	values, vectors := symmetricEigen(a, 3)
+	for c := 0; c < 3; c++ {
+		if c > 0 && values[c] > values[c-1] {
+			t.Errorf("eigenvalues should be descending, got %v", values)
+		}
+		// A v = lambda v
+		for i := 0; i < 3; i++ {
+			var av float64
+			for j := 0; j < 3; j++ {
+				av += orig[i*3+j] * vectors[j*3+c]
+			}
+			if math.Abs(av-values[c]*vectors[i*3+c]) > 1e-9 {
+				t.Fatalf("column %d is not an eigenvector of %v", c, values[c])
+			}
+		}
+	}
+	if math.Abs(values[0]+values[1]+values[2]-12) > 1e-9 {
+		t.Errorf("eigenvalues should sum to the trace 12, got %v", values)
+	}
+}
+
+func TestRandomizedSVD(t *testing.T) {
+	// a sparse 60 x 40 matrix of rank 3 with singular values 10, 5 and 2
+	rnd := rand.New(rand.NewSource(3))
+	basis := func(n int) [][]float64 {
+		d := newDenseMatrix(n, 3)
+		for i := range d.data {
+			if rnd.Intn(3) == 0 {
+				d.data[i] = rnd.NormFloat64()
+			}
+		}
+		orthonormalize(d)
+		cols := make([][]float64, 3)
+		for c := range cols {
+			cols[c] = make([]float64, n)
+			for i := 0; i < n; i++ {
+				cols[c][i] = d.data[i*3+c]
+			}
+		}
+		return cols
+	}
+	u, v := basis(60), basis(40)
+	want := []float64{10, 5, 2}
+	m := &sparseMatrix{rows: 60, cols: 40, indptr: make([]int, 61)}
+	for i := 0; i < 60; i++ {
+		for j := 0; j < 40; j++ {
+			var x float64
+			for c, s := range want {
+				x += s * u[c][i] * v[c][j]
+			}
+			if math.Abs(x) > 1e-12 {
+				m.indices = append(m.indices, int32(j))
+				m.values = append(m.values, x)
+			}
+		}
+		m.indptr[i+1] = len(m.indices)
+	}
+
+	sigma, left := randomizedSVD(m, 3, 5, 2, rand.New(rand.NewSource(1)), 2)
+	for c, s := range want {
+		if math.Abs(sigma[c]-s) > 1e-6 {
+			t.Errorf("expected singular value %v, got %v", s, sigma[c])
+		}
+		// the singular vectors match up to their sign
+		var dot float64
+		for i := 0; i < 60; i++ {
+			dot += left.data[i*3+c] * u[c][i]
+		}
+		if math.Abs(math.Abs(dot)-1) > 1e-6 {
+			t.Errorf("left singular vector %d should match, got a cosine of %v", c, dot)
+		}
+	}
+
+	at := m.transpose()
+	if at.rows != 40 || at.cols != 60 || len(at.values) != len(m.values) {
+		t.Fatalf("unexpected transpose %dx%d with %d values", at.rows, at.cols, len(at.values))
+	}
+	if back := at.transpose(); back.indptr[60] != m.indptr[60] || back.values[7] != m.values[7] || back.indices[7] != m.indices[7] {
+		t.Error("transposing twice should give the matrix back")
+	}
+}
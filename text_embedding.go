@@ -0,0 +1,264 @@
+package wordvec
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// SIF_WEIGHT is the parameter a of the smooth inverse frequency weight a / (a + p(w)).
+const SIF_WEIGHT float64 = 1e-3
+
+// EmbedMethod is a way of combining word vectors into a text vector, see EmbedText.
+type EmbedMethod int
+
+const (
+	// EmbedMean is the plain mean of the word vectors.
+	EmbedMean EmbedMethod = iota
+	// EmbedTFIDF weights every word by its frequency in the text times its inverse frequency in the training data, using the vocabulary count as document frequency.
+	EmbedTFIDF
+	// EmbedSIF is Smooth Inverse Frequency (Arora et al., https://openreview.net/forum?id=SyK00v5xx): words are weighted by SIF_WEIGHT / (SIF_WEIGHT + p(w)) and the projection on the first principal component of a set of texts is removed.
+	EmbedSIF
+)
+
+// String returns the name of the method.
+func (m EmbedMethod) String() string {
+	switch m {
+	case EmbedMean:
+		return "mean"
+	case EmbedTFIDF:
+		return "tfidf"
+	case EmbedSIF:
+		return "sif"
+	}
+	return fmt.Sprintf("EmbedMethod(%d)", int(m))
+}
+
+// ParseEmbedMethod returns the method with the given name: "mean", "tfidf" or "sif".
+func ParseEmbedMethod(name string) (EmbedMethod, error) {
+	switch strings.ToLower(name) {
+	case "mean", "avg", "average":
+		return EmbedMean, nil
+	case "tfidf", "tf-idf":
+		return EmbedTFIDF, nil
+	case "sif":
+		return EmbedSIF, nil
+	}
+	return 0, fmt.Errorf("Unknown embedding method %q", name)
+}
+
+// TextEmbedding is the vector of a text and how much of the text the vocabulary covers.
+type TextEmbedding struct {
+	Vector   []float32 `json:"vector"`
+	Tokens   int       `json:"tokens"`   // tokens in the text
+	Known    int       `json:"known"`    // tokens found in the vocabulary
+	Coverage float64   `json:"coverage"` // Known / Tokens, 0 for an empty text
+	OOV      []string  `json:"oov"`      // the unknown tokens, skipped, in order of first appearance
+}
+
+/*
+EmbedText combines the vectors of the words of a text into one vector with the given method. Tokens that are not in the vocabulary are skipped and reported in the coverage stats; a text without known tokens gets a zero vector.
+
+For EmbedSIF the common component set by FitSIF is removed; without FitSIF the vector is only the weighted mean. Use EmbedTexts to embed a whole collection at once.
+*/
+func (v *VectorModel) EmbedText(tokens []string, method EmbedMethod) (*TextEmbedding, error) {
+	if err := v.checkEmbedMethod(method); err != nil {
+		return nil, err
+	}
+	te := v.weightedMean(tokens, method)
+	if method == EmbedSIF && v.sifComponent != nil {
+		removeComponent(te.Vector, v.sifComponent)
+	}
+	return te, nil
+}
+
+/*
+EmbedTexts embeds every text like EmbedText. For EmbedSIF the first principal component of the weighted means of these texts is removed from each of them, as in the SIF paper, rather than the component set by FitSIF.
+*/
+func (v *VectorModel) EmbedTexts(texts [][]string, method EmbedMethod) ([]*TextEmbedding, error) {
+	if err := v.checkEmbedMethod(method); err != nil {
+		return nil, err
+	}
+	embeddings := make([]*TextEmbedding, len(texts))
+	for i, tokens := range texts {
+		embeddings[i] = v.weightedMean(tokens, method)
+	}
+	if method == EmbedSIF {
+		u := firstPrincipalComponent(embeddings, v.Layer1VecSize, v.NextRandom)
+		for _, te := range embeddings {
+			removeComponent(te.Vector, u)
+		}
+	}
+	return embeddings, nil
+}
+
+// FitSIF computes the first principal component of the SIF weighted means of a sample of texts; EmbedText then removes it from every EmbedSIF vector. It is not safe to call while other goroutines embed texts.
+func (v *VectorModel) FitSIF(texts [][]string) error {
+	if err := v.checkEmbedMethod(EmbedSIF); err != nil {
+		return err
+	}
+	embeddings := make([]*TextEmbedding, len(texts))
+	for i, tokens := range texts {
+		embeddings[i] = v.weightedMean(tokens, EmbedSIF)
+	}
+	u := firstPrincipalComponent(embeddings, v.Layer1VecSize, v.NextRandom)
+	if u == nil {
+		return errors.New("None of the texts has a token in the vocabulary")
+	}
+	v.sifComponent = u
+	return nil
+}
+
+func (v *VectorModel) checkEmbedMethod(method EmbedMethod) error {
+	if method != EmbedMean && method != EmbedTFIDF && method != EmbedSIF {
+		return fmt.Errorf("Unknown embedding method %v", method)
+	}
+	if v.VocabSize == 0 || len(v.Syn0) < v.VocabSize*v.Layer1VecSize {
+		return errors.New("Model has no trained word vectors")
+	}
+	return nil
+}
+
+// totalCount is the number of words the counts of the vocabulary were taken from.
+func (v *VectorModel) totalCount() float64 {
+	if v.TrainWords > 0 {
+		return float64(v.TrainWords)
+	}
+	var total float64
+	for a := 0; a < v.VocabSize; a++ {
+		total += float64(v.Vocab[a].Count)
+	}
+	return math.Max(total, 1)
+}
+
+// weightedMean is the weighted mean of the vectors of the known tokens, before any component removal.
+func (v *VectorModel) weightedMean(tokens []string, method EmbedMethod) *TextEmbedding {
+	size := v.Layer1VecSize
+	te := &TextEmbedding{Tokens: len(tokens), Vector: make([]float32, size)}
+	seen := make(map[string]bool)
+	tf := make(map[int]int)
+	var known, order []int
+	for _, token := range tokens {
+		a := v.SearchVocab(token)
+		if a < 0 {
+			if !seen[token] {
+				seen[token] = true
+				te.OOV = append(te.OOV, token)
+			}
+			continue
+		}
+		known = append(known, a)
+		if tf[a] == 0 {
+			order = append(order, a)
+		}
+		tf[a]++
+	}
+	te.Known = len(known)
+	if te.Tokens > 0 {
+		te.Coverage = float64(te.Known) / float64(te.Tokens)
+	}
+	if len(known) == 0 {
+		return te
+	}
+
+	total := v.totalCount()
+	sum := make([]float64, size)
+	vec := make([]float64, size)
+	var weights float64
+	for _, a := range order {
+		n := tf[a]
+		var w float64
+		count := math.Max(float64(v.Vocab[a].Count), 1)
+		switch method {
+		case EmbedMean:
+			w = float64(n)
+		case EmbedTFIDF:
+			w = float64(n) * (math.Log((1+total)/(1+count)) + 1)
+		case EmbedSIF:
+			w = float64(n) * SIF_WEIGHT / (SIF_WEIGHT + count/total)
+		}
+		for d := range vec {
+			vec[d] = 0
+		}
+		v.addInput(a, vec)
+		for d, x := range vec {
+			sum[d] += w * x
+		}
+		weights += w
+	}
+	// SIF averages over the number of words, the other methods over the weights
+	div := weights
+	if method == EmbedSIF {
+		div = float64(len(known))
+	}
+	for d, x := range sum {
+		te.Vector[d] = float32(x / div)
+	}
+	return te
+}
+
+// firstPrincipalComponent returns the unit first right singular vector of the matrix of the text vectors (not centered, as in the SIF paper), by power iteration on its Gram matrix. The iteration starts from a random vector seeded by nextRandom: a fixed start, e.g. all ones, converges to the wrong component when the first one is orthogonal to it. It is nil when all vectors are zero.
+func firstPrincipalComponent(embeddings []*TextEmbedding, dim int, nextRandom uint64) []float64 {
+	gram := make([]float64, dim*dim)
+	for _, te := range embeddings {
+		for i, xi := range te.Vector {
+			if xi == 0 {
+				continue
+			}
+			for j, xj := range te.Vector {
+				gram[i*dim+j] += float64(xi) * float64(xj)
+			}
+		}
+	}
+	u := make([]float64, dim)
+	var start float64
+	for i := range u {
+		nextRandom = nextRandom*25214903917 + 11
+		u[i] = (float64(nextRandom&0xFFFF) / 65536) - 0.5
+		start += u[i] * u[i]
+	}
+	start = math.Sqrt(start)
+	for i := range u {
+		u[i] /= start
+	}
+	next := make([]float64, dim)
+	for iter := 0; iter < 200; iter++ {
+		var norm float64
+		for i := range next {
+			next[i] = 0
+			for j, x := range u {
+				next[i] += gram[i*dim+j] * x
+			}
+			norm += next[i] * next[i]
+		}
+		if norm == 0 {
+			return nil
+		}
+		norm = math.Sqrt(norm)
+		var change float64
+		for i := range u {
+			x := next[i] / norm
+			change += math.Abs(x - u[i])
+			u[i] = x
+		}
+		if change < 1e-9 {
+			break
+		}
+	}
+	return u
+}
+
+// removeComponent subtracts the projection of vec on the unit vector u.
+func removeComponent(vec []float32, u []float64) {
+	if u == nil {
+		return
+	}
+	var proj float64
+	for i, x := range vec {
+		proj += float64(x) * u[i]
+	}
+	for i := range vec {
+		vec[i] -= float32(proj * u[i])
+	}
+}
@@ -0,0 +1,133 @@
+package wordvec
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseEmbedMethod(t *testing.T) {
+	for _, m := range []EmbedMethod{EmbedMean, EmbedTFIDF, EmbedSIF} {
+		got, err := ParseEmbedMethod(m.String())
+		if err != nil || got != m {
+			t.Errorf("expected %v back from %q, got %v (%v)", m, m.String(), got, err)
+		}
+	}
+	if _, err := ParseEmbedMethod("median"); err == nil {
+		t.Error("expected an error for an unknown method")
+	}
+}
+
+func TestEmbedTextMean(t *testing.T) {
+	mv := trainSmallModel(t)
+	te, err := mv.EmbedText(strings.Fields("the horse unicorn horse zebra unicorn"), EmbedMean)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if te.Tokens != 6 || te.Known != 3 || te.Coverage != 0.5 {
+		t.Errorf("expected 3 of 6 tokens known, got %d of %d (%f)", te.Known, te.Tokens, te.Coverage)
+	}
+	if strings.Join(te.OOV, " ") != "unicorn zebra" {
+		t.Errorf("expected unicorn and zebra out of vocabulary, got %v", te.OOV)
+	}
+	the, _ := mv.WordVector("the")
+	horse, _ := mv.WordVector("horse")
+	for d, x := range te.Vector {
+		want := (the[d] + 2*horse[d]) / 3
+		if math.Abs(float64(x-want)) > 1e-6 {
+			t.Fatalf("expected the mean of the known word vectors, got %v at %d instead of %v", x, d, want)
+		}
+	}
+
+	empty, err := mv.EmbedText([]string{"unicorn"}, EmbedMean)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty.Known != 0 || empty.Coverage != 0 || len(empty.Vector) != mv.Layer1VecSize {
+		t.Errorf("expected a zero vector without coverage, got %+v", empty)
+	}
+	for _, x := range empty.Vector {
+		if x != 0 {
+			t.Fatal("expected a zero vector")
+		}
+	}
+	if _, err := mv.EmbedText([]string{"the"}, EmbedMethod(9)); err == nil {
+		t.Error("expected an error for an unknown method")
+	}
+}
+
+func TestEmbedTextTFIDF(t *testing.T) {
+	mv := trainSmallModel(t)
+	// "the" is the most frequent word, so TF-IDF should pull the vector towards the rarer "corn"
+	tokens := strings.Fields("the the the corn")
+	mean, _ := mv.EmbedText(tokens, EmbedMean)
+	tfidf, err := mv.EmbedText(tokens, EmbedTFIDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corn, _ := mv.WordVector("corn")
+	if cosine32(tfidf.Vector, corn) <= cosine32(mean.Vector, corn) {
+		t.Errorf("TF-IDF should weigh the rare word more than the mean does")
+	}
+}
+
+// The first component (1, -1) is orthogonal to the all ones vector, a power iteration started there stays on the second component (1, 1).
+func TestFirstPrincipalComponentOrthogonalToOnes(t *testing.T) {
+	embeddings := []*TextEmbedding{
+		{Vector: []float32{3, -3}},
+		{Vector: []float32{-3, 3}},
+		{Vector: []float32{1, 1}},
+	}
+	u := firstPrincipalComponent(embeddings, 2, NEXT_RANDOM)
+	if u == nil || math.Abs(math.Abs(u[0])-math.Sqrt2/2) > 1e-6 || u[0]*u[1] > 0 {
+		t.Errorf("expected the component (1, -1) up to sign, got %v", u)
+	}
+}
+
+func TestEmbedTextSIF(t *testing.T) {
+	mv := trainSmallModel(t)
+	texts := [][]string{
+		strings.Fields("the horse finds apple in the field"),
+		strings.Fields("a dog walks near the barn with the cow"),
+		strings.Fields("the mouse eats bread in the garden"),
+		strings.Fields("a cat sits near the barn with the cow"),
+	}
+	embeddings, err := mv.EmbedTexts(texts, EmbedSIF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := firstPrincipalComponent(func() []*TextEmbedding {
+		raw := make([]*TextEmbedding, len(texts))
+		for i, tokens := range texts {
+			raw[i] = mv.weightedMean(tokens, EmbedSIF)
+		}
+		return raw
+	}(), mv.Layer1VecSize, mv.NextRandom)
+	for i, te := range embeddings {
+		var proj float64
+		for d, x := range te.Vector {
+			proj += float64(x) * u[d]
+		}
+		if math.Abs(proj) > 1e-4 {
+			t.Errorf("text %d still has a projection of %g on the common component", i, proj)
+		}
+	}
+
+	// without FitSIF a single text is only weighted
+	before, _ := mv.EmbedText(texts[0], EmbedSIF)
+	if err := mv.FitSIF(texts); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := mv.EmbedText(texts[0], EmbedSIF)
+	for d := range after.Vector {
+		if math.Abs(float64(after.Vector[d]-embeddings[0].Vector[d])) > 1e-5 {
+			t.Fatal("EmbedText after FitSIF on the same texts should match EmbedTexts")
+		}
+	}
+	if cosine32(before.Vector, after.Vector) > 0.9999 {
+		t.Error("FitSIF should change the SIF vectors")
+	}
+	if err := mv.FitSIF([][]string{{"unicorn"}}); err == nil {
+		t.Error("expected an error fitting on texts without known tokens")
+	}
+}
@@ -0,0 +1,568 @@
+package wordvec
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// threadStats holds what a single training thread accumulated during one pass over its chunk of the training file.
+type threadStats struct {
+	Words           int64
+	NegSamplingLoss float64
+	NegSamplingObs  int64
+	SoftMaxLoss     float64
+	SoftMaxObs      int64
+	GloVeLoss       float64
+	GloVeObs        int64
+}
+
+// InitNet allocates the network weights. Syn0 (the word vectors), SynSubword (the n-gram vectors of a subword model) and SynDoc (the document vectors) are seeded with small random values, Syn1 (hierarchical softmax) and Syn1neg (negative sampling) start at zero and hold outputSize() values per word. The Huffman tree is built here too since hierarchical softmax walks it; its error is returned.
+func (v *VectorModel) InitNet() error {
+	//fmt.Fprintf(os.Stdout, "Init Net %v", time.Now())
+	fmt.Fprintf(os.Stdout, "Init Net\n")
+	if v.SoftMax {
+		v.Syn1 = make([]float64, v.VocabSize*v.outputSize())
+	}
+	if v.NegSampling > 0 {
+		v.Syn1neg = make([]float64, v.VocabSize*v.outputSize())
+	}
+	nextRandom := v.initInputVectors()
+	v.initDocVectors(nextRandom)
+	return v.CreateBinaryTree()
+}
+
+// initInputVectors seeds Syn0 and, for a subword model, SynSubword with small random values and returns the random state after seeding Syn0.
+func (v *VectorModel) initInputVectors() uint64 {
+	var nextRandom uint64 = v.NextRandom
+
+	v.Syn0 = make([]float64, v.VocabSize*v.Layer1VecSize)
+	for a := 0; a < v.VocabSize; a++ {
+		for b := 0; b < v.Layer1VecSize; b++ {
+			nextRandom = nextRandom*25214903917 + 11
+			v.Syn0[a*v.Layer1VecSize+b] = ((float64(nextRandom&0xFFFF) / 65536) - 0.5) / float64(v.Layer1VecSize)
+		}
+	}
+	v.initSubwords(nextRandom)
+	return nextRandom
+}
+
+// sigmoid looks up f in ExpTable. The table holds the sigmoid over [0, MAX_EXP), so negative values use sigmoid(-f) = 1 - sigmoid(f). Callers must keep f inside (-MAX_EXP, MAX_EXP).
+func (v *VectorModel) sigmoid(f float64) float64 {
+	if f < 0 {
+		return 1 - v.ExpTable[int(-f*(EXP_TABLE_SIZE/MAX_EXP))]
+	}
+	return v.ExpTable[int(f*(EXP_TABLE_SIZE/MAX_EXP))]
+}
+
+// logLoss is the log-loss -log(sigmoid(f)) for a positive label and -log(1 - sigmoid(f)) for a negative one.
+func logLoss(f float64, label float64) float64 {
+	if label == 0 {
+		f = -f
+	}
+	if f > 0 {
+		return math.Log1p(math.Exp(-f))
+	}
+	return -f + math.Log1p(math.Exp(f))
+}
+
+// currentAlpha linearly decays StartingAlpha over all iterations, never going below 0.01% of the starting value.
+func (v *VectorModel) currentAlpha(wordCountActual int64) float64 {
+	alpha := v.StartingAlpha * (1 - float64(wordCountActual)/float64(int64(v.Iter)*v.TrainWords+1))
+	if alpha < v.StartingAlpha*0.0001 {
+		alpha = v.StartingAlpha * 0.0001
+	}
+	return alpha
+}
+
+/*
+trainTarget trains the prediction of word from the hidden layer in with hierarchical softmax and negative sampling, the inner step shared by every architecture and paragraph vectors. The gradient for in is added to neu1e; the output weights (Syn1, Syn1neg) are only updated when learn is set, InferVector keeps them frozen.
+
+The output weights of a word are the len(in) values at offset in its outputSize() row: offset is 0 except for structured skip-gram, where it selects the weights of the window position (see windowOffset), and CWindow predicts from the whole row.
+*/
+func (v *VectorModel) trainTarget(in []float64, word int, offset int, neu1e []float64, alpha float64, learn bool, nextRandom *uint64, stats *threadStats) {
+	var target, l2 int
+	var label, f, g float64
+	layer1Size := len(in)
+	stride := v.outputSize()
+	// HIERARCHICAL SOFTMAX
+	if v.SoftMax {
+		for d := 0; d < int(v.Vocab[word].Codelen); d++ {
+			f = 0
+			l2 = v.Vocab[word].Point[d]*stride + offset
+			// Propagate hidden -> output
+			for e := 0; e < layer1Size; e++ {
+				f += in[e] * v.Syn1[e+l2]
+			}
+			label = float64(1 - v.Vocab[word].Code[d])
+			stats.SoftMaxLoss += logLoss(f, label)
+			stats.SoftMaxObs++
+			if f <= -MAX_EXP || f >= MAX_EXP {
+				continue
+			}
+			// 'g' is the gradient multiplied by the learning rate
+			g = (label - v.sigmoid(f)) * alpha
+			// Propagate errors output -> hidden
+			for e := 0; e < layer1Size; e++ {
+				neu1e[e] += g * v.Syn1[e+l2]
+			}
+			// Learn weights hidden -> output
+			if learn {
+				for e := 0; e < layer1Size; e++ {
+					v.Syn1[e+l2] += g * in[e]
+				}
+			}
+		}
+	}
+	// NEGATIVE SAMPLING
+	if v.NegSampling > 0 {
+		for d := 0; d < v.NegSampling+1; d++ {
+			if d == 0 {
+				target = word
+				label = 1
+			} else {
+				*nextRandom = *nextRandom*25214903917 + 11
+				target = v.Table[(*nextRandom>>16)%uint64(v.TableSize)]
+				if target == 0 {
+					target = int(*nextRandom%uint64(v.VocabSize-1)) + 1
+				}
+				if target == word {
+					continue
+				}
+				label = 0
+			}
+			l2 = target*stride + offset
+			f = 0
+			for e := 0; e < layer1Size; e++ {
+				f += in[e] * v.Syn1neg[e+l2]
+			}
+			stats.NegSamplingLoss += logLoss(f, label)
+			stats.NegSamplingObs++
+			if f >= MAX_EXP {
+				g = (label - 1) * alpha
+			} else if f <= -MAX_EXP {
+				g = (label - 0) * alpha
+			} else {
+				g = (label - v.sigmoid(f)) * alpha
+			}
+			for e := 0; e < layer1Size; e++ {
+				neu1e[e] += g * v.Syn1neg[e+l2]
+			}
+			if learn {
+				for e := 0; e < layer1Size; e++ {
+					v.Syn1neg[e+l2] += g * in[e]
+				}
+			}
+		}
+	}
+}
+
+// recoverThread turns a panic of a training goroutine into its error, so a failing thread fails the training instead of the process.
+func recoverThread(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("Training thread panicked: %v", r)
+	}
+}
+
+// discard is the subsampling of frequent words: it randomly discards occurrences of frequent words while keeping their ranking the same.
+func (v *VectorModel) discard(word int, nextRandom *uint64) bool {
+	if v.Sample <= 0 {
+		return false
+	}
+	cn := float64(v.Vocab[word].Count)
+	ran := (math.Sqrt(cn/(v.Sample*float64(v.TrainWords))) + 1) * (v.Sample * float64(v.TrainWords)) / cn
+	*nextRandom = *nextRandom*25214903917 + 11
+	return ran < float64(*nextRandom&0xFFFF)/65536
+}
+
+/*
+trainModelThread runs one pass of training over the id-th of NumThreads chunks of the training file, or of the in-memory sentences of TrainSentences. nextRandom is the thread's random state and is carried between passes.
+
+With ParagraphVectors a document tag sets the document of the rest of its line: PV-DM (CBOW) adds the document vector to the context of every word, PV-DBOW (skip-gram) also predicts every word from the document vector.
+*/
+func (v *VectorModel) trainModelThread(ctx context.Context, id, epoch int, nextRandom *uint64) (stats threadStats, err error) {
+	var sentenceLength, sentencePosition int
+	var wordCount, lastWordCount int64
+	var sen []int = make([]int, v.MaxSentenceLen+1)
+	var neu1 []float64 = make([]float64, v.Layer1VecSize)
+	var neu1e []float64 = make([]float64, v.Layer1VecSize)
+	var hidden []float64 = make([]float64, v.Layer1VecSize) // skip-gram input of a subword model
+	var in, docRow []float64
+	var window, windowe []float64 // CWindow hidden layer and its gradient, one slice per window position
+	var eof bool
+	var word, lastWord, cw int
+	var lineDoc, doc int = -1, -1
+	var token string
+	alpha := v.currentAlpha(atomic.LoadInt64(&v.WordCountActual))
+	layer1Size := v.Layer1VecSize
+	if v.Architecture == ArchCWindow {
+		window = make([]float64, v.outputSize())
+		windowe = make([]float64, v.outputSize())
+	}
+
+	var fin *bufio.Reader
+	var next, end, pos int // sentence range of the thread and position in the current sentence, see TrainSentences
+	if v.sentences != nil {
+		next = len(v.sentences) * id / v.NumThreads
+		end = len(v.sentences) * (id + 1) / v.NumThreads
+	} else {
+		fi, ferr := os.Open(v.TrainFile)
+		if ferr != nil {
+			return stats, ferr
+		}
+		defer fi.Close()
+		if _, serr := fi.Seek(v.FileSize/int64(v.NumThreads)*int64(id), SEEK_SET); serr != nil {
+			return stats, serr
+		}
+		fin = bufio.NewReader(fi)
+	}
+
+	for {
+		if wordCount-lastWordCount > 10000 {
+			wordCountActual := atomic.AddInt64(&v.WordCountActual, wordCount-lastWordCount)
+			stats.Words += wordCount - lastWordCount
+			lastWordCount = wordCount
+			if v.DebugMode > 1 {
+				elapsed := time.Since(v.Start).Seconds()
+				fmt.Fprintf(os.Stdout, "%cAlpha: %f  Progress: %.2f%%  Words/thread/sec: %.2fk  ", 13, alpha,
+					float64(wordCountActual)/float64(int64(v.Iter)*v.TrainWords+1)*100,
+					float64(wordCountActual)/(elapsed*float64(v.NumThreads)+1)/1000)
+			}
+			alpha = v.currentAlpha(wordCountActual)
+			if err := ctx.Err(); err != nil {
+				return stats, err
+			}
+			if v.Progress != nil {
+				v.reportProgress(epoch, wordCountActual, alpha, stats.loss())
+			}
+		}
+		if sentenceLength == 0 && v.sentences != nil {
+			if next >= end {
+				eof = true
+			} else {
+				s := v.sentences[next]
+				for ; pos < len(s) && sentenceLength < v.MaxSentenceLen; pos++ {
+					if s[pos] == -1 {
+						continue
+					}
+					wordCount++
+					if v.discard(s[pos], nextRandom) {
+						continue
+					}
+					sen[sentenceLength] = s[pos]
+					sentenceLength++
+				}
+				if pos == len(s) {
+					// the </s> ending the sentence
+					wordCount++
+					next++
+					pos = 0
+				}
+			}
+			sentencePosition = 0
+		} else if sentenceLength == 0 {
+			doc = lineDoc
+			for {
+				token, err = v.ReadWord(fin)
+				if err == io.EOF {
+					eof = true
+					break
+				}
+				if v.ParagraphVectors && isDocTag(token) {
+					lineDoc = v.docTagIndex(token)
+					doc = lineDoc
+					continue
+				}
+				word = v.SearchVocab(token)
+				if word == -1 {
+					continue
+				}
+				wordCount++
+				if word == 0 {
+					lineDoc = -1
+					break
+				}
+				if v.discard(word, nextRandom) {
+					continue
+				}
+				sen[sentenceLength] = word
+				sentenceLength++
+				if sentenceLength >= v.MaxSentenceLen {
+					break
+				}
+			}
+			sentencePosition = 0
+			docRow = nil
+			if doc >= 0 {
+				docRow = v.SynDoc[doc*layer1Size : (doc+1)*layer1Size]
+			}
+		}
+		if sentencePosition == 0 && sentenceLength > 1 && v.Sessions == SessionShuffled {
+			shuffleSession(sen[:sentenceLength], nextRandom)
+		}
+		if eof || (v.sentences == nil && wordCount > v.TrainWords/int64(v.NumThreads)) {
+			atomic.AddInt64(&v.WordCountActual, wordCount-lastWordCount)
+			stats.Words += wordCount - lastWordCount
+			return stats, nil
+		}
+		if sentenceLength == 0 {
+			continue
+		}
+		word = sen[sentencePosition]
+		for c := 0; c < layer1Size; c++ {
+			neu1[c] = 0
+			neu1e[c] = 0
+		}
+		*nextRandom = *nextRandom*25214903917 + 11
+		b := int(*nextRandom % uint64(v.WindowSkipLen))
+		win := v.WindowSkipLen
+		if v.Sessions == SessionWhole {
+			win, b = sentenceLength, 0
+		}
+		switch v.Architecture {
+		case ArchCBOW:
+			// in -> hidden
+			cw = 0
+			for a := b; a < win*2+1-b; a++ {
+				if a == win {
+					continue
+				}
+				c := sentencePosition - win + a
+				if c < 0 || c >= sentenceLength {
+					continue
+				}
+				lastWord = sen[c]
+				v.addInput(lastWord, neu1)
+				cw++
+			}
+			if docRow != nil {
+				for d := 0; d < layer1Size; d++ {
+					neu1[d] += docRow[d]
+				}
+				cw++
+			}
+			if cw > 0 {
+				for c := 0; c < layer1Size; c++ {
+					neu1[c] /= float64(cw)
+				}
+				v.trainTarget(neu1, word, 0, neu1e, alpha, true, nextRandom, &stats)
+				// hidden -> in
+				for a := b; a < win*2+1-b; a++ {
+					if a == win {
+						continue
+					}
+					c := sentencePosition - win + a
+					if c < 0 || c >= sentenceLength {
+						continue
+					}
+					lastWord = sen[c]
+					v.updateInput(lastWord, neu1e)
+				}
+				if docRow != nil {
+					for d := 0; d < layer1Size; d++ {
+						docRow[d] += neu1e[d]
+					}
+				}
+			}
+		case ArchCWindow:
+			// in -> hidden, concatenated in window order
+			for d := range window {
+				window[d] = 0
+				windowe[d] = 0
+			}
+			cw = 0
+			for a := b; a < win*2+1-b; a++ {
+				if a == win {
+					continue
+				}
+				c := sentencePosition - win + a
+				if c < 0 || c >= sentenceLength {
+					continue
+				}
+				off := v.windowOffset(a)
+				v.addInput(sen[c], window[off:off+layer1Size])
+				cw++
+			}
+			if cw > 0 {
+				v.trainTarget(window, word, 0, windowe, alpha, true, nextRandom, &stats)
+				// hidden -> in
+				for a := b; a < win*2+1-b; a++ {
+					if a == win {
+						continue
+					}
+					c := sentencePosition - win + a
+					if c < 0 || c >= sentenceLength {
+						continue
+					}
+					off := v.windowOffset(a)
+					v.updateInput(sen[c], windowe[off:off+layer1Size])
+				}
+			}
+		default:
+			//train skip-gram, structured skip-gram with the output weights of the window position
+			offset := 0
+			for a := b; a < win*2+1-b; a++ {
+				if a == win {
+					continue
+				}
+				c := sentencePosition - win + a
+				if c < 0 || c >= sentenceLength {
+					continue
+				}
+				lastWord = sen[c]
+				in = v.Syn0[lastWord*layer1Size : (lastWord+1)*layer1Size]
+				if len(v.SynSubword) > 0 {
+					for d := range hidden {
+						hidden[d] = 0
+					}
+					v.addInput(lastWord, hidden)
+					in = hidden
+				}
+				for d := 0; d < layer1Size; d++ {
+					neu1e[d] = 0
+				}
+				if v.Architecture == ArchStructuredSkipGram {
+					offset = v.windowOffset(a)
+				}
+				v.trainTarget(in, word, offset, neu1e, alpha, true, nextRandom, &stats)
+				// Learn weights input -> hidden
+				v.updateInput(lastWord, neu1e)
+			}
+			// PV-DBOW: predict the word from the document vector
+			if docRow != nil {
+				for d := 0; d < layer1Size; d++ {
+					neu1e[d] = 0
+				}
+				v.trainTarget(docRow, word, 0, neu1e, alpha, true, nextRandom, &stats)
+				for d := 0; d < layer1Size; d++ {
+					docRow[d] += neu1e[d]
+				}
+			}
+		}
+		sentencePosition++
+		if sentencePosition >= sentenceLength {
+			sentenceLength = 0
+		}
+	}
+}
+
+/*
+TrainModel builds (or reads) the vocabulary, initializes the network and trains it for Iter epochs across NumThreads goroutines. The vectors (or k-means classes when KmeansClasses > 0) are written to OutputFile.
+
+Each thread accumulates the negative sampling and hierarchical softmax log-loss for its chunk of the training file; the per thread losses are merged at the end of every epoch into the returned TrainingReport. When WriteReport is set the report is also written as JSON to ReportFile().
+*/
+func (v *VectorModel) TrainModel() (*TrainingReport, error) {
+	return v.TrainModelContext(context.Background())
+}
+
+/*
+TrainModelContext is TrainModel that stops when ctx is done: the training threads check ctx every 10000 words and training returns ctx.Err() without writing any output. Progress, when set, is called along the way, so a long training run can be watched and cancelled, e.g. by the jobs of the server package.
+*/
+func (v *VectorModel) TrainModelContext(ctx context.Context) (*TrainingReport, error) {
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+	if v.TrainFile == "" {
+		return nil, errors.New("No training file specified")
+	}
+	fmt.Fprintf(os.Stdout, "Starting training using file %s\n", v.TrainFile)
+	if v.StartingAlpha == 0 {
+		v.StartingAlpha = v.Alpha
+	}
+	if err := v.prepareVocab(); err != nil {
+		return nil, err
+	}
+	if v.ParagraphVectors {
+		if err := v.LearnDocTags(); err != nil {
+			return nil, err
+		}
+	}
+	if v.OutputFile == "" {
+		return nil, errors.New("No output file specified")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	report, err := v.trainEpochs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.SaveOutput(); err != nil {
+		return report, err
+	}
+	if v.WriteReport {
+		if err := report.WriteJSONFile(v.ReportFile()); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// trainEpochs initializes the network over the vocabulary and trains it for Iter epochs across NumThreads goroutines until ctx is done, see TrainModelContext.
+func (v *VectorModel) trainEpochs(ctx context.Context) (*TrainingReport, error) {
+	if err := v.InitNet(); err != nil {
+		return nil, err
+	}
+	if v.NegSampling > 0 {
+		v.InitUnigramTable()
+	}
+
+	report := &TrainingReport{
+		StartingAlpha: v.StartingAlpha,
+		TrainWords:    v.TrainWords,
+		VocabSize:     v.VocabSize,
+	}
+	v.Start = time.Now()
+	v.WordCountActual = 0
+	nextRandoms := make([]uint64, v.NumThreads)
+	for id := range nextRandoms {
+		nextRandoms[id] = v.NextRandom + uint64(id)
+	}
+
+	for epoch := 0; epoch < v.Iter; epoch++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		epochStart := time.Now()
+		stats := make([]threadStats, v.NumThreads)
+		errs := make([]error, v.NumThreads)
+		var wg sync.WaitGroup
+		for id := 0; id < v.NumThreads; id++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				defer recoverThread(&errs[id])
+				stats[id], errs[id] = v.trainModelThread(ctx, id, epoch+1, &nextRandoms[id])
+			}(id)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			if err != nil {
+				return nil, err
+			}
+		}
+		// The decayed rate goes to the report only, Alpha stays the configured value
+		alpha := v.currentAlpha(v.WordCountActual)
+		report.addEpoch(epoch+1, stats, alpha, time.Since(epochStart))
+		if v.Progress != nil {
+			v.reportProgress(epoch+1, v.WordCountActual, alpha, report.FinalLoss())
+		}
+	}
+	report.finish(time.Since(v.Start))
+	v.TrainingTime = report.Elapsed
+	if v.DebugMode > 1 {
+		fmt.Fprintf(os.Stdout, "\n")
+	}
+	if v.DebugMode > 0 {
+		fmt.Fprintf(os.Stdout, "Trained %d words in %v, final loss %f\n", report.WordsProcessed, report.Elapsed, report.FinalLoss())
+	}
+	return report, nil
+}
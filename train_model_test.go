@@ -0,0 +1,265 @@
+package wordvec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var testFileForTraining string = "testdata/train_small.txt"
+
+// newSmallTrainingModel sets up a model on the small training file that is cheap enough to train in a unit test.
+func newSmallTrainingModel(t *testing.T, outFile string, modelParams ...ModelParams) *VectorModel {
+	params := append([]ModelParams{
+		VocabHashSizeOption(5000),
+		MinCountOption(1),
+		Layer1VecSizeOption(20),
+		DebugModeOption(0),
+		IterOption(3),
+		NumThreadsOption(2),
+	}, modelParams...)
+	mv, err := NewWord2VecModel(testFileForTraining, outFile, params...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mv.TableSize = 1e5
+	return mv
+}
+
+func TestTrainModelSkipGramNegSampling(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "vectors.txt")
+	mv := newSmallTrainingModel(t, out, BagOfWordsFalse)
+	report, err := mv.TrainModel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Epochs) != 3 {
+		t.Fatal("report should have 3 epochs, got", len(report.Epochs))
+	}
+	losses := report.EpochLosses()
+	if losses[len(losses)-1] >= losses[0] {
+		t.Errorf("loss should go down during training, got %v", losses)
+	}
+	if report.Epochs[0].SoftMaxLoss != 0 {
+		t.Error("softmax loss should be 0 when softmax is off, got", report.Epochs[0].SoftMaxLoss)
+	}
+	if report.WordsProcessed == 0 || report.WordsPerSec <= 0 {
+		t.Errorf("words processed and throughput should be positive, got %d and %f", report.WordsProcessed, report.WordsPerSec)
+	}
+	if report.Alpha >= report.StartingAlpha {
+		t.Errorf("alpha should decay from %f, got %f", report.StartingAlpha, report.Alpha)
+	}
+	if mv.Alpha != ALPHA_SKIP_GRAM {
+		t.Errorf("training should leave the configured alpha %f, got %f", ALPHA_SKIP_GRAM, mv.Alpha)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Scan()
+	header := strings.Fields(scanner.Text())
+	if header[0] != strconv.Itoa(mv.VocabSize) || header[1] != "20" {
+		t.Errorf("vector file header should be '%d 20', got %v", mv.VocabSize, header)
+	}
+	lines := 0
+	for scanner.Scan() {
+		if len(strings.Fields(scanner.Text())) != 21 {
+			t.Fatalf("vector line should have a word and 20 values, got %q", scanner.Text())
+		}
+		lines++
+	}
+	if lines != mv.VocabSize {
+		t.Errorf("vector file should have %d words, got %d", mv.VocabSize, lines)
+	}
+	if _, err := os.Stat(mv.ReportFile()); !os.IsNotExist(err) {
+		t.Error("report file should not be written by default")
+	}
+}
+
+func TestTrainModelCbowSoftMax(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "vectors.txt")
+	mv := newSmallTrainingModel(t, out, SoftMaxOptionTrue, NegSamplingOption(0), TrainingReportTrue)
+	report, err := mv.TrainModel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Epochs[0].SoftMaxLoss == 0 || report.Epochs[0].NegSamplingLoss != 0 {
+		t.Errorf("only softmax loss should be tracked, got %+v", report.Epochs[0])
+	}
+	losses := report.EpochLosses()
+	if losses[len(losses)-1] >= losses[0] {
+		t.Errorf("loss should go down during training, got %v", losses)
+	}
+
+	f, err := os.Open(mv.ReportFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	written, err := ReadTrainingReport(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written.Epochs) != 3 || written.FinalLoss() != report.FinalLoss() {
+		t.Errorf("written report should match the returned one, got %+v", written)
+	}
+}
+
+// A dot product of exactly MAX_EXP is past the end of ExpTable and must be clipped like larger ones: a positive target there is already learned.
+func TestTrainTargetClipsMaxExp(t *testing.T) {
+	v := &VectorModel{
+		ExpTable:      PreComputeExpTable(),
+		Layer1VecSize: 1,
+		NegSampling:   1,
+		Table:         []int{1},
+		TableSize:     1,
+		VocabSize:     2,
+		Syn1neg:       []float64{0, 1},
+	}
+	for _, f := range []float64{MAX_EXP, -MAX_EXP} {
+		neu1e := []float64{0}
+		var nextRandom uint64 = 1
+		v.trainTarget([]float64{f}, 1, 0, neu1e, 0.1, false, &nextRandom, &threadStats{})
+		want := 0.0
+		if f < 0 {
+			want = 0.1
+		}
+		if neu1e[0] != want {
+			t.Errorf("f = %g: expected gradient %g, got %g", f, want, neu1e[0])
+		}
+	}
+}
+
+func TestTrainModelKmeansClasses(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "classes.txt")
+	mv := newSmallTrainingModel(t, out, KmeansClassesOption(4))
+	if _, err := mv.TrainModel(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	lines := 0
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		class, err := strconv.Atoi(fields[1])
+		if err != nil || class < 0 || class >= 4 {
+			t.Errorf("class line should be '<word> <0-3>', got %q", scanner.Text())
+		}
+		lines++
+	}
+	if lines != mv.VocabSize {
+		t.Errorf("class file should have %d words, got %d", mv.VocabSize, lines)
+	}
+}
+
+func TestTrainModelReadVocab(t *testing.T) {
+	dir := t.TempDir()
+	vocab := filepath.Join(dir, "vocab.txt")
+	first := newSmallTrainingModel(t, filepath.Join(dir, "first.txt"), VocabOutFileOption(vocab), IterOption(1))
+	if _, err := first.TrainModel(); err != nil {
+		t.Fatal(err)
+	}
+	second := newSmallTrainingModel(t, filepath.Join(dir, "second.txt"), VocabInFileOption(vocab), IterOption(1))
+	if _, err := second.TrainModel(); err != nil {
+		t.Fatal(err)
+	}
+	if second.VocabSize != first.VocabSize || second.TrainWords != first.TrainWords {
+		t.Errorf("vocab read from file should match the learned one, got %d/%d words, want %d/%d", second.VocabSize, second.TrainWords, first.VocabSize, first.TrainWords)
+	}
+	for i := 0; i < first.VocabSize; i++ {
+		if second.SearchVocab(first.Vocab[i].Word) != i {
+			t.Errorf("word %s should be at index %d", first.Vocab[i].Word, i)
+		}
+	}
+}
+
+func TestTrainModelSaveVocabError(t *testing.T) {
+	dir := t.TempDir()
+	mv := newSmallTrainingModel(t, filepath.Join(dir, "out.txt"), VocabOutFileOption(filepath.Join(dir, "missing", "vocab.txt")), IterOption(1))
+	if _, err := mv.TrainModel(); err == nil {
+		t.Error("a vocabulary that can't be saved should fail training")
+	}
+}
+
+func TestTrainModelThreadPanic(t *testing.T) {
+	mv := newSmallTrainingModel(t, filepath.Join(t.TempDir(), "out.txt"), IterOption(1))
+	mv.ExpTable = nil
+	if _, err := mv.TrainModel(); err == nil || !strings.Contains(err.Error(), "panicked") {
+		t.Error("a panic in a training thread should be returned as an error, got", err)
+	}
+}
+
+func TestTrainModelMissingTrainFile(t *testing.T) {
+	mv := newSmallTrainingModel(t, filepath.Join(t.TempDir(), "vectors.txt"))
+	mv.TrainFile = "testdata/does_not_exist.txt"
+	if _, err := mv.TrainModel(); err == nil {
+		t.Error("training on a missing file should return an error")
+	}
+}
+
+func TestTrainModelProgress(t *testing.T) {
+	corpus, err := os.ReadFile(testFileForTraining)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	train := filepath.Join(dir, "train.txt")
+	if err := os.WriteFile(train, bytes.Repeat(corpus, 5), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var progress []TrainingProgress
+	mv := newSmallTrainingModel(t, filepath.Join(dir, "vectors.txt"), NumThreadsOption(1), IterOption(2),
+		ProgressOption(func(p TrainingProgress) { progress = append(progress, p) }))
+	mv.TrainFile = train
+	report, err := mv.TrainModel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(progress) < 4 {
+		t.Fatalf("expected reports within and after both epochs, got %+v", progress)
+	}
+	for i, p := range progress {
+		if p.Epochs != 2 || p.TotalWords != 2*mv.TrainWords || p.Words <= 0 || p.Fraction > 1 || p.Loss <= 0 {
+			t.Errorf("unexpected progress %+v", p)
+		}
+		if i > 0 && (p.Words < progress[i-1].Words || p.Epoch < progress[i-1].Epoch) {
+			t.Errorf("progress should only go forward, got %+v after %+v", p, progress[i-1])
+		}
+	}
+	last := progress[len(progress)-1]
+	if last.Epoch != 2 || last.Loss != report.FinalLoss() || last.Fraction < 0.9 {
+		t.Errorf("the last report should close epoch 2 with its loss %f, got %+v", report.FinalLoss(), last)
+	}
+}
+
+func TestTrainModelContextCancel(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "vectors.txt")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	epochs := 0
+	mv := newSmallTrainingModel(t, out, IterOption(5), ProgressOption(func(p TrainingProgress) {
+		epochs = p.Epoch
+		cancel()
+	}))
+	if _, err := mv.TrainModelContext(ctx); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if epochs != 1 {
+		t.Errorf("training should stop after the epoch that cancelled it, got to epoch %d", epochs)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("a cancelled training should not write %s", out)
+	}
+}
@@ -0,0 +1,182 @@
+package wordvec
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// EpochReport holds the merged training metrics of all threads for a single epoch (one pass over the training file).
+type EpochReport struct {
+	Epoch           int           `json:"epoch"`
+	Loss            float64       `json:"loss"`
+	NegSamplingLoss float64       `json:"neg_sampling_loss"`
+	SoftMaxLoss     float64       `json:"softmax_loss"`
+	GloVeLoss       float64       `json:"glove_loss,omitempty"`
+	Words           int64         `json:"words"`
+	Alpha           float64       `json:"alpha"`
+	Elapsed         time.Duration `json:"elapsed_ns"`
+	WordsPerSec     float64       `json:"words_per_sec"`
+}
+
+/*
+TrainingReport summarizes a TrainModel run so training runs with different hyperparameters can be compared.
+
+Losses are mean log-losses per prediction: NegSamplingLoss averages over the positive and negative samples, SoftMaxLoss over the nodes of the Huffman tree walked by hierarchical softmax. Loss is their sum, so it stays comparable across epochs whichever objectives are switched on. A GloVeModel reports the mean weighted squared error per co-occurrence as GloVeLoss instead, and counts co-occurrences as words.
+*/
+type TrainingReport struct {
+	Epochs         []EpochReport `json:"epochs"`
+	WordsProcessed int64         `json:"words_processed"`
+	TrainWords     int64         `json:"train_words"`
+	VocabSize      int           `json:"vocab_size"`
+	Elapsed        time.Duration `json:"elapsed_ns"`
+	StartingAlpha  float64       `json:"starting_alpha"`
+	Alpha          float64       `json:"alpha"`
+	WordsPerSec    float64       `json:"words_per_sec"`
+}
+
+// addEpoch merges the per thread stats of one epoch and appends them to the report.
+func (r *TrainingReport) addEpoch(epoch int, stats []threadStats, alpha float64, elapsed time.Duration) {
+	var merged threadStats
+	for _, s := range stats {
+		merged.Words += s.Words
+		merged.NegSamplingLoss += s.NegSamplingLoss
+		merged.NegSamplingObs += s.NegSamplingObs
+		merged.SoftMaxLoss += s.SoftMaxLoss
+		merged.SoftMaxObs += s.SoftMaxObs
+		merged.GloVeLoss += s.GloVeLoss
+		merged.GloVeObs += s.GloVeObs
+	}
+	e := EpochReport{
+		Epoch:   epoch,
+		Words:   merged.Words,
+		Alpha:   alpha,
+		Elapsed: elapsed,
+	}
+	e.NegSamplingLoss, e.SoftMaxLoss, e.GloVeLoss = merged.losses()
+	e.Loss = e.NegSamplingLoss + e.SoftMaxLoss + e.GloVeLoss
+	if elapsed > 0 {
+		e.WordsPerSec = float64(e.Words) / elapsed.Seconds()
+	}
+	r.Epochs = append(r.Epochs, e)
+	r.WordsProcessed += e.Words
+	r.Alpha = alpha
+}
+
+// losses returns the mean losses of the stats, see TrainingReport.
+func (s threadStats) losses() (negSampling, softMax, glove float64) {
+	if s.NegSamplingObs > 0 {
+		negSampling = s.NegSamplingLoss / float64(s.NegSamplingObs)
+	}
+	if s.SoftMaxObs > 0 {
+		softMax = s.SoftMaxLoss / float64(s.SoftMaxObs)
+	}
+	if s.GloVeObs > 0 {
+		glove = s.GloVeLoss / float64(s.GloVeObs)
+	}
+	return negSampling, softMax, glove
+}
+
+// loss returns the sum of the mean losses of the stats, like EpochReport.Loss.
+func (s threadStats) loss() float64 {
+	negSampling, softMax, glove := s.losses()
+	return negSampling + softMax + glove
+}
+
+/*
+TrainingProgress is a snapshot of a running training, passed to the Progress function of the model.
+
+Loss is the running loss of the current epoch as seen by the thread reporting it, or the loss of the whole epoch in the report made when an epoch ends. Words counts the words trained by all threads over all epochs so far and Fraction is its share of the Iter*TrainWords words of the training.
+*/
+type TrainingProgress struct {
+	Epoch       int           `json:"epoch"`
+	Epochs      int           `json:"epochs"`
+	Words       int64         `json:"words"`
+	TotalWords  int64         `json:"total_words"`
+	Fraction    float64       `json:"fraction"`
+	Alpha       float64       `json:"alpha"`
+	Loss        float64       `json:"loss"`
+	Elapsed     time.Duration `json:"elapsed_ns"`
+	WordsPerSec float64       `json:"words_per_sec"`
+}
+
+// reportProgress calls the Progress function of the model with a snapshot of the training.
+func (v *VectorModel) reportProgress(epoch int, words int64, alpha, loss float64) {
+	p := TrainingProgress{
+		Epoch:      epoch,
+		Epochs:     v.Iter,
+		Words:      words,
+		TotalWords: int64(v.Iter) * v.TrainWords,
+		Alpha:      alpha,
+		Loss:       loss,
+		Elapsed:    time.Since(v.Start),
+	}
+	if p.TotalWords > 0 {
+		p.Fraction = math.Min(1, float64(words)/float64(p.TotalWords))
+	}
+	if p.Elapsed > 0 {
+		p.WordsPerSec = float64(words) / p.Elapsed.Seconds()
+	}
+	v.Progress(p)
+}
+
+// finish records the total training time and overall throughput.
+func (r *TrainingReport) finish(elapsed time.Duration) {
+	r.Elapsed = elapsed
+	if elapsed > 0 {
+		r.WordsPerSec = float64(r.WordsProcessed) / elapsed.Seconds()
+	}
+}
+
+// EpochLosses returns the loss of every epoch in order.
+func (r *TrainingReport) EpochLosses() []float64 {
+	losses := make([]float64, len(r.Epochs))
+	for i, e := range r.Epochs {
+		losses[i] = e.Loss
+	}
+	return losses
+}
+
+// FinalLoss returns the loss of the last epoch, or 0 if no epoch was run.
+func (r *TrainingReport) FinalLoss() float64 {
+	if len(r.Epochs) == 0 {
+		return 0
+	}
+	return r.Epochs[len(r.Epochs)-1].Loss
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *TrainingReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteJSONFile writes the report as indented JSON to the named file.
+func (r *TrainingReport) WriteJSONFile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err = r.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadTrainingReport decodes a report written by WriteJSON.
+func ReadTrainingReport(r io.Reader) (*TrainingReport, error) {
+	report := &TrainingReport{}
+	if err := json.NewDecoder(r).Decode(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// ReportFile is the path the TrainingReport is written to when WriteReport is set: OutputFile with a ".report.json" suffix.
+func (v *VectorModel) ReportFile() string {
+	return v.OutputFile + ".report.json"
+}
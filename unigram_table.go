@@ -6,7 +6,7 @@ import (
 	"os"
 )
 
-// InitUnigramTable creates and seeds the 1-gram table
+// InitUnigramTable creates and seeds the 1-gram table used to draw negative samples. Each word fills a share of the table proportional to its count raised to the 3/4 power.
 func (v *VectorModel) InitUnigramTable() {
 	//fmt.Fprintf(os.Stdout, "Init UnigramTable %v", time.Now())
 	fmt.Fprintf(os.Stdout, "Init UnigramTable\n")
@@ -14,20 +14,20 @@ func (v *VectorModel) InitUnigramTable() {
 	var power float64 = 0.75
 	var d1 float64
 
-	v.Table = make([]int, TABLE_SIZE)
-	for a := 0; a < v.VocabMaxSize; a++ {
+	v.Table = make([]int, v.TableSize)
+	if v.VocabSize == 0 {
+		return
+	}
+	for a := 0; a < v.VocabSize; a++ {
 		trainWordPow += math.Pow(float64(v.Vocab[a].Count), power)
 	}
 	i := 0
 	d1 = math.Pow(float64(v.Vocab[0].Count), power) / trainWordPow
-	for n := i; n < TABLE_SIZE; n++ {
+	for n := 0; n < v.TableSize; n++ {
 		v.Table[n] = i
-		if float64(n)/float64(TABLE_SIZE) > d1 {
+		if float64(n)/float64(v.TableSize) > d1 && i < v.VocabSize-1 {
 			i++
 			d1 += math.Pow(float64(v.Vocab[i].Count), power) / trainWordPow
 		}
-		if i >= v.VocabMaxSize {
-			i = v.VocabMaxSize - 1
-		}
 	}
 }
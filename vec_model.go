@@ -11,7 +11,6 @@ const (
 	ALPHA_SKIP_GRAM float64 = 0.025 // learning rate defualt for skip-gram
 	ALPHA_CBOW      float64 = 0.05  // learning rate defualt for continuous bag-of-words model
 	BINARY_F        bool    = false
-	BAG_OF_WORDS    bool    = true //default learning rate (alpha) for cbow is 0.05; if false use alpha
 	DEBUG_MODE      int     = 2
 	IN_VOCAB_FILE   string  = ""
 	ITER            int     = 5
@@ -55,6 +54,19 @@ const (
 	VOCAB_HASH_SIZE_PHRASE int = 500000000 // Maximum 500M entries in the vocabulary for phrase model
 	//thresholds
 	PHRASE_THRESHOLD float64 = 100.0
+	//training report
+	WRITE_REPORT bool = false
+	//architecture
+	ARCHITECTURE Architecture = ArchCBOW // default learning rate (alpha) for cbow is 0.05, see Architecture
+	//item2vec sessions
+	SESSIONS SessionMode = SessionOff // lines are sentences, see SessionMode
+	//subword n-grams
+	SUBWORD_MIN_N   int = 3
+	SUBWORD_MAX_N   int = 6
+	SUBWORD_BUCKETS int = 0 // 0 = subwords off; fastText uses 2000000
+	//paragraph vectors
+	PARAGRAPH_VECTORS bool   = false
+	DOC_TAG_PREFIX    string = "_*" // tokens starting with it tag a document, see ParagraphVectors
 )
 
 type VocabWord struct {
@@ -93,59 +105,98 @@ Required:
 
 Optional (Option functions are supported):
 	Alpha		  Sets the starting learning rate; default is 0.025 for skip-gram,  and 0.05 for CBOW.
+	Architecture  Selects CBOW, skip-gram, structured skip-gram or CWindow (see Architecture); default is CBOW.
 	Binaryf		  Decides if the resulting vectors in binary file; default is false (off).
-	Cbow		  Uses the continuous bag of words model; default is true (use false for skip-gram model).
+	Buckets		  Number of hashed rows for character n-gram vectors (see SubwordBuckets); default is 0, which turns subwords off.
+	CatalogFile	  Item IDs of <file> (first field of every line) are kept in the vocabulary regardless of MinCount; default is "" (no catalog).
 	DebugMode	  Sets the debug mode (default = 2 = more info during training).
 	InVocabFile	  The vocabulary will be read from <file>, not constructed from the training data, if "" then program will generate vocab. Default is "".
 	Iter		  Is the number of iterations of training.
 	KmeansClasses Will output word classes rather than word vectors; default number of classes is 0 (vectors are written).
 	Layer1VecSize Sets size of word vectors; default is 100.
+	MaxCodeLen	  Sets the maximum length of the Huffman codes used by hierarchical softmax; default is 40.
+	MaxSentenceLen Sets the maximum number of words read as one sentence; default is 1000.
+	MaxN		  Longest character n-gram of a subword model; default is 6.
+	MaxStringLen  Sets the maximum length of a word, longer words are truncated; default is 100 (60 for word2phrase).
 	MinCount	  This will discard words that appear less than n times; default is 5.
+	MinN		  Shortest character n-gram of a subword model; default is 3.
+	MinReduce	  Words seen at most this many times are dropped when the vocabulary outgrows the hash table; default is 1.
 	NegSampling	  Number of negative examples; default is 5, common values are 3 - 10 (0 = not used).
+	NextRandom	  Seed of the random number generator; default is 1.
+	NumThreads	  Number of goroutines training in parallel; default is 12.
 	OutVocabFile  The vocabulary will be saved to <file>; if no file name given, i.e. "", then it won't be saved.
+	ParagraphVectors Trains a vector per document tag (a "_*tag" token on a line) alongside the words, PV-DM with CBOW and PV-DBOW with skip-gram; default is false.
+	PhraseScorer  Scores the bigrams of word2phrase with the word2phrase score, NPMI or chi-square (see PhraseScorer); default is the word2phrase score.
+	Progress	  Is called with a TrainingProgress every 10000 words a thread trains and after every epoch, from the training goroutines and so possibly concurrently; default is nil.
 	Sample		  Sets threshold for occurrence of words. Those that appear with higher frequency in the training data will be randomly down-sampled; default is 1e-3, useful range is (0, 1e-5).
+	Sessions	  Trains on every line as a set or session of items with a whole-session or shuffled window (see SessionMode); default is off.
 	SoftMax		  Use Hierarchical Softmax; default is false (not used).
+	StartingAlpha The learning rate the linear decay during training starts from; default is 0, which starts from Alpha.
+	Threshold	  The word2phrase threshold for forming phrases, higher values mean fewer phrases; default is 100.
+	VocabMaxSize  Initial capacity of the vocabulary, it grows as needed; default is 1000.
 	WindowSkipLen Set max skip length between words; default is 5.
+	WriteReport	  Writes the TrainingReport as JSON next to OutputFile (see ReportFile); default is false.
 */
 type VectorModel struct {
-	Alpha           float64
-	Binaryf         bool
-	Cbow            bool
-	DebugMode       int
-	ExpTable        []float64
-	FileSize        int64
-	Iter            int
-	KmeansClasses   int
-	Layer1VecSize   int
-	MaxCodeLen      int
-	MaxSentenceLen  int
-	MaxStringLen    int
-	MinCount        int
-	MinReduce       int
-	NegSampling     int
-	NextRandom      uint64
-	NumThreads      int
-	OutputFile      string
-	Sample          float64
-	SoftMax         bool
-	Start           time.Time
-	StartingAlpha   float64
-	Syn0            []float64
-	Syn1            []float64
-	Syn1neg         []float64
-	Table           []int
-	Threshold       float64
-	TrainFile       string
-	TrainWords      int64 //count of token types (non-unique words)
-	Vocab           VocabSlice
-	VocabHash       []int
-	VocabHashSize   int
-	VocabInFile     string
-	VocabMaxSize    int
-	VocabOutFile    string
-	VocabSize       int //count of tokens (unique words)
-	WindowSkipLen   int
-	WordCountActual int64
+	Alpha            float64
+	Architecture     Architecture
+	Binaryf          bool
+	Buckets          int
+	CatalogFile      string
+	DebugMode        int
+	DocTags          []string // document tags of a ParagraphVectors model, see LearnDocTags
+	ExpTable         []float64
+	FileSize         int64
+	Iter             int
+	KmeansClasses    int
+	Layer1VecSize    int
+	MaxCodeLen       int
+	MaxN             int
+	MaxSentenceLen   int
+	MaxStringLen     int
+	MinCount         int
+	MinN             int
+	MinReduce        int
+	NegSampling      int
+	NextRandom       uint64
+	NumThreads       int
+	OutputFile       string
+	ParagraphVectors bool
+	PhraseScorer     PhraseScorer
+	Progress         func(TrainingProgress)
+	Sample           float64
+	Sessions         SessionMode
+	SoftMax          bool
+	Start            time.Time
+	StartingAlpha    float64
+	Syn0             []float64
+	Syn1             []float64
+	Syn1neg          []float64
+	SynDoc           []float64 // document vectors, one row per DocTags entry
+	SynSubword       []float64 // character n-gram vectors, Buckets rows
+	Table            []int
+	TableSize        int
+	Threshold        float64
+	TrainFile        string
+	TrainWords       int64 //count of token types (non-unique words)
+	TrainingTime     time.Duration
+	Vocab            VocabSlice
+	VocabHash        []int
+	VocabHashSize    int
+	VocabInFile      string
+	VocabMaxSize     int
+	VocabOutFile     string
+	VocabSize        int //count of tokens (unique words)
+	WindowSkipLen    int
+	WordCountActual  int64
+	WriteReport      bool
+	alphaSet         bool            // set by AlphaOption, see BagOfWordsFalse
+	phrase           bool            // set by NewWord2PhraseModel, see Validate
+	subwords         [][]int         // n-gram buckets of every vocabulary word, see cacheSubwords
+	docIndex         map[string]int  // row of every document tag in SynDoc
+	sifComponent     []float64       // common component removed from EmbedSIF vectors, see FitSIF
+	catalog          map[string]bool // item IDs of CatalogFile, see loadCatalog
+	sentences        [][]int         // vocabulary indices of the in-memory corpus of TrainSentences, -1 for discarded words
 }
 
 // PrecomputeExpTable builds the computes an exponent table using EXP_TABLE_SIZE and MAX_EXP
@@ -161,7 +212,9 @@ func PreComputeExpTable() (expTable []float64) {
 }
 
 /*
-NewWord2PhraseModel creates the word vector model struct for running word2phrase modelling. It does not require the amount of parameters for word2vec and therefore the struct is much smaller and uses only a few of the fields. See NewWord2VecModel for how to use the ModelParams variadic arg.
+NewWord2PhraseModel creates the word vector model struct for running word2phrase modelling (see TrainPhrases) with the given Threshold. It does not require the amount of parameters for word2vec and therefore the struct is much smaller and uses only a few of the fields. See NewWord2VecModel for how to use the ModelParams variadic arg.
+
+The vocabulary and its hash are allocated once the options are valid, so a VocabHashSizeOption avoids allocating the VOCAB_HASH_SIZE_PHRASE entries of the default.
 */
 func NewWord2PhraseModel(trainFile string, outFile string, threshold float64, modelParams ...ModelParams) (*VectorModel, error) {
 	vm := &VectorModel{
@@ -171,13 +224,13 @@ func NewWord2PhraseModel(trainFile string, outFile string, threshold float64, mo
 		MinCount:      MIN_COUNT,
 		MinReduce:     MIN_REDUCE,
 		MaxStringLen:  MAX_STRING_PHRASE,
-		Vocab:         make(VocabSlice, MAX_VOCAB_PHRASE),
-		VocabHash:     make([]int, VOCAB_HASH_SIZE_PHRASE),
+		VocabMaxSize:  MAX_VOCAB_PHRASE,
 		VocabHashSize: VOCAB_HASH_SIZE_PHRASE,
 		VocabSize:     0,
 		TrainWords:    0,
-		Threshold:     PHRASE_THRESHOLD,
+		Threshold:     threshold,
 		NextRandom:    NEXT_RANDOM,
+		phrase:        true,
 	}
 
 	for _, mp := range modelParams {
@@ -186,6 +239,10 @@ func NewWord2PhraseModel(trainFile string, outFile string, threshold float64, mo
 			return &VectorModel{}, err
 		}
 	}
+	if err := vm.Validate(); err != nil {
+		return &VectorModel{}, err
+	}
+	vm.allocateVocab()
 
 	return vm, nil
 }
@@ -203,47 +260,52 @@ An example with zero learning rate, no debugging output, doing 2o iterations and
 An example using k-means classes for words instead of vectors:
 	wvm := NewWord2VecModel("training_data.txt", "word2vec_kmeans_file.txt", )
 
-Many arguments will not need to be defined and can rely on the defaults. See the tests for VecModel for more examples.
+Many arguments will not need to be defined and can rely on the defaults. See the tests for VecModel for more examples. The options are checked with Validate once they have all been applied, an invalid combination returns the joined errors of every offending field. The vocabulary and its hash are only allocated for valid options.
 */
 func NewWord2VecModel(trainFile, outFile string, modelParams ...ModelParams) (*VectorModel, error) {
 	vm := &VectorModel{
-		Alpha:           ALPHA_CBOW,
-		Binaryf:         BINARY_F,
-		Cbow:            BAG_OF_WORDS,
-		DebugMode:       DEBUG_MODE,
-		ExpTable:        PreComputeExpTable(),
-		FileSize:        0,
-		VocabInFile:     IN_VOCAB_FILE,
-		Iter:            ITER,
-		KmeansClasses:   KMEANS_CLASSES,
-		Layer1VecSize:   LAYER1_VEC_SIZE,
-		MaxCodeLen:      MAX_CODE_LENGTH,
-		MaxSentenceLen:  MAX_SENTENCE_WORD,
-		MaxStringLen:    MAX_STRING_WORD,
-		MinCount:        MIN_COUNT,
-		MinReduce:       MIN_REDUCE,
-		NegSampling:     NEG_SAMPLING,
-		NextRandom:      NEXT_RANDOM,
-		NumThreads:      NUM_THREADS,
-		VocabOutFile:    IN_VOCAB_FILE,
-		OutputFile:      outFile,
-		Sample:          SAMPLE,
-		SoftMax:         SOFTMAX,
-		Start:           time.Now(),
-		StartingAlpha:   0,
-		Syn0:            []float64{},
-		Syn1:            []float64{},
-		Syn1neg:         []float64{},
-		Table:           []int{},
-		TrainFile:       trainFile,
-		TrainWords:      0,
-		Vocab:           make(VocabSlice, MAX_VOCAB_WORD),
-		VocabHash:       make([]int, VOCAB_HASH_SIZE_WORD),
-		VocabHashSize:   VOCAB_HASH_SIZE_WORD,
-		VocabMaxSize:    MAX_VOCAB_WORD,
-		VocabSize:       0,
-		WindowSkipLen:   WINDOW_SKIP_LEN,
-		WordCountActual: 0,
+		Alpha:            ALPHA_CBOW,
+		Architecture:     ARCHITECTURE,
+		Binaryf:          BINARY_F,
+		Buckets:          SUBWORD_BUCKETS,
+		DebugMode:        DEBUG_MODE,
+		ExpTable:         PreComputeExpTable(),
+		FileSize:         0,
+		VocabInFile:      IN_VOCAB_FILE,
+		Iter:             ITER,
+		KmeansClasses:    KMEANS_CLASSES,
+		Layer1VecSize:    LAYER1_VEC_SIZE,
+		MaxCodeLen:       MAX_CODE_LENGTH,
+		MaxN:             SUBWORD_MAX_N,
+		MaxSentenceLen:   MAX_SENTENCE_WORD,
+		MaxStringLen:     MAX_STRING_WORD,
+		MinCount:         MIN_COUNT,
+		MinN:             SUBWORD_MIN_N,
+		MinReduce:        MIN_REDUCE,
+		NegSampling:      NEG_SAMPLING,
+		NextRandom:       NEXT_RANDOM,
+		NumThreads:       NUM_THREADS,
+		VocabOutFile:     IN_VOCAB_FILE,
+		OutputFile:       outFile,
+		ParagraphVectors: PARAGRAPH_VECTORS,
+		Sample:           SAMPLE,
+		Sessions:         SESSIONS,
+		SoftMax:          SOFTMAX,
+		Start:            time.Now(),
+		StartingAlpha:    0,
+		Syn0:             []float64{},
+		Syn1:             []float64{},
+		Syn1neg:          []float64{},
+		Table:            []int{},
+		TableSize:        TABLE_SIZE,
+		TrainFile:        trainFile,
+		TrainWords:       0,
+		VocabHashSize:    VOCAB_HASH_SIZE_WORD,
+		VocabMaxSize:     MAX_VOCAB_WORD,
+		VocabSize:        0,
+		WindowSkipLen:    WINDOW_SKIP_LEN,
+		WordCountActual:  0,
+		WriteReport:      WRITE_REPORT,
 	}
 
 	for _, mp := range modelParams {
@@ -252,11 +314,16 @@ func NewWord2VecModel(trainFile, outFile string, modelParams ...ModelParams) (*V
 			return &VectorModel{}, err
 		}
 	}
+	if err := vm.Validate(); err != nil {
+		return &VectorModel{}, err
+	}
+	vm.allocateVocab()
 
 	return vm, nil
 }
 
-func InitNet() {}
-
-func TrainModelThread(id int) {}
-func TrainModel()             {}
+// allocateVocab allocates the VocabMaxSize entries of the vocabulary and the VocabHashSize entries of its hash, once Validate has checked the sizes.
+func (v *VectorModel) allocateVocab() {
+	v.Vocab = make(VocabSlice, v.VocabMaxSize)
+	v.VocabHash = make([]int, v.VocabHashSize)
+}
@@ -4,6 +4,7 @@ package wordvec
 import (
 	//"github.com/jbowles/word_vectors"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -85,11 +86,11 @@ func TestPreComputeExpTable(t *testing.T) {
 func TestNewWord2VecDefault(t *testing.T) {
 	m, _ := NewWord2VecModel("training_data.txt", "word2vec_output.txt")
 
-	if m.Cbow == false {
-		t.Error("cbow should be true but was:", m.Cbow)
+	if m.Architecture != ArchCBOW {
+		t.Error("architecture should be cbow but was:", m.Architecture)
 	}
 
-	if m.Cbow == true && m.Alpha != ALPHA_CBOW {
+	if m.Architecture == ArchCBOW && m.Alpha != ALPHA_CBOW {
 		t.Error("alpha learning rate when using cbow should be float64 `0.05` but was:", m.Alpha)
 	}
 }
@@ -102,8 +103,8 @@ func TestNewWord2VecCbowLearningRate(t *testing.T) {
 		SoftMaxOptionTrue,
 	)
 
-	if mv.Cbow != false {
-		t.Error("cbow should be false but was:", mv.Cbow)
+	if mv.Architecture != ArchSkipGram {
+		t.Error("architecture should be skip-gram but was:", mv.Architecture)
 	}
 
 	if mv.Alpha != float64(0.025) {
@@ -157,8 +158,8 @@ func TestNewWord2VecOptionsMany(t *testing.T) {
 	if m.Binaryf != true {
 		t.Error("binary file option should be 'true' but was:", m.Binaryf)
 	}
-	if m.Cbow != false {
-		t.Error("cbow should be false but was:", m.Cbow)
+	if m.Architecture != ArchSkipGram {
+		t.Error("architecture should be skip-gram but was:", m.Architecture)
 	}
 
 	if m.Alpha != float64(0) {
@@ -200,3 +201,112 @@ func TestNewWord2VecOptionsMany(t *testing.T) {
 	}
 
 }
+
+var validatetests = []struct {
+	name   string
+	params []ModelParams
+	fields []string
+}{
+	{"zero vector size", []ModelParams{Layer1VecSizeOption(0)}, []string{"Layer1VecSize"}},
+	{"negative window", []ModelParams{WindowSkipLenOption(-1)}, []string{"WindowSkipLen"}},
+	{"no objective", []ModelParams{NegSamplingOption(0)}, []string{"NegSampling is 0 and SoftMax is off"}},
+	{"negative sample", []ModelParams{SampleOption(-1e-3)}, []string{"Sample"}},
+	{"zero iterations", []ModelParams{IterOption(0)}, []string{"Iter"}},
+	{"negative hash size", []ModelParams{VocabHashSizeOption(-1)}, []string{"VocabHashSize"}},
+	{"many", []ModelParams{Layer1VecSizeOption(-5), IterOption(0), MinCountOption(-1)}, []string{"Layer1VecSize", "Iter", "MinCount"}},
+}
+
+func TestNewWord2VecValidate(t *testing.T) {
+	for _, vt := range validatetests {
+		_, err := NewWord2VecModel("training_data.txt", "word2vec_output.txt", vt.params...)
+		if err == nil {
+			t.Errorf("%s: expected a validation error", vt.name)
+			continue
+		}
+		for _, field := range vt.fields {
+			if !strings.Contains(err.Error(), field) {
+				t.Errorf("%s: error should name %s, got %q", vt.name, field, err)
+			}
+		}
+	}
+}
+
+func TestNewWord2PhraseValidate(t *testing.T) {
+	_, err := NewWord2PhraseModel("training_data.txt", "phrase_output.txt", 100, VocabHashSizeOption(-1))
+	if err == nil || !strings.Contains(err.Error(), "VocabHashSize") {
+		t.Error("a negative hash size should fail validation naming VocabHashSize, got", err)
+	}
+}
+
+func TestValidateNumThreads(t *testing.T) {
+	mv, err := NewWord2VecModel("training_data.txt", "word2vec_output.txt", BagOfWordsFalse, SoftMaxOptionTrue, NegSamplingOption(0))
+	if err != nil {
+		t.Fatal("softmax without negative sampling should be valid, got", err)
+	}
+	mv.NumThreads = 0
+	if err := mv.Validate(); err == nil || !strings.Contains(err.Error(), "NumThreads") {
+		t.Error("zero threads should fail validation naming NumThreads, got", err)
+	}
+	if _, err := mv.TrainModel(); err == nil {
+		t.Error("TrainModel should refuse to train an invalid model")
+	}
+}
+
+func TestAlphaOptionOrder(t *testing.T) {
+	before, _ := NewWord2VecModel("training_data.txt", "word2vec_output.txt", AlphaOption(0.01), BagOfWordsFalse)
+	after, _ := NewWord2VecModel("training_data.txt", "word2vec_output.txt", BagOfWordsFalse, AlphaOption(0.01))
+	if before.Alpha != 0.01 || after.Alpha != 0.01 {
+		t.Errorf("alpha should be 0.01 whatever the option order, got %v and %v", before.Alpha, after.Alpha)
+	}
+}
+
+func TestNewWord2VecTuningOptions(t *testing.T) {
+	m, err := NewWord2VecModel(
+		"training_data.txt",
+		"word2vec_output.txt",
+		NumThreadsOption(3),
+		MinReduceOption(2),
+		MaxSentenceLenOption(500),
+		MaxStringLenOption(50),
+		MaxCodeLenOption(30),
+		ThresholdOption(50),
+		NextRandomOption(42),
+		VocabMaxSizeOption(5000),
+		StartingAlphaOption(0.1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.NumThreads != 3 || m.MinReduce != 2 || m.MaxSentenceLen != 500 || m.MaxStringLen != 50 || m.MaxCodeLen != 30 {
+		t.Errorf("expected 3/2/500/50/30, got %d/%d/%d/%d/%d", m.NumThreads, m.MinReduce, m.MaxSentenceLen, m.MaxStringLen, m.MaxCodeLen)
+	}
+	if m.Threshold != 50 || m.NextRandom != 42 || m.StartingAlpha != 0.1 {
+		t.Errorf("expected 50/42/0.1, got %v/%v/%v", m.Threshold, m.NextRandom, m.StartingAlpha)
+	}
+	if m.VocabMaxSize != 5000 || len(m.Vocab) != 5000 {
+		t.Errorf("vocab should be allocated for 5000 words, got %d/%d", m.VocabMaxSize, len(m.Vocab))
+	}
+}
+
+var badoptiontests = []struct {
+	option ModelParams
+	field  string
+}{
+	{NumThreadsOption(0), "NumThreads"},
+	{MinReduceOption(-1), "MinReduce"},
+	{MaxSentenceLenOption(0), "MaxSentenceLen"},
+	{MaxStringLenOption(0), "MaxStringLen"},
+	{MaxCodeLenOption(-3), "MaxCodeLen"},
+	{ThresholdOption(-1), "Threshold"},
+	{VocabMaxSizeOption(1), "VocabMaxSize"},
+	{StartingAlphaOption(-0.5), "StartingAlpha"},
+}
+
+func TestNewWord2VecBadTuningOptions(t *testing.T) {
+	for _, bt := range badoptiontests {
+		_, err := NewWord2VecModel("training_data.txt", "word2vec_output.txt", bt.option)
+		if err == nil || !strings.Contains(err.Error(), bt.field) {
+			t.Errorf("expected an error naming %s, got %v", bt.field, err)
+		}
+	}
+}
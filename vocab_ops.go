@@ -6,25 +6,26 @@ import (
 	"io"
 	"os"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
-/*
-TODO these functions are related to using vocabulary files.
-func ReadVocab()               {}
-*/
-
-func (v *VectorModel) LearnVocabFromTrainFile() {
+// LearnVocabFromTrainFile builds the vocabulary from the words in TrainFile, discarding words that occur less than MinCount times. Document tags of a ParagraphVectors model are left out.
+func (v *VectorModel) LearnVocabFromTrainFile() error {
 	//fmt.Fprintf(os.Stdout, "Learning Vocab from Training File: %s, %v\n", v.TrainFile, time.Now())
 	fmt.Fprintf(os.Stdout, "Learning Vocab from Training File: %s\n", v.TrainFile)
 	var fin *bufio.Reader
+	if err := v.loadCatalog(); err != nil {
+		return err
+	}
 
 	v.resetVocabHashIndices()
 
 	f, ferr := os.Open(v.TrainFile)
 	if ferr != nil {
 		fmt.Fprintf(os.Stderr, "No Training File: %s, %v\n", ferr, time.Now())
-		os.Exit(1)
+		return ferr
 	}
 	defer f.Close()
 
@@ -37,6 +38,9 @@ func (v *VectorModel) LearnVocabFromTrainFile() {
 		if rerr == io.EOF {
 			break
 		}
+		if v.ParagraphVectors && isDocTag(word) {
+			continue
+		}
 		v.TrainWords++
 		if (v.DebugMode > 1) && (v.TrainWords%100000 == 0) {
 			fmt.Fprintf(os.Stdout, "%dK%c", v.TrainWords/1000, 13)
@@ -60,8 +64,81 @@ func (v *VectorModel) LearnVocabFromTrainFile() {
 		fmt.Fprintf(os.Stdout, "Vocab size: %d\n", v.VocabSize)
 		fmt.Fprintf(os.Stdout, "Words in training file: %d\n", v.TrainWords)
 	}
-	fileStat, _ := os.Stat(v.TrainFile)
+	fileStat, serr := f.Stat()
+	if serr != nil {
+		return serr
+	}
 	v.FileSize = fileStat.Size()
+	return nil
+}
+
+// ReadVocab builds the vocabulary from VocabInFile, a file of "word count" lines as written by SaveVocab, instead of learning it from the training data.
+func (v *VectorModel) ReadVocab() error {
+	fmt.Fprintf(os.Stdout, "Reading Vocab from file: %s\n", v.VocabInFile)
+	f, ferr := os.Open(v.VocabInFile)
+	if ferr != nil {
+		fmt.Fprintf(os.Stderr, "Vocabulary file not found: %s, %v\n", ferr, time.Now())
+		return ferr
+	}
+	defer f.Close()
+	if err := v.loadCatalog(); err != nil {
+		return err
+	}
+
+	v.resetVocabHashIndices()
+	v.VocabSize = 0
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		count, cerr := strconv.Atoi(fields[1])
+		if cerr != nil {
+			return fmt.Errorf("bad count for word %q in vocabulary file: %v", fields[0], cerr)
+		}
+		a := v.addWordToVocab(fields[0])
+		v.Vocab[a].Count = count
+	}
+	if serr := scanner.Err(); serr != nil {
+		return serr
+	}
+	v.sortVocab()
+	if v.DebugMode > 0 {
+		fmt.Fprintf(os.Stdout, "Vocab size: %d\n", v.VocabSize)
+		fmt.Fprintf(os.Stdout, "Words in training file: %d\n", v.TrainWords)
+	}
+
+	fileStat, serr := os.Stat(v.TrainFile)
+	if serr != nil {
+		fmt.Fprintf(os.Stderr, "No Training File: %s, %v\n", serr, time.Now())
+		return serr
+	}
+	v.FileSize = fileStat.Size()
+	return nil
+}
+
+// prepareVocab reads the vocabulary from VocabInFile or learns it from TrainFile, saves it to VocabOutFile when set and checks that it is big enough to train on; TrainModel and the count-based trainers start with it.
+func (v *VectorModel) prepareVocab() error {
+	if v.VocabInFile != "" {
+		if err := v.ReadVocab(); err != nil {
+			return err
+		}
+	} else {
+		if err := v.LearnVocabFromTrainFile(); err != nil {
+			return err
+		}
+	}
+	if v.VocabOutFile != "" {
+		if err := v.SaveVocab(); err != nil {
+			return err
+		}
+	}
+	if v.VocabSize < 2 {
+		return fmt.Errorf("Vocabulary of %d words is too small to train; lower MinCount or use a bigger training file", v.VocabSize)
+	}
+	return nil
 }
 
 // SearchVocab returns the position of a single word in the vocabulary. If word is not found return -1.
@@ -77,20 +154,25 @@ func (v *VectorModel) SearchVocab(word string) int {
 		}
 		hash = (hash + 1) % uint(v.VocabHashSize)
 	}
-	// will this ever be met??
-	return -1
 }
 
-func (v *VectorModel) SaveVocab() {
+// SaveVocab writes the vocabulary to VocabOutFile as "word count" lines, the format read by ReadVocab.
+func (v *VectorModel) SaveVocab() error {
 	fmt.Fprintf(os.Stdout, "Save vocab to file: %s\n", v.VocabOutFile)
-	f, _ := os.Create(v.VocabOutFile)
+	f, err := os.Create(v.VocabOutFile)
+	if err != nil {
+		return err
+	}
 	defer f.Close()
 	writer := bufio.NewWriter(f)
 	for i := 0; i < v.VocabSize; i++ {
 		fmt.Fprintf(writer, "%s %d\n", v.Vocab[i].Word, v.Vocab[i].Count)
 	}
-	writer.Flush()
+	if err := writer.Flush(); err != nil {
+		return err
+	}
 	//fmt.Fprintf(os.Stdout, "Finished saving vocab: %s\n", v.VocabOutFile)
+	return f.Close()
 }
 
 // ResetVocabHashIndices resets all indices of the vocab hash to -1. This is done for querying later on so we can distinguish indexes with zero, that have not been touched since initializatio, from indexes that have been modified. See also RecomputeVocabHash, LearnVocabFromTrainFile().
@@ -141,7 +223,7 @@ func (v *VectorModel) reduceVocab() {
 	var b int = 0
 	var hash uint
 	for a := 0; a < v.VocabSize; a++ {
-		if v.Vocab[a].Count > v.MinReduce {
+		if v.Vocab[a].Count > v.MinReduce || v.inCatalog(v.Vocab[a].Word) {
 			v.Vocab[b].Count = v.Vocab[a].Count
 			v.Vocab[b].Word = v.Vocab[a].Word
 			b++
@@ -152,7 +234,7 @@ func (v *VectorModel) reduceVocab() {
 	v.VocabSize = b
 	v.resetVocabHashIndices()
 
-	for c := 0; c < v.VocabHashSize; c++ {
+	for c := 0; c < v.VocabSize; c++ {
 		//Hash will be re-computed; it is not actual
 		hash = v.recomputeVocabHash(v.GetWordHash(v.Vocab[c].Word))
 		v.VocabHash[hash] = c
@@ -173,19 +255,27 @@ func (v *VectorModel) sortVocab() {
 
 	size := v.VocabSize
 	v.TrainWords = 0
-	for b := 0; b < size; b++ {
-		// Words occuring less than VectorModel.MinCount times will be discarded from the vocab
-		if (v.Vocab[b].Count < v.MinCount) && (b != 0) {
-			v.VocabSize--
-			v.Vocab[b].Word = ""
-		} else {
-			// Hash will be re-computed, after the sorting it is not actual
-			hash = v.recomputeVocabHash(v.GetWordHash(v.Vocab[b].Word))
-			v.VocabHash[hash] = b
-			v.TrainWords += int64(v.Vocab[b].Count)
+	b := 0
+	for a := 0; a < size; a++ {
+		// Words occuring less than VectorModel.MinCount times will be discarded from the vocab, unless they are catalog items
+		if (v.Vocab[a].Count < v.MinCount) && (a != 0) && !v.inCatalog(v.Vocab[a].Word) {
+			v.Vocab[a].Word = ""
+			continue
 		}
+		// A catalog item can sort after discarded words, so kept words move down to b
+		if a != b {
+			v.Vocab[b] = v.Vocab[a]
+			v.Vocab[a] = VocabWord{}
+		}
+		// Hash will be re-computed, after the sorting it is not actual
+		hash = v.recomputeVocabHash(v.GetWordHash(v.Vocab[b].Word))
+		v.VocabHash[hash] = b
+		v.TrainWords += int64(v.Vocab[b].Count)
+		b++
 	}
+	v.VocabSize = b
 	v.Vocab = v.Vocab[:v.VocabSize+1]
+	v.VocabMaxSize = len(v.Vocab)
 	// Allocate memory for the binary tree constuction
 	for c := 0; c < v.VocabSize; c++ {
 		v.Vocab[c].Code = make([]byte, v.MaxCodeLen)
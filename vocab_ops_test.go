@@ -2,6 +2,7 @@ package wordvec
 
 import (
 	"flag"
+	"fmt"
 	"testing"
 )
 
@@ -126,6 +127,37 @@ func TestLearnVocabFromTrainFileReduce(t *testing.T) {
 	mv.SaveVocab()
 }
 
+// Counting more distinct tokens than fit in 0.7 of the hash table forces a reduceVocab() while the vocabulary slice is still shorter than the hash table; the rehash must only cover the kept words.
+func TestCountTokenReduce(t *testing.T) {
+	mv, err := NewWord2VecModel(testFileOneForLearnVocab, "word2vec_output.txt", VocabHashSizeOption(2500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mv.resetVocabHashIndices()
+	mv.VocabSize = 0
+	mv.addWordToVocab("</s>")
+	for i := 0; i < 3; i++ {
+		mv.countToken("common")
+	}
+	for i := 0; i < 1800; i++ {
+		mv.countToken(fmt.Sprintf("rare%d", i))
+	}
+	if mv.MinReduce != MIN_REDUCE+1 {
+		t.Fatalf("expected one reduce, MinReduce is %d", mv.MinReduce)
+	}
+	if i := mv.SearchVocab("common"); i == -1 || mv.Vocab[i].Count != 3 {
+		t.Errorf("common should survive the reduce with count 3, got index %d", i)
+	}
+	if i := mv.SearchVocab("rare0"); i != -1 {
+		t.Errorf("rare0 should be reduced away, got index %d", i)
+	}
+	for hash, i := range mv.VocabHash {
+		if i >= mv.VocabSize {
+			t.Fatalf("hash %d points to %d past the vocabulary size %d", hash, i, mv.VocabSize)
+		}
+	}
+}
+
 /*
 TODO add this test
 